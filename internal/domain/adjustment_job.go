@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+
+	OperationTemperature = "temperature"
+
+	CallbackStatusPending    = "pending"
+	CallbackStatusDelivered  = "delivered"
+	CallbackStatusDeadLetter = "dead_letter"
+)
+
+// Image is a stored input, the id is the sha256 of its content
+type Image struct {
+	ID         string    `gorm:"column:id;primaryKey;size:64" json:"id"`
+	ClientID   string    `gorm:"column:client_id;size:255;index" json:"client_id"`
+	Width      int       `gorm:"column:width" json:"width"`
+	Height     int       `gorm:"column:height" json:"height"`
+	SizeBytes  int64     `gorm:"column:size_bytes" json:"size_bytes"`
+	StorageKey string    `gorm:"column:storage_key;size:255" json:"storage_key"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Image) TableName() string {
+	return "images"
+}
+
+// AdjustmentJob records one adjustment request, its parameters, timings and where the output went
+type AdjustmentJob struct {
+	ID        string `gorm:"column:id;primaryKey;size:36" json:"id"`
+	ImageID   string `gorm:"column:image_id;size:64;index" json:"image_id"`
+	ClientID  string `gorm:"column:client_id;size:255;index" json:"client_id"`
+	Operation string `gorm:"column:operation;size:32" json:"operation"`
+	Params    string `gorm:"column:params;type:text" json:"params"`
+	Status    string `gorm:"column:status;size:16;index" json:"status"`
+	Error     string `gorm:"column:error;type:text" json:"error"`
+	OutputKey string `gorm:"column:output_key;size:255" json:"output_key"`
+	Cached    bool   `gorm:"column:cached" json:"cached"`
+	Result    string `gorm:"column:result;type:text" json:"-"`
+	// CallbackUrl receives the WebhookPayload once the job is done or failed
+	CallbackUrl string `gorm:"column:callback_url;size:2048" json:"callback_url"`
+	// CallbackStatus stays pending until the callback is delivered or dead lettered,
+	// pending deliveries resume after a restart from CallbackAttempts at CallbackNextAttemptAt
+	CallbackStatus        string     `gorm:"column:callback_status;size:16;index" json:"callback_status"`
+	CallbackAttempts      int        `gorm:"column:callback_attempts" json:"callback_attempts"`
+	CallbackNextAttemptAt *time.Time `gorm:"column:callback_next_attempt_at" json:"callback_next_attempt_at"`
+	DurationMs            int64      `gorm:"column:duration_ms" json:"duration_ms"`
+	QueuedAt              time.Time  `gorm:"column:queued_at" json:"queued_at"`
+	StartedAt             *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt            *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	CreatedAt             time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt             time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (AdjustmentJob) TableName() string {
+	return "adjustment_jobs"
+}
+
+// ImageRepository Repository Interface
+type ImageRepository interface {
+	// Save stores the image unless a record with the same id exists
+	Save(ctx context.Context, image *Image) error
+	GetByID(ctx context.Context, id string) (Image, error)
+	// FetchByIDs returns the images of ids which still have a record, unknown ids are skipped
+	FetchByIDs(ctx context.Context, ids []string) ([]Image, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// AdjustmentJobRepository Repository Interface
+type AdjustmentJobRepository interface {
+	Create(ctx context.Context, job *AdjustmentJob) error
+	Update(ctx context.Context, job *AdjustmentJob) error
+	GetByID(ctx context.Context, id string) (AdjustmentJob, error)
+	// FetchByImageID returns the latest jobs a client requested of an image first
+	FetchByImageID(ctx context.Context, imageID, clientID string, limit int) ([]AdjustmentJob, error)
+	// FetchByStatus returns the oldest queued jobs first, a limit of -1 returns every job
+	FetchByStatus(ctx context.Context, statuses []string, limit int) ([]AdjustmentJob, error)
+	// FetchByCallbackStatus returns the jobs whose callback is in status, the oldest first
+	FetchByCallbackStatus(ctx context.Context, status string, limit int) ([]AdjustmentJob, error)
+}
+
+// WebhookDelivery records one attempt to post a WebhookPayload to the callback url of a job
+type WebhookDelivery struct {
+	ID         uint      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	JobID      string    `gorm:"column:job_id;size:36;index" json:"job_id"`
+	Url        string    `gorm:"column:url;size:2048" json:"url"`
+	Attempt    int       `gorm:"column:attempt" json:"attempt"`
+	StatusCode int       `gorm:"column:status_code" json:"status_code"`
+	Error      string    `gorm:"column:error;type:text" json:"error"`
+	Delivered  bool      `gorm:"column:delivered" json:"delivered"`
+	DeadLetter bool      `gorm:"column:dead_letter" json:"dead_letter"`
+	DurationMs int64     `gorm:"column:duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryRepository Repository Interface
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *WebhookDelivery) error
+	// FetchByJobID returns the attempts in the order they were made
+	FetchByJobID(ctx context.Context, jobID string) ([]WebhookDelivery, error)
+}
+
+// WebhookPayload is posted to the callback url, Data mirrors the synchronous response
+type WebhookPayload struct {
+	JobID  string                   `json:"job_id"`
+	Status string                   `json:"status"`
+	Error  string                   `json:"error"`
+	Data   *ImageAdjustmentResponse `json:"data"`
+}
+
+// JobResponse reports the state of a job, Result is filled with fresh signed urls once it is done
+type JobResponse struct {
+	ID         string                   `json:"id"`
+	ImageID    string                   `json:"image_id"`
+	Operation  string                   `json:"operation"`
+	Status     string                   `json:"status"`
+	Error      string                   `json:"error"`
+	DurationMs int64                    `json:"duration_ms"`
+	QueuedAt   time.Time                `json:"queued_at"`
+	StartedAt  *time.Time               `json:"started_at"`
+	FinishedAt *time.Time               `json:"finished_at"`
+	Result     *ImageAdjustmentResponse `json:"result"`
+}
@@ -1,10 +1,40 @@
 package domain
 
 import (
+	"context"
 	beegoContext "github.com/beego/beego/v2/server/web/context"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"time"
+)
+
+const (
+	// FileDownloadPath serves stored inputs and outputs behind signed links
+	FileDownloadPath = "/api/v1/files/"
+
+	FitContain = "contain"
+	FitCover   = "cover"
+	FitFill    = "fill"
+	FitInside  = "inside"
+
+	InterpolationLanczos3 = "lanczos3"
+	InterpolationBilinear = "bilinear"
+
+	FlipHorizontal = "horizontal"
+	FlipVertical   = "vertical"
+	FlipBoth       = "both"
+
+	DenoiseMedian    = "median"
+	DenoiseBilateral = "bilateral"
+
+	CompareSplit      = "split"
+	CompareSideBySide = "side_by_side"
+	CompareWipe       = "wipe"
+
+	WhiteBalanceMedian = "median"
+	WhiteBalanceAnchor = "anchor"
 )
 
 type ImageAdjustmentRequest struct {
@@ -12,6 +42,42 @@ type ImageAdjustmentRequest struct {
 	FileHeader *multipart.FileHeader `json:"file_header"`
 	AdjustmentTemperature float64 `json:"adjustment_temperature" validate:"required"`
 	Preview string `json:"preview"`
+	MaxOutputBytes int `json:"max_output_bytes" validate:"omitempty,min=1"`
+	AllowDownscale string `json:"allow_downscale"`
+	Width int `json:"width" validate:"omitempty,min=1,max=10000"`
+	Height int `json:"height" validate:"omitempty,min=1,max=10000"`
+	Fit string `json:"fit" validate:"omitempty,enum=contain-cover-fill-inside"`
+	Interpolation string `json:"interpolation" validate:"omitempty,enum=lanczos3-bilinear"`
+	Sizes []int `json:"sizes" validate:"omitempty,max=10,dive,min=1,max=10000"`
+	Crop string `json:"crop"`
+	CropAspect string `json:"crop_aspect"`
+	Gravity string `json:"gravity" validate:"omitempty,enum=center-north-south-east-west-northeast-northwest-southeast-southwest"`
+	Rotate float64 `json:"rotate" validate:"finite"`
+	Background string `json:"background"`
+	Flip string `json:"flip" validate:"omitempty,enum=horizontal-vertical-both"`
+	WhiteBalanceGains []float64 `json:"white_balance_gains,omitempty" validate:"omitempty,len=3,dive,gt=0,max=8"`
+	// Tint shifts the colour from green (-100) to magenta (100) on top of the white balance gains
+	Tint float64 `json:"tint,omitempty" validate:"omitempty,min=-100,max=100"`
+	Denoise string `json:"denoise" validate:"omitempty,enum=median-bilateral"`
+	DenoiseRadius int `json:"denoise_radius" validate:"omitempty,min=1,max=5"`
+	BlurRadius float64 `json:"blur_radius" validate:"omitempty,min=0,max=50"`
+	SharpenAmount float64 `json:"sharpen_amount" validate:"omitempty,min=0,max=5"`
+	SharpenRadius float64 `json:"sharpen_radius" validate:"omitempty,min=0,max=50"`
+	SharpenThreshold float64 `json:"sharpen_threshold" validate:"omitempty,min=0,max=255"`
+	Compare string `json:"compare,omitempty" validate:"omitempty,enum=split-side_by_side-wipe"`
+	ComparePosition float64 `json:"compare_position,omitempty" validate:"omitempty,gt=0,lt=1"`
+	CompareLabels string `json:"compare_labels,omitempty" validate:"omitempty,max=64"`
+	Variants int `json:"variants,omitempty" validate:"omitempty,min=2,max=15"`
+	StepKelvin float64 `json:"step_kelvin,omitempty" validate:"omitempty,min=1,max=5000"`
+	ContactSheet string `json:"contact_sheet,omitempty"`
+	Metrics string `json:"metrics,omitempty"`
+	MetricsHeatmap string `json:"metrics_heatmap,omitempty"`
+	ClientID string `json:"client_id"`
+	StoreInput string `json:"store_input"`
+	Async string `json:"async"`
+	CallbackUrl string `json:"callback_url" validate:"omitempty,url,startswith=http"`
+	// Preset names the preset the parameters were filled in from
+	Preset string `json:"preset,omitempty"`
 }
 
 type ImageAdjustmentResponse struct {
@@ -19,20 +85,114 @@ type ImageAdjustmentResponse struct {
 	InputPathDirImage string `json:"input_path_dir_image"`
 	OutputFileImage string `json:"output_file_image"`
 	OutputPathDirImage string `json:"output_path_dir_image"`
+	Quality int `json:"quality"`
+	OutputSizeBytes int `json:"output_size_bytes"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	Renditions []ImageRendition `json:"renditions"`
+	Cached bool `json:"cached"`
+	Metrics *ImageMetrics `json:"metrics,omitempty"`
+	OutputImage []byte `json:"-"`
+}
+
+// ImageRequest uploads an image once so it can be adjusted many times by id
+type ImageRequest struct {
+	File multipart.File `json:"file"`
+	FileHeader *multipart.FileHeader `json:"file_header"`
+	ClientID string `json:"client_id"`
+}
+
+type ImageResponse struct {
+	ID string `json:"id"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	SizeBytes int64 `json:"size_bytes"`
+	ContentType string `json:"content_type"`
+	FileImage string `json:"file_image"`
+	PathDirImage string `json:"path_dir_image"`
+	// OwnerToken is only returned on upload, it is required to delete the image or read its history
+	OwnerToken string `json:"owner_token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImageOwnerRequest identifies the client acting on an image it uploaded
+type ImageOwnerRequest struct {
+	ID string `json:"id"`
+	ClientID string `json:"client_id"`
+	OwnerToken string `json:"owner_token"`
+}
+
+// FileRequest is a signed link to a stored input or output
+type FileRequest struct {
+	Key string `json:"key" validate:"required"`
+	Expires string `json:"expires"`
+	KeyID string `json:"kid"`
+	Signature string `json:"signature"`
+}
+
+type FileResponse struct {
+	Content io.ReadCloser
+	ContentType string
+	Size int64
+}
+
+type ImageRendition struct {
+	Size int `json:"size"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	OutputFileImage string `json:"output_file_image"`
+	OutputPathDirImage string `json:"output_path_dir_image"`
+	Quality int `json:"quality"`
+	OutputSizeBytes int `json:"output_size_bytes"`
 }
 
 // ImageAdjustmentUseCase UseCase Interface
 type ImageAdjustmentUseCase interface {
 	ImageAdjustmentTemperature(beegoCtx *beegoContext.Context, request ImageAdjustmentRequest) (res ImageAdjustmentResponse,err error)
+	// ImageAdjustmentBatch adjusts every image of the zip in request.File and streams the output zip
+	// to the writer returned by openOutput, which is only called once the archive is accepted
+	ImageAdjustmentBatch(beegoCtx *beegoContext.Context, request ImageAdjustmentRequest, openOutput func() io.Writer) (res BatchManifest,err error)
+	// ImageAdjustmentTemperatureFiles adjusts the file of every request concurrently, one result per request
+	ImageAdjustmentTemperatureFiles(beegoCtx *beegoContext.Context, requests []ImageAdjustmentRequest) (res []ImageAdjustmentFileResult,err error)
+	// ImageAdjustmentVariants renders request.Variants temperature variants around the requested adjustment
+	ImageAdjustmentVariants(beegoCtx *beegoContext.Context, request ImageAdjustmentRequest) (res TemperatureVariantsResponse,err error)
+	// ImageWhiteBalance applies one white balance correction, estimated from all files or the anchor, to every file
+	ImageWhiteBalance(beegoCtx *beegoContext.Context, request WhiteBalanceRequest) (res WhiteBalanceResponse,err error)
+	// ImageHistogram returns the histograms and statistics of an uploaded or stored image
+	ImageHistogram(beegoCtx *beegoContext.Context, request HistogramRequest) (res HistogramResponse,err error)
+	// ImagePalette returns the dominant colours of an uploaded or stored image, before and after an optional adjustment
+	ImagePalette(beegoCtx *beegoContext.Context, request PaletteRequest) (res PaletteResponse,err error)
+	GetFile(beegoCtx *beegoContext.Context, request FileRequest) (res FileResponse,err error)
+	CreateImage(beegoCtx *beegoContext.Context, request ImageRequest) (res ImageResponse,err error)
+	GetImage(beegoCtx *beegoContext.Context, id string) (res ImageResponse,err error)
+	// DeleteImage removes what the owner uploaded and produced from the image, objects other clients use are kept
+	DeleteImage(beegoCtx *beegoContext.Context, request ImageOwnerRequest) error
+	ImageTemperature(beegoCtx *beegoContext.Context, id string, request ImageAdjustmentRequest) (res ImageAdjustmentResponse,err error)
+	// FetchImageAdjustments returns the adjustments the owner requested of the image
+	FetchImageAdjustments(beegoCtx *beegoContext.Context, request ImageOwnerRequest) (res []AdjustmentJob,err error)
+	EnqueueImageAdjustmentTemperature(beegoCtx *beegoContext.Context, request ImageAdjustmentRequest) (res JobResponse,err error)
+	EnqueueImageTemperature(beegoCtx *beegoContext.Context, id string, request ImageAdjustmentRequest) (res JobResponse,err error)
+	GetJob(beegoCtx *beegoContext.Context, id string) (res JobResponse,err error)
+	FetchJobDeliveries(beegoCtx *beegoContext.Context, id string) (res []WebhookDelivery,err error)
+	// StartWorkers resumes the persisted queued jobs and processes new ones until ctx is done
+	StartWorkers(ctx context.Context, workers int, timeout time.Duration) error
 }
 
 
 func (f *ImageAdjustmentRequest) ValidateFile() error {
-	if f.File == nil {
+	return validateJpegFile(f.File, f.FileHeader)
+}
+
+func (f *ImageRequest) ValidateFile() error {
+	return validateJpegFile(f.File, f.FileHeader)
+}
+
+func validateJpegFile(multipartFile multipart.File, fileHeader *multipart.FileHeader) error {
+	if multipartFile == nil {
 		return response.ErrRequiredFile
 	}
 
-	file, err := f.FileHeader.Open()
+	file, err := fileHeader.Open()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+const (
+	BatchEntryDone   = "done"
+	BatchEntryFailed = "failed"
+
+	// BatchManifestName is the entry of the output archive describing every input
+	BatchManifestName = "manifest.json"
+)
+
+// BatchLimits bounds the work of a single batch request, a zero Workers uses one per CPU
+type BatchLimits struct {
+	Workers    int
+	MaxEntries int
+	Timeout    time.Duration
+}
+
+// BatchEntryResult is the outcome of one image of a batch, Output names its entry in the output archive.
+// Params are the parameters the entry was adjusted with once the preset and batch defaults were applied,
+// OutputKey is the content address of the output, where the same adjustment of the image is cached.
+type BatchEntryResult struct {
+	Name            string          `json:"name"`
+	Status          string          `json:"status"`
+	ErrorCode       string          `json:"error_code,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	Params          json.RawMessage `json:"params"`
+	Output          string          `json:"output,omitempty"`
+	OutputKey       string          `json:"output_key,omitempty"`
+	Quality         int             `json:"quality,omitempty"`
+	OutputSizeBytes int             `json:"output_size_bytes,omitempty"`
+	Width           int             `json:"width,omitempty"`
+	Height          int             `json:"height,omitempty"`
+	Cached          bool            `json:"cached,omitempty"`
+	Metrics         *ImageMetrics   `json:"metrics,omitempty"`
+	DurationMs      int64           `json:"duration_ms"`
+}
+
+// BatchManifest is written as manifest.json next to the outputs of a batch
+type BatchManifest struct {
+	Params     json.RawMessage    `json:"params"`
+	Total      int                `json:"total"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	DurationMs int64              `json:"duration_ms"`
+	Entries    []BatchEntryResult `json:"entries"`
+}
+
+// ImageAdjustmentFileResult is the outcome of one part of a files[] request, Data holds an
+// ImageAdjustmentResponse or, for async requests, a JobResponse
+type ImageAdjustmentFileResult struct {
+	FileName string            `json:"file_name"`
+	Status   string            `json:"status"`
+	Code     string            `json:"code,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Errors   []response.Errors `json:"errors,omitempty"`
+	// Illuminant is the estimated r,g,b of the light of the file, only for white balance
+	Illuminant []float64   `json:"illuminant,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// WhiteBalanceRequest corrects the white balance of a set of images from the same shoot with shared gains,
+// the illuminant is the per channel median of the files or the illuminant of the file at AnchorIndex
+type WhiteBalanceRequest struct {
+	Strategy    string                   `json:"strategy" validate:"omitempty,enum=median-anchor"`
+	AnchorIndex int                      `json:"anchor_index" validate:"omitempty,min=0"`
+	Files       []ImageAdjustmentRequest `json:"-"`
+}
+
+// WhiteBalanceResponse gives the shared illuminant and the r,g,b gains applied to every file, the
+// gains can be passed as white_balance_gains to adjust later frames of the same shoot alike
+type WhiteBalanceResponse struct {
+	Strategy    string                      `json:"strategy"`
+	AnchorIndex int                         `json:"anchor_index"`
+	Illuminant  []float64                   `json:"illuminant"`
+	Gains       []float64                   `json:"gains"`
+	Files       []ImageAdjustmentFileResult `json:"files"`
+}
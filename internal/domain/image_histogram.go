@@ -0,0 +1,63 @@
+package domain
+
+import "mime/multipart"
+
+// HistogramRenderPNG answers the histogram drawn as an image instead of json
+const HistogramRenderPNG = "png"
+
+// HistogramRequest describes the uploaded File or, when File is nil, the stored image ImageID.
+// With an Adjustment the histogram of the adjusted image is returned too.
+type HistogramRequest struct {
+	File       multipart.File          `json:"file"`
+	FileHeader *multipart.FileHeader   `json:"file_header"`
+	ImageID    string                  `json:"image_id"`
+	Render     string                  `json:"render" validate:"omitempty,enum=png"`
+	Adjustment *ImageAdjustmentRequest `json:"-"`
+}
+
+func (f *HistogramRequest) ValidateFile() error {
+	return validateJpegFile(f.File, f.FileHeader)
+}
+
+type HistogramPercentiles struct {
+	P1  int `json:"p1"`
+	P5  int `json:"p5"`
+	P25 int `json:"p25"`
+	P75 int `json:"p75"`
+	P95 int `json:"p95"`
+	P99 int `json:"p99"`
+}
+
+// ChannelStatistics describes the 0-255 values of one channel, the clipped
+// percentages count the pixels at 0 and at 255
+type ChannelStatistics struct {
+	Histogram                []int                `json:"histogram"`
+	Mean                     float64              `json:"mean"`
+	Median                   int                  `json:"median"`
+	Percentiles              HistogramPercentiles `json:"percentiles"`
+	ShadowsClippedPercent    float64              `json:"shadows_clipped_percent"`
+	HighlightsClippedPercent float64              `json:"highlights_clipped_percent"`
+}
+
+// HistogramStatistics has 256 bins for every channel and for the Rec. 709 luminance of an image.
+// A pixel counts as clipped in the shadows when all channels are 0 and in the highlights when any is 255.
+type HistogramStatistics struct {
+	Width                    int               `json:"width"`
+	Height                   int               `json:"height"`
+	Red                      ChannelStatistics `json:"red"`
+	Green                    ChannelStatistics `json:"green"`
+	Blue                     ChannelStatistics `json:"blue"`
+	Luminance                ChannelStatistics `json:"luminance"`
+	ShadowsClippedPercent    float64           `json:"shadows_clipped_percent"`
+	HighlightsClippedPercent float64           `json:"highlights_clipped_percent"`
+}
+
+// HistogramResponse describes the image as it is and, for a request with an adjustment, the adjusted image
+type HistogramResponse struct {
+	HistogramStatistics
+	Format      string               `json:"format"`
+	ContentType string               `json:"content_type"`
+	Adjusted    *HistogramStatistics `json:"adjusted,omitempty"`
+	// Image is the png rendering for render = png
+	Image []byte `json:"-"`
+}
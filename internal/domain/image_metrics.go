@@ -0,0 +1,13 @@
+package domain
+
+// ImageMetrics compares the output of an adjustment with its input, before any resize.
+// PSNR is capped at 100 dB for identical images and DeltaE is CIEDE2000.
+type ImageMetrics struct {
+	PSNR       float64 `json:"psnr"`
+	SSIM       float64 `json:"ssim"`
+	DeltaEMean float64 `json:"delta_e_mean"`
+	DeltaEMax  float64 `json:"delta_e_max"`
+	// the heat map shows the delta e of every pixel, only with metrics_heatmap = true
+	HeatmapFileImage    string `json:"heatmap_file_image,omitempty"`
+	HeatmapPathDirImage string `json:"heatmap_path_dir_image,omitempty"`
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "mime/multipart"
+
+// DefaultPaletteColors is the number of colours of a palette when colors is not given
+const DefaultPaletteColors = 5
+
+// PaletteRequest describes the uploaded File or, when File is nil, the stored image ImageID.
+// With an Adjustment the palette of the adjusted image is returned too.
+type PaletteRequest struct {
+	File       multipart.File          `json:"file"`
+	FileHeader *multipart.FileHeader   `json:"file_header"`
+	ImageID    string                  `json:"image_id"`
+	Colors     int                     `json:"colors" validate:"omitempty,min=1,max=16"`
+	Adjustment *ImageAdjustmentRequest `json:"-"`
+}
+
+func (f *PaletteRequest) ValidateFile() error {
+	return validateJpegFile(f.File, f.FileHeader)
+}
+
+// PaletteColor is one dominant colour, Proportion is the share of the pixels closest to it
+type PaletteColor struct {
+	Hex        string     `json:"hex"`
+	RGB        [3]uint8   `json:"rgb"`
+	Lab        [3]float64 `json:"lab"`
+	Proportion float64    `json:"proportion"`
+}
+
+// PaletteResponse lists the dominant colours by proportion, largest first
+type PaletteResponse struct {
+	Palette         []PaletteColor `json:"palette"`
+	AdjustedPalette []PaletteColor `json:"adjusted_palette,omitempty"`
+}
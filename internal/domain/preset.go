@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+)
+
+// Preset is a named set of adjustment parameters, Params holds its PresetParams as json
+type Preset struct {
+	Name        string    `gorm:"column:name;primaryKey;size:64" json:"name"`
+	Description string    `gorm:"column:description;size:255" json:"description"`
+	Params      string    `gorm:"column:params;type:text" json:"-"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Preset) TableName() string {
+	return "presets"
+}
+
+// PresetParams are the parameters of ImageAdjustmentRequest a preset can hold
+type PresetParams struct {
+	AdjustmentTemperature float64   `json:"adjustment_temperature" validate:"required"`
+	WhiteBalanceGains     []float64 `json:"white_balance_gains,omitempty" validate:"omitempty,len=3,dive,gt=0,max=8"`
+	Tint                  float64   `json:"tint,omitempty" validate:"omitempty,min=-100,max=100"`
+	Denoise               string    `json:"denoise,omitempty" validate:"omitempty,enum=median-bilateral"`
+	DenoiseRadius         int       `json:"denoise_radius,omitempty" validate:"omitempty,min=1,max=5"`
+	BlurRadius            float64   `json:"blur_radius,omitempty" validate:"omitempty,min=0,max=50"`
+	SharpenAmount         float64   `json:"sharpen_amount,omitempty" validate:"omitempty,min=0,max=5"`
+	SharpenRadius         float64   `json:"sharpen_radius,omitempty" validate:"omitempty,min=0,max=50"`
+	SharpenThreshold      float64   `json:"sharpen_threshold,omitempty" validate:"omitempty,min=0,max=255"`
+}
+
+// ApplyTo copies every parameter of the preset into request which given reports as not sent with the request
+func (p PresetParams) ApplyTo(request *ImageAdjustmentRequest, given func(field string) bool) {
+	if !given("adjustment_temperature") {
+		request.AdjustmentTemperature = p.AdjustmentTemperature
+	}
+	if !given("white_balance_gains") {
+		request.WhiteBalanceGains = p.WhiteBalanceGains
+	}
+	if !given("tint") {
+		request.Tint = p.Tint
+	}
+	if !given("denoise") {
+		request.Denoise = p.Denoise
+	}
+	if !given("denoise_radius") {
+		request.DenoiseRadius = p.DenoiseRadius
+	}
+	if !given("blur_radius") {
+		request.BlurRadius = p.BlurRadius
+	}
+	if !given("sharpen_amount") {
+		request.SharpenAmount = p.SharpenAmount
+	}
+	if !given("sharpen_radius") {
+		request.SharpenRadius = p.SharpenRadius
+	}
+	if !given("sharpen_threshold") {
+		request.SharpenThreshold = p.SharpenThreshold
+	}
+}
+
+// PresetRequest creates a preset, or replaces every field of the preset Name
+type PresetRequest struct {
+	Name        string       `json:"name" validate:"required,max=64,slug"`
+	Description string       `json:"description" validate:"omitempty,max=255"`
+	Params      PresetParams `json:"params"`
+}
+
+type PresetResponse struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Params      PresetParams `json:"params"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// PresetRepository Repository Interface
+type PresetRepository interface {
+	Create(ctx context.Context, preset *Preset) error
+	Update(ctx context.Context, preset *Preset) error
+	GetByName(ctx context.Context, name string) (Preset, error)
+	// Fetch returns every preset by name
+	Fetch(ctx context.Context) ([]Preset, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// PresetUseCase UseCase Interface
+type PresetUseCase interface {
+	CreatePreset(beegoCtx *beegoContext.Context, request PresetRequest) (res PresetResponse, err error)
+	FetchPresets(beegoCtx *beegoContext.Context) (res []PresetResponse, err error)
+	GetPreset(beegoCtx *beegoContext.Context, name string) (res PresetResponse, err error)
+	UpdatePreset(beegoCtx *beegoContext.Context, request PresetRequest) (res PresetResponse, err error)
+	DeletePreset(beegoCtx *beegoContext.Context, name string) error
+}
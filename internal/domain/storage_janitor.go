@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionPolicy limits what is kept in the image storage, a zero value disables that rule
+type RetentionPolicy struct {
+	MaxAge            time.Duration
+	MaxTotalBytes     int64
+	KeepLastPerClient int
+}
+
+type StorageJanitorStats struct {
+	Runs           int64     `json:"runs"`
+	LastRunAt      time.Time `json:"last_run_at"`
+	LastDuration   string    `json:"last_duration"`
+	LastError      string    `json:"last_error"`
+	TotalObjects   int64     `json:"total_objects"`
+	TotalBytes     int64     `json:"total_bytes"`
+	DeletedObjects int64     `json:"deleted_objects"`
+	DeletedBytes   int64     `json:"deleted_bytes"`
+}
+
+// StorageJanitor enforces the RetentionPolicy on the image storage
+type StorageJanitor interface {
+	Start(ctx context.Context, interval time.Duration)
+	RunOnce(ctx context.Context) error
+	Stats() StorageJanitorStats
+}
@@ -0,0 +1,18 @@
+package domain
+
+// DefaultStepKelvin is the distance between two temperature variants when step_kelvin is not given
+const DefaultStepKelvin = 300
+
+// TemperatureVariant is one rendering of a bracket, OffsetKelvin is positive for warmer variants.
+// Passing Gains as white_balance_gains reproduces the variant.
+type TemperatureVariant struct {
+	OffsetKelvin float64   `json:"offset_kelvin"`
+	Gains        []float64 `json:"gains"`
+	ImageAdjustmentResponse
+}
+
+type TemperatureVariantsResponse struct {
+	StepKelvin   float64                  `json:"step_kelvin"`
+	Variants     []TemperatureVariant     `json:"variants"`
+	ContactSheet *ImageAdjustmentResponse `json:"contact_sheet"`
+}
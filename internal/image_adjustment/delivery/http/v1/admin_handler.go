@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	beego "github.com/beego/beego/v2/server/web"
+	"github.com/radyatamaa/image-temperature-adjustment/internal"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+)
+
+// AdminHandler serves the operational endpoints, every request needs the admin api key in X-Admin-Key
+type AdminHandler struct {
+	ZapLogger zaplogger.Logger
+	internal.BaseController
+	response.ApiResponse
+	StorageJanitor domain.StorageJanitor
+	AdminApiKey    string
+}
+
+func NewAdminHandler(storageJanitor domain.StorageJanitor, zapLogger zaplogger.Logger, adminApiKey string) {
+	pHandler := &AdminHandler{
+		ZapLogger:      zapLogger,
+		StorageJanitor: storageJanitor,
+		AdminApiKey:    adminApiKey,
+	}
+	beego.Router("/api/v1/admin/storage/janitor", pHandler, "get:StorageJanitorStats")
+}
+
+func (h *AdminHandler) Prepare() {
+	h.SetLangVersion()
+	requireAdminApiKey(&h.BaseController, h.ApiResponse, h.AdminApiKey)
+}
+
+// requireAdminApiKey answers 401 and stops the request unless X-Admin-Key matches adminApiKey,
+// without a configured key the admin endpoints can not be used at all
+func requireAdminApiKey(controller *internal.BaseController, apiResponse response.ApiResponse, adminApiKey string) {
+	apiKey := controller.Ctx.Input.Header("X-Admin-Key")
+	if apiKey == "" {
+		apiResponse.ResponseError(controller.Ctx, http.StatusUnauthorized, response.MissingApiKeyCodeError, response.ErrorCodeText(response.MissingApiKeyCodeError, controller.Locale.Lang), response.ErrMissingApiKey)
+		controller.StopRun()
+	}
+	if adminApiKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(adminApiKey)) != 1 {
+		apiResponse.ResponseError(controller.Ctx, http.StatusUnauthorized, response.InvalidApiKeyCodeError, response.ErrorCodeText(response.InvalidApiKeyCodeError, controller.Locale.Lang), response.ErrInvalidApiKey)
+		controller.StopRun()
+	}
+}
+
+// StorageJanitorStats
+// @Title StorageJanitorStats
+// @Tags Admin
+// @Summary StorageJanitorStats reports the runs of the storage retention janitor
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Router /v1/admin/storage/janitor [get]
+func (h *AdminHandler) StorageJanitorStats() {
+	h.Ok(h.Ctx, h.Tr("message.success"), h.StorageJanitor.Stats())
+	return
+}
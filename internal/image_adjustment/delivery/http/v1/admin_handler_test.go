@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	beego "github.com/beego/beego/v2/server/web"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorageJanitor struct {
+	domain.StorageJanitor
+	stats domain.StorageJanitorStats
+}
+
+func (f fakeStorageJanitor) Stats() domain.StorageJanitorStats {
+	return f.stats
+}
+
+// serveAdmin runs Prepare and, unless it stopped the request, StorageJanitorStats
+func serveAdmin(adminApiKey, headerKey string) (*httptest.ResponseRecorder, response.ApiResponse) {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/storage/janitor", nil)
+	if headerKey != "" {
+		request.Header.Set("X-Admin-Key", headerKey)
+	}
+	recorder := httptest.NewRecorder()
+
+	handler := &AdminHandler{
+		StorageJanitor: fakeStorageJanitor{stats: domain.StorageJanitorStats{Runs: 3}},
+		AdminApiKey:    adminApiKey,
+	}
+	helper.PrepareHandler(&handler.Controller, request, recorder)
+
+	func() {
+		defer func() {
+			if err := recover(); err != nil && err != beego.ErrAbort {
+				panic(err)
+			}
+		}()
+		handler.Prepare()
+		handler.StorageJanitorStats()
+	}()
+
+	var body response.ApiResponse
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder, body
+}
+
+func TestAdminHandlerRequiresApiKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminKey   string
+		headerKey  string
+		statusCode int
+		code       string
+	}{
+		{name: "missing key", adminKey: "secret", statusCode: http.StatusUnauthorized, code: response.MissingApiKeyCodeError},
+		{name: "wrong key", adminKey: "secret", headerKey: "other", statusCode: http.StatusUnauthorized, code: response.InvalidApiKeyCodeError},
+		{name: "no key configured", headerKey: "secret", statusCode: http.StatusUnauthorized, code: response.InvalidApiKeyCodeError},
+		{name: "valid key", adminKey: "secret", headerKey: "secret", statusCode: http.StatusOK, code: "OK"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, body := serveAdmin(test.adminKey, test.headerKey)
+			assert.Equal(t, test.statusCode, recorder.Code)
+			assert.Equal(t, test.code, body.Code)
+		})
+	}
+}
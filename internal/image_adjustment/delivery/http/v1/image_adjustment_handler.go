@@ -8,11 +8,15 @@ import (
 	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/signer"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/validator"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
 	"gorm.io/gorm"
-	"io/ioutil"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 )
 
 type ImageAdjustmentHandler struct {
@@ -20,14 +24,27 @@ type ImageAdjustmentHandler struct {
 	internal.BaseController
 	response.ApiResponse
 	Usecase domain.ImageAdjustmentUseCase
+	PresetUsecase domain.PresetUseCase
 }
 
-func NewImageAdjustmentHandler(useCase domain.ImageAdjustmentUseCase, zapLogger zaplogger.Logger) {
+func NewImageAdjustmentHandler(useCase domain.ImageAdjustmentUseCase, presetUseCase domain.PresetUseCase, zapLogger zaplogger.Logger) {
 	pHandler := &ImageAdjustmentHandler{
-		ZapLogger: zapLogger,
-		Usecase:   useCase,
+		ZapLogger:     zapLogger,
+		Usecase:       useCase,
+		PresetUsecase: presetUseCase,
 	}
 	beego.Router("/api/v1/image_adjustment/temperature", pHandler, "post:ImageAdjustmentTemperature")
+	beego.Router("/api/v1/image_adjustment/batch", pHandler, "post:ImageAdjustmentBatch")
+	beego.Router("/api/v1/image_adjustment/white_balance", pHandler, "post:ImageWhiteBalance")
+	beego.Router("/api/v1/image_adjustment/histogram", pHandler, "post:ImageHistogram")
+	beego.Router("/api/v1/image_adjustment/palette", pHandler, "post:ImagePalette")
+	beego.Router(domain.FileDownloadPath+"*", pHandler, "get:DownloadFile")
+	beego.Router("/api/v1/images", pHandler, "post:CreateImage")
+	beego.Router("/api/v1/images/:id", pHandler, "get:GetImage;delete:DeleteImage")
+	beego.Router("/api/v1/images/:id/temperature", pHandler, "post:ImageTemperature")
+	beego.Router("/api/v1/images/:id/adjustments", pHandler, "get:FetchImageAdjustments")
+	beego.Router("/api/v1/jobs/:id", pHandler, "get:GetJob")
+	beego.Router("/api/v1/jobs/:id/deliveries", pHandler, "get:FetchJobDeliveries")
 }
 
 func (h *ImageAdjustmentHandler) Prepare() {
@@ -41,15 +58,56 @@ func (h *ImageAdjustmentHandler) Prepare() {
 // @Summary ImageAdjustmentTemperature
 // @Produce json
 // @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id for retention, defaults to the caller ip"
 // @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Success 202 {object} swagger.BaseResponse{errors=[]object,data=object}
 // @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
 // @Failure 408 {object} swagger.RequestTimeoutResponse{errors=[]object,data=object}
 // @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
 // @Param        file   formData  file    true  "file"
-// @Param        adjustment_temperature  formData  string  true  "adjustment_temperature"
-// @Param        preview  formData  string  false  "preview = true or false"
+// @Param        files[]   formData  file    false  "several files adjusted concurrently instead of file, answers one result per file"
+// @Param        adjustment_temperature  formData  string  false  "adjustment_temperature, required without preset"
+// @Param        preset  formData  string  false  "name of a preset filling in every parameter not sent with the request"
+// @Param        preview  formData  string  false  "preview = true or false, a preview returns the image and stores nothing"
+// @Param        store_input  formData  string  false  "store_input = false to not keep the original upload"
+// @Param        async  formData  string  false  "async = true answers 202 with a job, poll /v1/jobs/{id} for the result"
+// @Param        callback_url  formData  string  false  "url which receives a signed POST once the job is done or failed"
+// @Param        max_output_bytes  formData  int  false  "max size of output jpeg in bytes, quality is lowered to fit"
+// @Param        allow_downscale  formData  string  false  "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes"
+// @Param        width  formData  int  false  "output width in pixels"
+// @Param        height  formData  int  false  "output height in pixels"
+// @Param        fit  formData  string  false  "fit = contain, cover, fill or inside (default inside)"
+// @Param        interpolation  formData  string  false  "interpolation = lanczos3 or bilinear (default lanczos3)"
+// @Param        sizes  formData  string  false  "rendition widths, e.g. [320,640,1280], clamped to the source width"
+// @Param        rotate  formData  number  false  "clockwise rotation in degrees, applied first"
+// @Param        background  formData  string  false  "background fill for arbitrary rotation, hex e.g. #ffffff (default black)"
+// @Param        flip  formData  string  false  "flip = horizontal, vertical or both, applied after rotate"
+// @Param        crop  formData  string  false  "crop rect x,y,width,height, applied after rotate and flip"
+// @Param        crop_aspect  formData  string  false  "crop to aspect ratio width:height, e.g. 16:9"
+// @Param        gravity  formData  string  false  "gravity for crop_aspect = center, north, south, east, west, northeast, northwest, southeast or southwest"
+// @Param        denoise  formData  string  false  "denoise = median or bilateral, applied before the temperature pass"
+// @Param        denoise_radius  formData  int  false  "denoise window radius 1-5 (default 1)"
+// @Param        white_balance_gains  formData  string  false  "r,g,b gains applied before the temperature pass, e.g. the gains of /v1/image_adjustment/white_balance"
+// @Param        tint  formData  number  false  "tint from green -100 to magenta 100, applied with the white balance gains"
+// @Param        compare  formData  string  false  "compare = split, side_by_side or wipe renders the original and the adjusted image into one output"
+// @Param        compare_position  formData  number  false  "divider position of split and wipe between 0 and 1 (default 0.5)"
+// @Param        compare_labels  formData  string  false  "compare_labels = true labels Before and After, or give both texts e.g. Original,Graded"
+// @Param        variants  formData  int  false  "render 2-15 variants step_kelvin apart around the requested adjustment instead of one output, runs synchronously so async and callback_url are rejected"
+// @Param        step_kelvin  formData  number  false  "kelvin between two variants (default 300)"
+// @Param        contact_sheet  formData  string  false  "contact_sheet = true adds one image of the labelled variants side by side, a preview answers only this image"
+// @Param        metrics  formData  string  false  "metrics = true adds psnr, ssim and the mean and max CIEDE2000 delta e between input and output"
+// @Param        metrics_heatmap  formData  string  false  "metrics_heatmap = true also stores a heat map of the delta e of every pixel, not for a preview"
+// @Param        blur_radius  formData  number  false  "gaussian blur sigma in pixels, applied after the temperature pass"
+// @Param        sharpen_amount  formData  number  false  "unsharp mask amount, e.g. 0.5, applied last"
+// @Param        sharpen_radius  formData  number  false  "unsharp mask radius in pixels (default 1)"
+// @Param        sharpen_threshold  formData  number  false  "unsharp mask threshold 0-255"
 // @Router /v1/image_adjustment/temperature [post]
 func (h *ImageAdjustmentHandler) ImageAdjustmentTemperature() {
+	if fileHeaders, err := h.GetFiles("files[]"); err == nil && len(fileHeaders) > 0 {
+		h.imageAdjustmentTemperatureFiles(fileHeaders)
+		return
+	}
+
 	file, fileHeader, err := h.GetFile("file")
 	if err != nil {
 		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
@@ -57,12 +115,13 @@ func (h *ImageAdjustmentHandler) ImageAdjustmentTemperature() {
 		return
 	}
 
-	request := domain.ImageAdjustmentRequest{
-		File:                  file,
-		FileHeader:            fileHeader,
-		AdjustmentTemperature: helper.StringToFloat(h.GetString("adjustment_temperature")),
-		Preview: 				h.GetString("preview"),
+	request, err := h.adjustmentRequest()
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
 	}
+	request.File = file
+	request.FileHeader = fileHeader
 
 	if err := validator.Validate.ValidateStruct(&request); err != nil {
 		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
@@ -84,28 +143,483 @@ func (h *ImageAdjustmentHandler) ImageAdjustmentTemperature() {
 		return
 	}
 
-	result, err := h.Usecase.ImageAdjustmentTemperature(h.Ctx, request)
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.ResponseError(h.Ctx, http.StatusRequestTimeout, response.RequestTimeoutCodeError, response.ErrorCodeText(response.RequestTimeoutCodeError, h.Locale.Lang), err)
+	if request.Variants > 0 {
+		result, err := h.Usecase.ImageAdjustmentVariants(h.Ctx, request)
+		if err != nil {
+			h.responseAdjustmentError(err)
 			return
 		}
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			h.ResponseError(h.Ctx, http.StatusBadRequest, response.DataNotFoundCodeError, response.ErrorCodeText(response.DataNotFoundCodeError, h.Locale.Lang), err)
-			return
+		if request.Preview == "true" {
+			h.Ctx.Output.Header("Content-Type", "image/jpeg")
+			h.Ctx.Output.Body(result.ContactSheet.OutputImage)
+		} else {
+			h.Ok(h.Ctx, h.Tr("message.success"), result)
 		}
-		h.ResponseError(h.Ctx, http.StatusInternalServerError, response.ServerErrorCode, response.ErrorCodeText(response.ServerErrorCode, h.Locale.Lang), err)
 		return
 	}
-	if request.Preview == "true" {
-		imageData, err := ioutil.ReadFile(result.OutputPathDirImage)
+
+	if request.Async == "true" {
+		job, err := h.Usecase.EnqueueImageAdjustmentTemperature(h.Ctx, request)
 		if err != nil {
-			h.ResponseError(h.Ctx, http.StatusInternalServerError, response.ServerErrorCode, response.ErrorCodeText(response.ServerErrorCode, h.Locale.Lang), err)
+			h.responseAdjustmentError(err)
 			return
 		}
-		h.Ctx.Output.Body(imageData)
+		h.Accepted(h.Ctx, h.Tr("message.success"), job)
+		return
+	}
+
+	result, err := h.Usecase.ImageAdjustmentTemperature(h.Ctx, request)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	if request.Preview == "true" {
+		h.Ctx.Output.Header("Content-Type", "image/jpeg")
+		h.Ctx.Output.Body(result.OutputImage)
 	}else {
 		h.Ok(h.Ctx, h.Tr("message.success"), result)
 	}
 	return
 }
+
+// ImageAdjustmentBatch
+// @Title ImageAdjustmentBatch
+// @Tags ImageAdjustment
+// @Summary ImageAdjustmentBatch
+// @Produce application/zip
+// @Param Accept-Language header string false "lang"
+// @Success 200 {file} file "zip of the adjusted images with a manifest.json giving the status, error and parameters of every entry"
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        file   formData  file    true  "zip archive of jpeg images"
+// @Param        adjustment_temperature  formData  string  false  "adjustment_temperature, required without preset"
+// @Param        preset  formData  string  false  "name of a preset filling in every parameter not sent with the request"
+// @Param        max_output_bytes  formData  int  false  "max size of output jpeg in bytes, quality is lowered to fit"
+// @Param        allow_downscale  formData  string  false  "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes"
+// @Param        width  formData  int  false  "output width in pixels"
+// @Param        height  formData  int  false  "output height in pixels"
+// @Param        fit  formData  string  false  "fit = contain, cover, fill or inside (default inside)"
+// @Param        interpolation  formData  string  false  "interpolation = lanczos3 or bilinear (default lanczos3)"
+// @Param        rotate  formData  number  false  "clockwise rotation in degrees, applied first"
+// @Param        background  formData  string  false  "background fill for arbitrary rotation, hex e.g. #ffffff (default black)"
+// @Param        flip  formData  string  false  "flip = horizontal, vertical or both, applied after rotate"
+// @Param        crop  formData  string  false  "crop rect x,y,width,height, applied after rotate and flip"
+// @Param        crop_aspect  formData  string  false  "crop to aspect ratio width:height, e.g. 16:9"
+// @Param        gravity  formData  string  false  "gravity for crop_aspect = center, north, south, east, west, northeast, northwest, southeast or southwest"
+// @Param        denoise  formData  string  false  "denoise = median or bilateral, applied before the temperature pass"
+// @Param        denoise_radius  formData  int  false  "denoise window radius 1-5 (default 1)"
+// @Param        tint  formData  number  false  "tint from green -100 to magenta 100"
+// @Param        blur_radius  formData  number  false  "gaussian blur sigma in pixels, applied after the temperature pass"
+// @Param        sharpen_amount  formData  number  false  "unsharp mask amount, e.g. 0.5, applied last"
+// @Param        sharpen_radius  formData  number  false  "unsharp mask radius in pixels (default 1)"
+// @Param        sharpen_threshold  formData  number  false  "unsharp mask threshold 0-255"
+// @Router /v1/image_adjustment/batch [post]
+func (h *ImageAdjustmentHandler) ImageAdjustmentBatch() {
+	file, fileHeader, err := h.GetFile("file")
+	if err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.RequiredFileErrorCode, response.ErrorCodeText(response.RequiredFileErrorCode, h.Locale.Lang), response.ErrRequiredFile)
+		return
+	}
+	defer file.Close()
+
+	request, err := h.adjustmentRequest()
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	request.File = file
+	request.FileHeader = fileHeader
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	// Once the archive is accepted the status is sent and the outputs are streamed
+	streaming := false
+	_, err = h.Usecase.ImageAdjustmentBatch(h.Ctx, request, func() io.Writer {
+		streaming = true
+		h.Ctx.Output.Header("Content-Type", "application/zip")
+		h.Ctx.Output.Header("Content-Disposition", `attachment; filename="image_adjustment_batch.zip"`)
+		h.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+		return h.Ctx.ResponseWriter
+	})
+	if err != nil {
+		if streaming {
+			h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+			return
+		}
+		h.responseAdjustmentError(err)
+		return
+	}
+	return
+}
+
+// imageAdjustmentTemperatureFiles answers a files[] request with one result per file, a file
+// which fails validation or processing does not fail the others
+func (h *ImageAdjustmentHandler) imageAdjustmentTemperatureFiles(fileHeaders []*multipart.FileHeader) {
+	request, err := h.adjustmentRequest()
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+	if request.Preview == "true" {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.MultiFilePreviewErrorCode, response.ErrorCodeText(response.MultiFilePreviewErrorCode, h.Locale.Lang), response.ErrMultiFilePreview)
+		return
+	}
+
+	results, requests, positions := h.validateFiles(request, fileHeaders)
+	defer closeFiles(requests)
+
+	fileResults, err := h.Usecase.ImageAdjustmentTemperatureFiles(h.Ctx, requests)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.mergeFileResults(results, positions, fileResults)
+
+	h.Ok(h.Ctx, h.Tr("message.success"), results)
+	return
+}
+
+// validateFiles validates every part of a files[] request, the failed ones are already in results
+// and the others are returned as requests with their position in results. The caller closes their files.
+func (h *ImageAdjustmentHandler) validateFiles(request domain.ImageAdjustmentRequest, fileHeaders []*multipart.FileHeader) (results []domain.ImageAdjustmentFileResult, requests []domain.ImageAdjustmentRequest, positions []int) {
+	results = make([]domain.ImageAdjustmentFileResult, len(fileHeaders))
+	requests = make([]domain.ImageAdjustmentRequest, 0, len(fileHeaders))
+	positions = make([]int, 0, len(fileHeaders))
+	for index, fileHeader := range fileHeaders {
+		fileRequest := request
+		fileRequest.FileHeader = fileHeader
+
+		file, err := fileHeader.Open()
+		if err == nil {
+			fileRequest.File = file
+			err = fileRequest.ValidateFile()
+			if err != nil {
+				file.Close()
+			}
+		}
+		if err != nil {
+			code := response.ApiValidationCodeError
+			if errors.Is(err, response.ErrRequiredFile) || errors.Is(err, response.ErrInvalidFormatFileJpeg) {
+				code = response.ErrorCodeOf(err)
+			}
+			results[index] = domain.ImageAdjustmentFileResult{
+				FileName: fileHeader.Filename,
+				Status:   domain.BatchEntryFailed,
+				Code:     code,
+				Message:  response.ErrorCodeText(code, h.Locale.Lang),
+				Errors:   []response.Errors{{Field: fmt.Sprintf("files[%d]", index), Description: err.Error()}},
+			}
+			continue
+		}
+		requests = append(requests, fileRequest)
+		positions = append(positions, index)
+	}
+
+	return results, requests, positions
+}
+
+func closeFiles(requests []domain.ImageAdjustmentRequest) {
+	for _, request := range requests {
+		request.File.Close()
+	}
+}
+
+// mergeFileResults puts the results of the usecase back at the position of their file
+func (h *ImageAdjustmentHandler) mergeFileResults(results []domain.ImageAdjustmentFileResult, positions []int, fileResults []domain.ImageAdjustmentFileResult) {
+	for position, result := range fileResults {
+		index := positions[position]
+		if result.Status == domain.BatchEntryFailed {
+			result.Errors = []response.Errors{{Field: fmt.Sprintf("files[%d]", index), Description: result.Message}}
+			result.Message = response.ErrorCodeText(result.Code, h.Locale.Lang)
+		}
+		results[index] = result
+	}
+}
+
+// ImageWhiteBalance
+// @Title ImageWhiteBalance
+// @Tags ImageAdjustment
+// @Summary ImageWhiteBalance
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id for retention, defaults to the caller ip"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        files[]   formData  file    true  "images of the same shoot, every one gets the same white balance correction"
+// @Param        strategy  formData  string  false  "strategy = median (default) for the median illuminant of the files or anchor for the illuminant of one file"
+// @Param        anchor_index  formData  int  false  "index in files[] of the anchor file, for strategy = anchor"
+// @Param        adjustment_temperature  formData  string  false  "adjustment_temperature applied after the white balance (default 1, unchanged)"
+// @Param        preset  formData  string  false  "name of a preset filling in every parameter not sent with the request"
+// @Param        store_input  formData  string  false  "store_input = false to not keep the original uploads"
+// @Param        max_output_bytes  formData  int  false  "max size of output jpeg in bytes, quality is lowered to fit"
+// @Param        allow_downscale  formData  string  false  "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes"
+// @Param        width  formData  int  false  "output width in pixels"
+// @Param        height  formData  int  false  "output height in pixels"
+// @Param        fit  formData  string  false  "fit = contain, cover, fill or inside (default inside)"
+// @Param        interpolation  formData  string  false  "interpolation = lanczos3 or bilinear (default lanczos3)"
+// @Param        sizes  formData  string  false  "rendition widths, e.g. [320,640,1280], clamped to the source width"
+// @Router /v1/image_adjustment/white_balance [post]
+func (h *ImageAdjustmentHandler) ImageWhiteBalance() {
+	fileHeaders, err := h.GetFiles("files[]")
+	if err != nil {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.RequiredFileErrorCode, response.ErrorCodeText(response.RequiredFileErrorCode, h.Locale.Lang), response.ErrRequiredFile)
+		return
+	}
+
+	request, err := h.adjustmentRequest()
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	if request.AdjustmentTemperature == 0 {
+		request.AdjustmentTemperature = 1
+	}
+	// the same gains are applied to every file, gains of the caller would be replaced
+	request.WhiteBalanceGains = nil
+	request.Async = ""
+	request.CallbackUrl = ""
+	whiteBalanceRequest := domain.WhiteBalanceRequest{
+		Strategy:    h.GetString("strategy"),
+		AnchorIndex: helper.StringToInt(h.GetString("anchor_index")),
+	}
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+	if err := validator.Validate.ValidateStruct(&whiteBalanceRequest); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+	if request.Preview == "true" {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.MultiFilePreviewErrorCode, response.ErrorCodeText(response.MultiFilePreviewErrorCode, h.Locale.Lang), response.ErrMultiFilePreview)
+		return
+	}
+
+	results, requests, positions := h.validateFiles(request, fileHeaders)
+	defer closeFiles(requests)
+	whiteBalanceRequest.Files = requests
+
+	// the anchor is an index in files[], the usecase only sees the valid files
+	anchorIndex := whiteBalanceRequest.AnchorIndex
+	if whiteBalanceRequest.Strategy == domain.WhiteBalanceAnchor {
+		whiteBalanceRequest.AnchorIndex = -1
+		for position, index := range positions {
+			if index == anchorIndex {
+				whiteBalanceRequest.AnchorIndex = position
+			}
+		}
+	}
+
+	result, err := h.Usecase.ImageWhiteBalance(h.Ctx, whiteBalanceRequest)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.mergeFileResults(results, positions, result.Files)
+	result.Files = results
+	result.AnchorIndex = anchorIndex
+
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// adjustmentRequest reads the adjustment parameters shared by every adjustment endpoint, the
+// parameters of a preset fill in every one which is not sent with the request
+func (h *ImageAdjustmentHandler) adjustmentRequest() (domain.ImageAdjustmentRequest, error) {
+	request := domain.ImageAdjustmentRequest{
+		AdjustmentTemperature: helper.StringToFloat(h.GetString("adjustment_temperature")),
+		Preview: 				h.GetString("preview"),
+		MaxOutputBytes:        helper.StringToInt(h.GetString("max_output_bytes")),
+		AllowDownscale:        h.GetString("allow_downscale"),
+		Width:                 helper.StringToInt(h.GetString("width")),
+		Height:                helper.StringToInt(h.GetString("height")),
+		Fit:                   h.GetString("fit"),
+		Interpolation:         h.GetString("interpolation"),
+		Sizes:                 helper.StringToIntSlice(h.GetString("sizes")),
+		Crop:                  h.GetString("crop"),
+		CropAspect:            h.GetString("crop_aspect"),
+		Gravity:               h.GetString("gravity"),
+		Rotate:                helper.StringToFloat(h.GetString("rotate")),
+		Background:            h.GetString("background"),
+		Flip:                  h.GetString("flip"),
+		WhiteBalanceGains:     helper.StringToFloatSlice(h.GetString("white_balance_gains")),
+		Tint:                  helper.StringToFloat(h.GetString("tint")),
+		Denoise:               h.GetString("denoise"),
+		DenoiseRadius:         helper.StringToInt(h.GetString("denoise_radius")),
+		BlurRadius:            helper.StringToFloat(h.GetString("blur_radius")),
+		SharpenAmount:         helper.StringToFloat(h.GetString("sharpen_amount")),
+		SharpenRadius:         helper.StringToFloat(h.GetString("sharpen_radius")),
+		SharpenThreshold:      helper.StringToFloat(h.GetString("sharpen_threshold")),
+		ClientID:              helper.InlineConditionString(h.Ctx.Input.Header("X-Client-ID") != "", h.Ctx.Input.Header("X-Client-ID"), h.Ctx.Input.IP()),
+		Compare:               h.GetString("compare"),
+		ComparePosition:       helper.StringToFloat(h.GetString("compare_position")),
+		CompareLabels:         h.GetString("compare_labels"),
+		Variants:              helper.StringToInt(h.GetString("variants")),
+		StepKelvin:            helper.StringToFloat(h.GetString("step_kelvin")),
+		ContactSheet:          h.GetString("contact_sheet"),
+		Metrics:               h.GetString("metrics"),
+		MetricsHeatmap:        h.GetString("metrics_heatmap"),
+		StoreInput:            h.GetString("store_input"),
+		Async:                 h.GetString("async"),
+		CallbackUrl:           h.GetString("callback_url"),
+		Preset:                h.GetString("preset"),
+	}
+	if request.Preset == "" {
+		return request, nil
+	}
+
+	preset, err := h.PresetUsecase.GetPreset(h.Ctx, request.Preset)
+	if err != nil {
+		return request, err
+	}
+	preset.Params.ApplyTo(&request, func(field string) bool {
+		return h.GetString(field) != ""
+	})
+	return request, nil
+}
+
+func (h *ImageAdjustmentHandler) responseAdjustmentError(err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.ResponseError(h.Ctx, http.StatusRequestTimeout, response.RequestTimeoutCodeError, response.ErrorCodeText(response.RequestTimeoutCodeError, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrOutputSizeUnreachable) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.OutputSizeUnreachableErrorCode, response.ErrorCodeText(response.OutputSizeUnreachableErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidCrop) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidCropErrorCode, response.ErrorCodeText(response.InvalidCropErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidBackground) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidBackgroundErrorCode, response.ErrorCodeText(response.InvalidBackgroundErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrUploadTooLarge) {
+		h.ResponseError(h.Ctx, http.StatusRequestEntityTooLarge, response.UploadTooLargeErrorCode, response.ErrorCodeText(response.UploadTooLargeErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidArchive) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidArchiveErrorCode, response.ErrorCodeText(response.InvalidArchiveErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrTooManyFiles) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.TooManyFilesErrorCode, response.ErrorCodeText(response.TooManyFilesErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidAnchor) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidAnchorErrorCode, response.ErrorCodeText(response.InvalidAnchorErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrPresetNotFound) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.PresetNotFoundErrorCode, response.ErrorCodeText(response.PresetNotFoundErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrAsyncPreview) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.AsyncPreviewErrorCode, response.ErrorCodeText(response.AsyncPreviewErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrAsyncVariants) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.AsyncVariantsErrorCode, response.ErrorCodeText(response.AsyncVariantsErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrJobQueueFull) {
+		h.ResponseError(h.Ctx, http.StatusServiceUnavailable, response.JobQueueFullErrorCode, response.ErrorCodeText(response.JobQueueFullErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrMissingToken) {
+		h.ResponseError(h.Ctx, http.StatusUnauthorized, response.MissingTokenCodeError, response.ErrorCodeText(response.MissingTokenCodeError, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidToken) {
+		h.ResponseError(h.Ctx, http.StatusUnauthorized, response.InvalidTokenCodeError, response.ErrorCodeText(response.InvalidTokenCodeError, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrImageNotFound) || errors.Is(err, response.ErrJobNotFound) {
+		h.ResponseError(h.Ctx, http.StatusNotFound, response.DataNotFoundCodeError, response.ErrorCodeText(response.DataNotFoundCodeError, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.DataNotFoundCodeError, response.ErrorCodeText(response.DataNotFoundCodeError, h.Locale.Lang), err)
+		return
+	}
+	h.ResponseError(h.Ctx, http.StatusInternalServerError, response.ServerErrorCode, response.ErrorCodeText(response.ServerErrorCode, h.Locale.Lang), err)
+	return
+}
+
+// DownloadFile
+// @Title DownloadFile
+// @Tags ImageAdjustment
+// @Summary DownloadFile
+// @Produce image/jpeg
+// @Param Accept-Language header string false "lang"
+// @Success 200 {file} file
+// @Failure 401 {object} swagger.UnauthorizedResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        key  path  string  true  "stored input or output key"
+// @Param        expires  query  string  true  "expiry unix time of the link"
+// @Param        kid  query  string  true  "id of the signing key"
+// @Param        signature  query  string  true  "link signature"
+// @Router /v1/files/{key} [get]
+func (h *ImageAdjustmentHandler) DownloadFile() {
+	request := domain.FileRequest{
+		Key:       h.Ctx.Input.Param(":splat"),
+		Expires:   h.GetString(signer.QueryExpires),
+		KeyID:     h.GetString(signer.QueryKeyID),
+		Signature: h.GetString(signer.QuerySignature),
+	}
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	result, err := h.Usecase.GetFile(h.Ctx, request)
+	if err != nil {
+		if errors.Is(err, response.ErrMissingToken) {
+			h.ResponseError(h.Ctx, http.StatusUnauthorized, response.MissingTokenCodeError, response.ErrorCodeText(response.MissingTokenCodeError, h.Locale.Lang), err)
+			return
+		}
+		if errors.Is(err, response.ErrInvalidToken) {
+			h.ResponseError(h.Ctx, http.StatusUnauthorized, response.InvalidTokenCodeError, response.ErrorCodeText(response.InvalidTokenCodeError, h.Locale.Lang), err)
+			return
+		}
+		if errors.Is(err, response.ErrExpiredToken) {
+			h.ResponseError(h.Ctx, http.StatusUnauthorized, response.ExpiredTokenCodeError, response.ErrorCodeText(response.ExpiredTokenCodeError, h.Locale.Lang), err)
+			return
+		}
+		if errors.Is(err, response.ErrFileNotFound) {
+			h.ResponseError(h.Ctx, http.StatusNotFound, response.DataNotFoundCodeError, response.ErrorCodeText(response.DataNotFoundCodeError, h.Locale.Lang), err)
+			return
+		}
+		h.ResponseError(h.Ctx, http.StatusInternalServerError, response.ServerErrorCode, response.ErrorCodeText(response.ServerErrorCode, h.Locale.Lang), err)
+		return
+	}
+	defer result.Content.Close()
+
+	h.Ctx.Output.Header("Content-Type", result.ContentType)
+	h.Ctx.Output.Header("Content-Length", strconv.FormatInt(result.Size, 10))
+	h.Ctx.Output.Header("Cache-Control", "private, max-age=0")
+	h.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(h.Ctx.ResponseWriter, result.Content); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+	}
+	return
+}
@@ -0,0 +1,343 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	beego "github.com/beego/beego/v2/server/web"
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeImageAdjustmentUseCase answers files[] requests with results, the requests it got are kept
+type fakeImageAdjustmentUseCase struct {
+	domain.ImageAdjustmentUseCase
+	results             func(requests []domain.ImageAdjustmentRequest) []domain.ImageAdjustmentFileResult
+	requests            []domain.ImageAdjustmentRequest
+	whiteBalanceRequest domain.WhiteBalanceRequest
+	histogramRequest    domain.HistogramRequest
+}
+
+func (f *fakeImageAdjustmentUseCase) ImageAdjustmentTemperatureFiles(beegoCtx *beegoContext.Context, requests []domain.ImageAdjustmentRequest) ([]domain.ImageAdjustmentFileResult, error) {
+	f.requests = requests
+	if len(requests) > 2 {
+		return nil, response.ErrTooManyFiles
+	}
+	return f.results(requests), nil
+}
+
+func (f *fakeImageAdjustmentUseCase) ImageWhiteBalance(beegoCtx *beegoContext.Context, request domain.WhiteBalanceRequest) (domain.WhiteBalanceResponse, error) {
+	f.whiteBalanceRequest = request
+	if request.Strategy == domain.WhiteBalanceAnchor && request.AnchorIndex < 0 {
+		return domain.WhiteBalanceResponse{}, response.ErrInvalidAnchor
+	}
+	return domain.WhiteBalanceResponse{
+		Strategy:    request.Strategy,
+		AnchorIndex: request.AnchorIndex,
+		Illuminant:  []float64{180, 150, 120},
+		Gains:       []float64{0.8333, 1, 1.25},
+		Files:       f.results(request.Files),
+	}, nil
+}
+
+func (f *fakeImageAdjustmentUseCase) ImageHistogram(beegoCtx *beegoContext.Context, request domain.HistogramRequest) (domain.HistogramResponse, error) {
+	f.histogramRequest = request
+	res := domain.HistogramResponse{Format: "jpeg"}
+	if request.Adjustment != nil {
+		res.Adjusted = &domain.HistogramStatistics{Width: 8}
+	}
+	return res, nil
+}
+
+// fakePresetUseCase answers GetPreset with presets
+type fakePresetUseCase struct {
+	domain.PresetUseCase
+	presets map[string]domain.PresetParams
+}
+
+func (f *fakePresetUseCase) GetPreset(beegoCtx *beegoContext.Context, name string) (domain.PresetResponse, error) {
+	params, ok := f.presets[name]
+	if !ok {
+		return domain.PresetResponse{}, response.ErrPresetNotFound
+	}
+	return domain.PresetResponse{Name: name, Params: params}, nil
+}
+
+func jpegFile(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for index := range img.Pix {
+		img.Pix[index] = uint8(index)
+	}
+	img.Set(0, 0, color.White)
+	var buffer bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buffer, img, nil))
+	return buffer.Bytes()
+}
+
+// multipartRequest posts the fields and the files[] parts in order to path
+func multipartRequest(t *testing.T, path string, fields map[string]string, files map[string][]byte, order []string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.NoError(t, writer.WriteField(name, value))
+	}
+	for _, name := range order {
+		part, err := writer.CreateFormFile("files[]", name)
+		assert.NoError(t, err)
+		part.Write(files[name])
+	}
+	assert.NoError(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, path, &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	assert.NoError(t, request.ParseMultipartForm(1<<20))
+	return request
+}
+
+// serveFiles runs action on a files[] request, results are the files of the response data
+func serveFiles(t *testing.T, useCase *fakeImageAdjustmentUseCase, request *http.Request, action func(handler *ImageAdjustmentHandler)) (*httptest.ResponseRecorder, response.ApiResponse, []domain.ImageAdjustmentFileResult) {
+	recorder := httptest.NewRecorder()
+	handler := &ImageAdjustmentHandler{
+		ZapLogger: zaplogger.NewZapLogger(filepath.Join(t.TempDir(), "test.log"), ""),
+		Usecase:   useCase,
+	}
+	helper.PrepareHandler(&handler.Controller, request, recorder)
+
+	func() {
+		defer func() {
+			if err := recover(); err != nil && err != beego.ErrAbort {
+				panic(err)
+			}
+		}()
+		handler.Prepare()
+		action(handler)
+	}()
+
+	var body response.ApiResponse
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	var results []domain.ImageAdjustmentFileResult
+	data, _ := json.Marshal(body.Data)
+	if json.Unmarshal(data, &results) != nil {
+		var whiteBalance domain.WhiteBalanceResponse
+		json.Unmarshal(data, &whiteBalance)
+		results = whiteBalance.Files
+	}
+	return recorder, body, results
+}
+
+func TestImageAdjustmentTemperatureFiles(t *testing.T) {
+	useCase := &fakeImageAdjustmentUseCase{results: func(requests []domain.ImageAdjustmentRequest) []domain.ImageAdjustmentFileResult {
+		return []domain.ImageAdjustmentFileResult{
+			{FileName: requests[0].FileHeader.Filename, Status: domain.BatchEntryDone, Data: "first"},
+			{FileName: requests[1].FileHeader.Filename, Status: domain.BatchEntryFailed, Code: response.OutputSizeUnreachableErrorCode, Message: response.ErrOutputSizeUnreachable.Error()},
+		}
+	}}
+	upload := jpegFile(t)
+	request := multipartRequest(t, "/api/v1/image_adjustment/temperature",
+		map[string]string{"adjustment_temperature": "1.2", "width": "4"},
+		map[string][]byte{"a.jpg": upload, "notes.txt": []byte("not an image"), "c.jpg": upload},
+		[]string{"a.jpg", "notes.txt", "c.jpg"},
+	)
+
+	recorder, body, results := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageAdjustmentTemperature)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "OK", body.Code)
+
+	// only the valid files reach the usecase, every one with the shared parameters
+	if assert.Len(t, useCase.requests, 2) {
+		assert.Equal(t, "a.jpg", useCase.requests[0].FileHeader.Filename)
+		assert.Equal(t, "c.jpg", useCase.requests[1].FileHeader.Filename)
+		for _, fileRequest := range useCase.requests {
+			assert.Equal(t, 1.2, fileRequest.AdjustmentTemperature)
+			assert.Equal(t, 4, fileRequest.Width)
+		}
+	}
+
+	// the results keep the order of the files
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "a.jpg", results[0].FileName)
+		assert.Equal(t, domain.BatchEntryDone, results[0].Status)
+		assert.Equal(t, "first", results[0].Data)
+		assert.Empty(t, results[0].Errors)
+
+		assert.Equal(t, "notes.txt", results[1].FileName)
+		assert.Equal(t, domain.BatchEntryFailed, results[1].Status)
+		assert.Equal(t, response.InvalidFormatFileJpegErrorCode, results[1].Code)
+		if assert.Len(t, results[1].Errors, 1) {
+			assert.Equal(t, "files[1]", results[1].Errors[0].Field)
+		}
+
+		assert.Equal(t, "c.jpg", results[2].FileName)
+		assert.Equal(t, response.OutputSizeUnreachableErrorCode, results[2].Code)
+		if assert.Len(t, results[2].Errors, 1) {
+			assert.Equal(t, "files[2]", results[2].Errors[0].Field)
+			assert.Equal(t, response.ErrOutputSizeUnreachable.Error(), results[2].Errors[0].Description)
+		}
+	}
+}
+
+func TestImageAdjustmentTemperatureFilesRejectsRequest(t *testing.T) {
+	upload := jpegFile(t)
+	tests := []struct {
+		name       string
+		fields     map[string]string
+		files      []string
+		statusCode int
+		code       string
+	}{
+		{name: "preview", fields: map[string]string{"adjustment_temperature": "1.2", "preview": "true"}, files: []string{"a.jpg"}, statusCode: http.StatusBadRequest, code: response.MultiFilePreviewErrorCode},
+		{name: "too many files", fields: map[string]string{"adjustment_temperature": "1.2"}, files: []string{"a.jpg", "b.jpg", "c.jpg"}, statusCode: http.StatusBadRequest, code: response.TooManyFilesErrorCode},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useCase := &fakeImageAdjustmentUseCase{}
+			files := map[string][]byte{}
+			for _, name := range test.files {
+				files[name] = upload
+			}
+
+			request := multipartRequest(t, "/api/v1/image_adjustment/temperature", test.fields, files, test.files)
+			recorder, body, _ := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageAdjustmentTemperature)
+			assert.Equal(t, test.statusCode, recorder.Code)
+			assert.Equal(t, test.code, body.Code)
+		})
+	}
+}
+
+func TestImageWhiteBalanceMapsTheAnchor(t *testing.T) {
+	upload := jpegFile(t)
+	files := map[string][]byte{"a.jpg": upload, "notes.txt": []byte("not an image"), "c.jpg": upload}
+	order := []string{"a.jpg", "notes.txt", "c.jpg"}
+	doneResults := func(requests []domain.ImageAdjustmentRequest) []domain.ImageAdjustmentFileResult {
+		results := make([]domain.ImageAdjustmentFileResult, len(requests))
+		for index, request := range requests {
+			results[index] = domain.ImageAdjustmentFileResult{FileName: request.FileHeader.Filename, Status: domain.BatchEntryDone}
+		}
+		return results
+	}
+
+	// anchor_index points into files[], the usecase only sees the valid files
+	useCase := &fakeImageAdjustmentUseCase{results: doneResults}
+	request := multipartRequest(t, "/api/v1/image_adjustment/white_balance", map[string]string{"strategy": "anchor", "anchor_index": "2", "async": "true"}, files, order)
+	recorder, body, results := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageWhiteBalance)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 1, useCase.whiteBalanceRequest.AnchorIndex)
+	if assert.Len(t, useCase.whiteBalanceRequest.Files, 2) {
+		// every file is adjusted right away with unchanged temperature
+		assert.Equal(t, 1.0, useCase.whiteBalanceRequest.Files[0].AdjustmentTemperature)
+		assert.Empty(t, useCase.whiteBalanceRequest.Files[0].Async)
+	}
+	data, _ := json.Marshal(body.Data)
+	var result domain.WhiteBalanceResponse
+	assert.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, 2, result.AnchorIndex)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, []string{"a.jpg", "notes.txt", "c.jpg"}, []string{results[0].FileName, results[1].FileName, results[2].FileName})
+		assert.Equal(t, domain.BatchEntryFailed, results[1].Status)
+	}
+
+	// an anchor which failed validation is rejected
+	useCase = &fakeImageAdjustmentUseCase{results: doneResults}
+	request = multipartRequest(t, "/api/v1/image_adjustment/white_balance", map[string]string{"strategy": "anchor", "anchor_index": "1"}, files, order)
+	recorder, body, _ = serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageWhiteBalance)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.InvalidAnchorErrorCode, body.Code)
+	assert.Equal(t, -1, useCase.whiteBalanceRequest.AnchorIndex)
+}
+
+func TestImageHistogramTakesTheAdjustment(t *testing.T) {
+	useCase := &fakeImageAdjustmentUseCase{}
+	request := multipartRequest(t, "/api/v1/image_adjustment/histogram", map[string]string{"image_id": "stored"}, nil, nil)
+	recorder, _, _ := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageHistogram)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "stored", useCase.histogramRequest.ImageID)
+	assert.Nil(t, useCase.histogramRequest.Adjustment)
+
+	// the parameters of the temperature endpoint give the histogram of the adjusted image
+	request = multipartRequest(t, "/api/v1/image_adjustment/histogram", map[string]string{"image_id": "stored", "adjustment_temperature": "1.2", "denoise": "median"}, nil, nil)
+	recorder, body, _ := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageHistogram)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	if assert.NotNil(t, useCase.histogramRequest.Adjustment) {
+		assert.Equal(t, 1.2, useCase.histogramRequest.Adjustment.AdjustmentTemperature)
+		assert.Equal(t, "median", useCase.histogramRequest.Adjustment.Denoise)
+	}
+	data, _ := json.Marshal(body.Data)
+	var result domain.HistogramResponse
+	assert.NoError(t, json.Unmarshal(data, &result))
+	if assert.NotNil(t, result.Adjusted) {
+		assert.Equal(t, 8, result.Adjusted.Width)
+	}
+
+	// an invalid adjustment is rejected
+	request = multipartRequest(t, "/api/v1/image_adjustment/histogram", map[string]string{"image_id": "stored", "adjustment_temperature": "1.2", "denoise": "unknown"}, nil, nil)
+	recorder, body, _ = serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageHistogram)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.ApiValidationCodeError, body.Code)
+}
+
+func TestAdjustmentRequestTakesTheTint(t *testing.T) {
+	presets := &fakePresetUseCase{presets: map[string]domain.PresetParams{"magenta": {AdjustmentTemperature: 1.1, Tint: 40}}}
+	histogram := func(useCase *fakeImageAdjustmentUseCase, fields map[string]string) (*httptest.ResponseRecorder, response.ApiResponse) {
+		request := multipartRequest(t, "/api/v1/image_adjustment/histogram", fields, nil, nil)
+		recorder, body, _ := serveFiles(t, useCase, request, func(handler *ImageAdjustmentHandler) {
+			handler.PresetUsecase = presets
+			handler.ImageHistogram()
+		})
+		return recorder, body
+	}
+
+	useCase := &fakeImageAdjustmentUseCase{}
+	recorder, _ := histogram(useCase, map[string]string{"image_id": "stored", "adjustment_temperature": "1.2", "tint": "-25"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	if assert.NotNil(t, useCase.histogramRequest.Adjustment) {
+		assert.Equal(t, -25.0, useCase.histogramRequest.Adjustment.Tint)
+	}
+
+	// the tint of a preset is used unless the request sends one
+	recorder, _ = histogram(useCase, map[string]string{"image_id": "stored", "preset": "magenta"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	if assert.NotNil(t, useCase.histogramRequest.Adjustment) {
+		assert.Equal(t, 40.0, useCase.histogramRequest.Adjustment.Tint)
+		assert.Equal(t, 1.1, useCase.histogramRequest.Adjustment.AdjustmentTemperature)
+	}
+	recorder, _ = histogram(useCase, map[string]string{"image_id": "stored", "preset": "magenta", "tint": "-10"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, -10.0, useCase.histogramRequest.Adjustment.Tint)
+
+	// a tint beyond -100 to 100 is rejected
+	recorder, body := histogram(&fakeImageAdjustmentUseCase{}, map[string]string{"image_id": "stored", "adjustment_temperature": "1.2", "tint": "150"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.ApiValidationCodeError, body.Code)
+}
+
+func TestAdjustmentRequestRejectsNonFiniteRotate(t *testing.T) {
+	for _, rotate := range []string{"NaN", "Inf", "-Inf"} {
+		t.Run(rotate, func(t *testing.T) {
+			useCase := &fakeImageAdjustmentUseCase{}
+			request := multipartRequest(t, "/api/v1/image_adjustment/histogram", map[string]string{"image_id": "stored", "adjustment_temperature": "1.2", "rotate": rotate}, nil, nil)
+			recorder, body, _ := serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageHistogram)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, response.ApiValidationCodeError, body.Code)
+			assert.Empty(t, useCase.histogramRequest.ImageID)
+
+			upload := jpegFile(t)
+			request = multipartRequest(t, "/api/v1/image_adjustment/temperature", map[string]string{"adjustment_temperature": "1.2", "rotate": rotate}, map[string][]byte{"a.jpg": upload}, []string{"a.jpg"})
+			recorder, body, _ = serveFiles(t, useCase, request, (*ImageAdjustmentHandler).ImageAdjustmentTemperature)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, response.ApiValidationCodeError, body.Code)
+			assert.Empty(t, useCase.requests)
+		})
+	}
+}
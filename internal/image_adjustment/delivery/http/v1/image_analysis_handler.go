@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/validator"
+)
+
+// ImageHistogram
+// @Title ImageHistogram
+// @Tags ImageAdjustment
+// @Summary ImageHistogram returns the r, g, b and luminance histograms with clipping and statistics of an uploaded or stored image, with adjustment_temperature also those of the adjusted image
+// @Produce json
+// @Produce image/png
+// @Param Accept-Language header string false "lang"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 408 {object} swagger.RequestTimeoutResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        file   formData  file    false  "jpeg image, required without image_id"
+// @Param        image_id  formData  string  false  "id of an image uploaded to /v1/images"
+// @Param        render  formData  string  false  "render = png answers the histogram drawn as a png instead of json, the adjusted one below the original"
+// @Param        adjustment_temperature  formData  string  false  "also return the histogram of the image adjusted with the parameters of /v1/image_adjustment/temperature"
+// @Param        preset  formData  string  false  "also return the histogram of the image adjusted with a preset, parameters sent with the request override it"
+// @Router /v1/image_adjustment/histogram [post]
+func (h *ImageAdjustmentHandler) ImageHistogram() {
+	request := domain.HistogramRequest{
+		ImageID: h.GetString("image_id"),
+		Render:  h.GetString("render"),
+	}
+	if file, fileHeader, err := h.GetFile("file"); err == nil {
+		defer file.Close()
+		request.File = file
+		request.FileHeader = fileHeader
+	}
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	// The adjustment takes the parameters of the temperature endpoint
+	if h.GetString("adjustment_temperature") != "" || h.GetString("preset") != "" {
+		adjustment, err := h.adjustmentRequest()
+		if err != nil {
+			h.responseAdjustmentError(err)
+			return
+		}
+		if err := validator.Validate.ValidateStruct(&adjustment); err != nil {
+			h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+			h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+			return
+		}
+		request.Adjustment = &adjustment
+	}
+
+	// A stored image is analysed when no file is uploaded
+	if request.File != nil || request.ImageID == "" {
+		if err := request.ValidateFile(); err != nil {
+			h.responseFileError(err)
+			return
+		}
+	}
+
+	result, err := h.Usecase.ImageHistogram(h.Ctx, request)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	if request.Render == domain.HistogramRenderPNG {
+		h.Ctx.Output.Header("Content-Type", "image/png")
+		h.Ctx.Output.Body(result.Image)
+	} else {
+		h.Ok(h.Ctx, h.Tr("message.success"), result)
+	}
+	return
+}
+
+// ImagePalette
+// @Title ImagePalette
+// @Tags ImageAdjustment
+// @Summary ImagePalette returns the dominant colours of an uploaded or stored image, with adjustment_temperature also those of the adjusted image
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 408 {object} swagger.RequestTimeoutResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        file   formData  file    false  "jpeg image, required without image_id"
+// @Param        image_id  formData  string  false  "id of an image uploaded to /v1/images"
+// @Param        colors  formData  int  false  "number of colours 1-16 (default 5)"
+// @Param        adjustment_temperature  formData  string  false  "also return the palette of the image adjusted with the parameters of /v1/image_adjustment/temperature"
+// @Param        preset  formData  string  false  "also return the palette of the image adjusted with a preset, parameters sent with the request override it"
+// @Router /v1/image_adjustment/palette [post]
+func (h *ImageAdjustmentHandler) ImagePalette() {
+	request := domain.PaletteRequest{
+		ImageID: h.GetString("image_id"),
+		Colors:  helper.StringToInt(h.GetString("colors")),
+	}
+	if file, fileHeader, err := h.GetFile("file"); err == nil {
+		defer file.Close()
+		request.File = file
+		request.FileHeader = fileHeader
+	}
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	// The adjustment takes the parameters of the temperature endpoint
+	if h.GetString("adjustment_temperature") != "" || h.GetString("preset") != "" {
+		adjustment, err := h.adjustmentRequest()
+		if err != nil {
+			h.responseAdjustmentError(err)
+			return
+		}
+		if err := validator.Validate.ValidateStruct(&adjustment); err != nil {
+			h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+			h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+			return
+		}
+		request.Adjustment = &adjustment
+	}
+
+	if request.File != nil || request.ImageID == "" {
+		if err := request.ValidateFile(); err != nil {
+			h.responseFileError(err)
+			return
+		}
+	}
+
+	result, err := h.Usecase.ImagePalette(h.Ctx, request)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// responseFileError answers the error of validating an uploaded file
+func (h *ImageAdjustmentHandler) responseFileError(err error) {
+	if errors.Is(err, response.ErrRequiredFile) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.RequiredFileErrorCode, response.ErrorCodeText(response.RequiredFileErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrInvalidFormatFileJpeg) {
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidFormatFileJpegErrorCode, response.ErrorCodeText(response.InvalidFormatFileJpegErrorCode, h.Locale.Lang), err)
+		return
+	}
+	h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+	h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+}
@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/validator"
+)
+
+// CreateImage
+// @Title CreateImage
+// @Tags Image
+// @Summary CreateImage, keep the owner_token of the response to delete the image or read its history
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id for retention, defaults to the caller ip"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 408 {object} swagger.RequestTimeoutResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        file   formData  file    true  "file"
+// @Router /v1/images [post]
+func (h *ImageAdjustmentHandler) CreateImage() {
+	file, fileHeader, err := h.GetFile("file")
+	if err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	request := domain.ImageRequest{
+		File:       file,
+		FileHeader: fileHeader,
+		ClientID:   helper.InlineConditionString(h.Ctx.Input.Header("X-Client-ID") != "", h.Ctx.Input.Header("X-Client-ID"), h.Ctx.Input.IP()),
+	}
+
+	if err := request.ValidateFile(); err != nil {
+		if errors.Is(err, response.ErrRequiredFile) {
+			h.ResponseError(h.Ctx, http.StatusBadRequest, response.RequiredFileErrorCode, response.ErrorCodeText(response.RequiredFileErrorCode, h.Locale.Lang), err)
+			return
+		}
+		if errors.Is(err, response.ErrInvalidFormatFileJpeg) {
+			h.ResponseError(h.Ctx, http.StatusBadRequest, response.InvalidFormatFileJpegErrorCode, response.ErrorCodeText(response.InvalidFormatFileJpegErrorCode, h.Locale.Lang), err)
+			return
+		}
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	result, err := h.Usecase.CreateImage(h.Ctx, request)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// GetImage
+// @Title GetImage
+// @Tags Image
+// @Summary GetImage
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "image id"
+// @Router /v1/images/{id} [get]
+func (h *ImageAdjustmentHandler) GetImage() {
+	result, err := h.Usecase.GetImage(h.Ctx, h.Ctx.Input.Param(":id"))
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// DeleteImage
+// @Title DeleteImage
+// @Tags Image
+// @Summary DeleteImage removes the upload and its outputs of the caller, objects other clients still use are kept
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id the image was uploaded with, defaults to the caller ip"
+// @Param X-Owner-Token header string true "owner_token returned by the upload"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.UnauthorizedResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "image id"
+// @Router /v1/images/{id} [delete]
+func (h *ImageAdjustmentHandler) DeleteImage() {
+	err := h.Usecase.DeleteImage(h.Ctx, h.imageOwnerRequest())
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), nil)
+	return
+}
+
+// imageOwnerRequest identifies the caller by the client id and the owner token of the upload
+func (h *ImageAdjustmentHandler) imageOwnerRequest() domain.ImageOwnerRequest {
+	return domain.ImageOwnerRequest{
+		ID:         h.Ctx.Input.Param(":id"),
+		ClientID:   helper.InlineConditionString(h.Ctx.Input.Header("X-Client-ID") != "", h.Ctx.Input.Header("X-Client-ID"), h.Ctx.Input.IP()),
+		OwnerToken: h.Ctx.Input.Header("X-Owner-Token"),
+	}
+}
+
+// ImageTemperature
+// @Title ImageTemperature
+// @Tags Image
+// @Summary ImageTemperature adjusts an uploaded image, takes the parameters of /v1/image_adjustment/temperature except file and store_input
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id for retention, defaults to the caller ip"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 408 {object} swagger.RequestTimeoutResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "image id"
+// @Param        adjustment_temperature  formData  string  false  "adjustment_temperature, required without preset"
+// @Param        preset  formData  string  false  "name of a preset filling in every parameter not sent with the request"
+// @Param        preview  formData  string  false  "preview = true or false, a preview returns the image and stores nothing"
+// @Param        async  formData  string  false  "async = true answers 202 with a job, poll /v1/jobs/{id} for the result"
+// @Param        callback_url  formData  string  false  "url which receives a signed POST once the job is done or failed"
+// @Param        width  formData  int  false  "output width in pixels"
+// @Param        height  formData  int  false  "output height in pixels"
+// @Param        fit  formData  string  false  "fit = contain, cover, fill or inside (default inside)"
+// @Param        sizes  formData  string  false  "rendition widths, e.g. [320,640,1280], clamped to the source width"
+// @Router /v1/images/{id}/temperature [post]
+func (h *ImageAdjustmentHandler) ImageTemperature() {
+	request, err := h.adjustmentRequest()
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	request.StoreInput = ""
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	if request.Async == "true" {
+		job, err := h.Usecase.EnqueueImageTemperature(h.Ctx, h.Ctx.Input.Param(":id"), request)
+		if err != nil {
+			h.responseAdjustmentError(err)
+			return
+		}
+		h.Accepted(h.Ctx, h.Tr("message.success"), job)
+		return
+	}
+
+	result, err := h.Usecase.ImageTemperature(h.Ctx, h.Ctx.Input.Param(":id"), request)
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	if request.Preview == "true" {
+		h.Ctx.Output.Header("Content-Type", "image/jpeg")
+		h.Ctx.Output.Body(result.OutputImage)
+	} else {
+		h.Ok(h.Ctx, h.Tr("message.success"), result)
+	}
+	return
+}
+
+// FetchImageAdjustments
+// @Title FetchImageAdjustments
+// @Tags Image
+// @Summary FetchImageAdjustments returns the adjustments the caller requested of an image, latest first
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Client-ID header string false "client id the image was uploaded with, defaults to the caller ip"
+// @Param X-Owner-Token header string true "owner_token returned by the upload"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.UnauthorizedResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "image id"
+// @Router /v1/images/{id}/adjustments [get]
+func (h *ImageAdjustmentHandler) FetchImageAdjustments() {
+	result, err := h.Usecase.FetchImageAdjustments(h.Ctx, h.imageOwnerRequest())
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// GetJob
+// @Title GetJob
+// @Tags Job
+// @Summary GetJob reports the state of an adjustment job, queued, running, done or failed, with the result urls once done
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "job id"
+// @Router /v1/jobs/{id} [get]
+func (h *ImageAdjustmentHandler) GetJob() {
+	result, err := h.Usecase.GetJob(h.Ctx, h.Ctx.Input.Param(":id"))
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// FetchJobDeliveries
+// @Title FetchJobDeliveries
+// @Tags Job
+// @Summary FetchJobDeliveries lists every attempt to post the job outcome to its callback_url
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        id  path  string  true  "job id"
+// @Router /v1/jobs/{id}/deliveries [get]
+func (h *ImageAdjustmentHandler) FetchJobDeliveries() {
+	result, err := h.Usecase.FetchJobDeliveries(h.Ctx, h.Ctx.Input.Param(":id"))
+	if err != nil {
+		h.responseAdjustmentError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	beego "github.com/beego/beego/v2/server/web"
+	"github.com/radyatamaa/image-temperature-adjustment/internal"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/validator"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+)
+
+// PresetHandler manages the adjustment presets, every request needs the admin api key in X-Admin-Key
+type PresetHandler struct {
+	ZapLogger zaplogger.Logger
+	internal.BaseController
+	response.ApiResponse
+	Usecase     domain.PresetUseCase
+	AdminApiKey string
+}
+
+func NewPresetHandler(useCase domain.PresetUseCase, zapLogger zaplogger.Logger, adminApiKey string) {
+	pHandler := &PresetHandler{
+		ZapLogger:   zapLogger,
+		Usecase:     useCase,
+		AdminApiKey: adminApiKey,
+	}
+	beego.Router("/api/v1/presets", pHandler, "get:FetchPresets;post:CreatePreset")
+	beego.Router("/api/v1/presets/:name", pHandler, "get:GetPreset;put:UpdatePreset;delete:DeletePreset")
+}
+
+func (h *PresetHandler) Prepare() {
+	h.SetLangVersion()
+	requireAdminApiKey(&h.BaseController, h.ApiResponse, h.AdminApiKey)
+}
+
+// CreatePreset
+// @Title CreatePreset
+// @Tags Preset
+// @Summary CreatePreset
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 409 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        name  formData  string  true  "preset name of lower case letters and digits joined by dashes, e.g. golden-hour"
+// @Param        description  formData  string  false  "description"
+// @Param        adjustment_temperature  formData  string  true  "adjustment_temperature"
+// @Param        white_balance_gains  formData  string  false  "r,g,b gains applied before the temperature pass"
+// @Param        tint  formData  number  false  "tint from green -100 to magenta 100"
+// @Param        denoise  formData  string  false  "denoise = median or bilateral"
+// @Param        denoise_radius  formData  int  false  "denoise window radius 1-5"
+// @Param        blur_radius  formData  number  false  "gaussian blur sigma in pixels"
+// @Param        sharpen_amount  formData  number  false  "unsharp mask amount"
+// @Param        sharpen_radius  formData  number  false  "unsharp mask radius in pixels"
+// @Param        sharpen_threshold  formData  number  false  "unsharp mask threshold 0-255"
+// @Router /v1/presets [post]
+func (h *PresetHandler) CreatePreset() {
+	request := h.presetRequest(h.GetString("name"))
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	result, err := h.Usecase.CreatePreset(h.Ctx, request)
+	if err != nil {
+		h.responsePresetError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// FetchPresets
+// @Title FetchPresets
+// @Tags Preset
+// @Summary FetchPresets returns every preset by name
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Router /v1/presets [get]
+func (h *PresetHandler) FetchPresets() {
+	result, err := h.Usecase.FetchPresets(h.Ctx)
+	if err != nil {
+		h.responsePresetError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// GetPreset
+// @Title GetPreset
+// @Tags Preset
+// @Summary GetPreset
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        name  path  string  true  "preset name"
+// @Router /v1/presets/{name} [get]
+func (h *PresetHandler) GetPreset() {
+	result, err := h.Usecase.GetPreset(h.Ctx, h.Ctx.Input.Param(":name"))
+	if err != nil {
+		h.responsePresetError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// UpdatePreset
+// @Title UpdatePreset
+// @Tags Preset
+// @Summary UpdatePreset replaces the description and every parameter of a preset
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 400 {object} swagger.BadRequestErrorValidationResponse{errors=[]swagger.ValidationErrors,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        name  path  string  true  "preset name"
+// @Param        description  formData  string  false  "description"
+// @Param        adjustment_temperature  formData  string  true  "adjustment_temperature"
+// @Param        white_balance_gains  formData  string  false  "r,g,b gains applied before the temperature pass"
+// @Param        tint  formData  number  false  "tint from green -100 to magenta 100"
+// @Param        denoise  formData  string  false  "denoise = median or bilateral"
+// @Param        denoise_radius  formData  int  false  "denoise window radius 1-5"
+// @Param        blur_radius  formData  number  false  "gaussian blur sigma in pixels"
+// @Param        sharpen_amount  formData  number  false  "unsharp mask amount"
+// @Param        sharpen_radius  formData  number  false  "unsharp mask radius in pixels"
+// @Param        sharpen_threshold  formData  number  false  "unsharp mask threshold 0-255"
+// @Router /v1/presets/{name} [put]
+func (h *PresetHandler) UpdatePreset() {
+	request := h.presetRequest(h.Ctx.Input.Param(":name"))
+
+	if err := validator.Validate.ValidateStruct(&request); err != nil {
+		h.Ctx.Input.SetData("stackTrace", h.ZapLogger.SetMessageLog(err))
+		h.ResponseError(h.Ctx, http.StatusBadRequest, response.ApiValidationCodeError, response.ErrorCodeText(response.ApiValidationCodeError, h.Locale.Lang), err)
+		return
+	}
+
+	result, err := h.Usecase.UpdatePreset(h.Ctx, request)
+	if err != nil {
+		h.responsePresetError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), result)
+	return
+}
+
+// DeletePreset
+// @Title DeletePreset
+// @Tags Preset
+// @Summary DeletePreset
+// @Produce json
+// @Param Accept-Language header string false "lang"
+// @Param X-Admin-Key header string true "admin api key"
+// @Success 200 {object} swagger.BaseResponse{errors=[]object,data=object}
+// @Failure 401 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 404 {object} swagger.BadRequestResponse{errors=[]object,data=object}
+// @Failure 500 {object} swagger.InternalServerErrorResponse{errors=[]object,data=object}
+// @Param        name  path  string  true  "preset name"
+// @Router /v1/presets/{name} [delete]
+func (h *PresetHandler) DeletePreset() {
+	err := h.Usecase.DeletePreset(h.Ctx, h.Ctx.Input.Param(":name"))
+	if err != nil {
+		h.responsePresetError(err)
+		return
+	}
+	h.Ok(h.Ctx, h.Tr("message.success"), nil)
+	return
+}
+
+// presetRequest reads the preset name and parameters, with the same names as on the adjustment endpoints
+func (h *PresetHandler) presetRequest(name string) domain.PresetRequest {
+	return domain.PresetRequest{
+		Name:        name,
+		Description: h.GetString("description"),
+		Params: domain.PresetParams{
+			AdjustmentTemperature: helper.StringToFloat(h.GetString("adjustment_temperature")),
+			WhiteBalanceGains:     helper.StringToFloatSlice(h.GetString("white_balance_gains")),
+			Tint:                  helper.StringToFloat(h.GetString("tint")),
+			Denoise:               h.GetString("denoise"),
+			DenoiseRadius:         helper.StringToInt(h.GetString("denoise_radius")),
+			BlurRadius:            helper.StringToFloat(h.GetString("blur_radius")),
+			SharpenAmount:         helper.StringToFloat(h.GetString("sharpen_amount")),
+			SharpenRadius:         helper.StringToFloat(h.GetString("sharpen_radius")),
+			SharpenThreshold:      helper.StringToFloat(h.GetString("sharpen_threshold")),
+		},
+	}
+}
+
+func (h *PresetHandler) responsePresetError(err error) {
+	if errors.Is(err, response.ErrPresetNotFound) {
+		h.ResponseError(h.Ctx, http.StatusNotFound, response.PresetNotFoundErrorCode, response.ErrorCodeText(response.PresetNotFoundErrorCode, h.Locale.Lang), err)
+		return
+	}
+	if errors.Is(err, response.ErrPresetAlreadyExists) {
+		h.ResponseError(h.Ctx, http.StatusConflict, response.DataAlreadyExistCodeError, response.ErrorCodeText(response.DataAlreadyExistCodeError, h.Locale.Lang), err)
+		return
+	}
+	h.ResponseError(h.Ctx, http.StatusInternalServerError, response.ServerErrorCode, response.ErrorCodeText(response.ServerErrorCode, h.Locale.Lang), err)
+}
@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	beego "github.com/beego/beego/v2/server/web"
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"github.com/stretchr/testify/assert"
+)
+
+func (f *fakePresetUseCase) CreatePreset(beegoCtx *beegoContext.Context, request domain.PresetRequest) (domain.PresetResponse, error) {
+	if _, ok := f.presets[request.Name]; ok {
+		return domain.PresetResponse{}, response.ErrPresetAlreadyExists
+	}
+	f.presets[request.Name] = request.Params
+	return domain.PresetResponse{Name: request.Name, Description: request.Description, Params: request.Params}, nil
+}
+
+func (f *fakePresetUseCase) UpdatePreset(beegoCtx *beegoContext.Context, request domain.PresetRequest) (domain.PresetResponse, error) {
+	if _, ok := f.presets[request.Name]; !ok {
+		return domain.PresetResponse{}, response.ErrPresetNotFound
+	}
+	f.presets[request.Name] = request.Params
+	return domain.PresetResponse{Name: request.Name, Description: request.Description, Params: request.Params}, nil
+}
+
+func (f *fakePresetUseCase) DeletePreset(beegoCtx *beegoContext.Context, name string) error {
+	if _, ok := f.presets[name]; !ok {
+		return response.ErrPresetNotFound
+	}
+	delete(f.presets, name)
+	return nil
+}
+
+// servePreset runs Prepare and, unless it stopped the request, action for the preset name of the path
+func servePreset(t *testing.T, useCase *fakePresetUseCase, method, name string, fields map[string]string, action func(handler *PresetHandler)) (*httptest.ResponseRecorder, response.ApiResponse) {
+	request := multipartRequest(t, "/api/v1/presets/"+name, fields, nil, nil)
+	request.Method = method
+	request.Header.Set("X-Admin-Key", "secret")
+	recorder := httptest.NewRecorder()
+
+	handler := &PresetHandler{
+		ZapLogger:   zaplogger.NewZapLogger(filepath.Join(t.TempDir(), "test.log"), ""),
+		Usecase:     useCase,
+		AdminApiKey: "secret",
+	}
+	helper.PrepareHandler(&handler.Controller, request, recorder)
+	handler.Ctx.Input.SetParam(":name", name)
+
+	func() {
+		defer func() {
+			if err := recover(); err != nil && err != beego.ErrAbort {
+				panic(err)
+			}
+		}()
+		handler.Prepare()
+		action(handler)
+	}()
+
+	var body response.ApiResponse
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder, body
+}
+
+func TestPresetHandlerCreatePreset(t *testing.T) {
+	useCase := &fakePresetUseCase{presets: map[string]domain.PresetParams{}}
+	create := func(fields map[string]string) (*httptest.ResponseRecorder, response.ApiResponse) {
+		return servePreset(t, useCase, http.MethodPost, "", fields, func(handler *PresetHandler) { handler.CreatePreset() })
+	}
+
+	recorder, body := create(map[string]string{"name": "golden-hour", "adjustment_temperature": "1.2", "tint": "15", "denoise": "median", "denoise_radius": "2"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "OK", body.Code)
+	assert.Equal(t, domain.PresetParams{AdjustmentTemperature: 1.2, Tint: 15, Denoise: "median", DenoiseRadius: 2}, useCase.presets["golden-hour"])
+
+	// a preset of the name exists already
+	recorder, body = create(map[string]string{"name": "golden-hour", "adjustment_temperature": "1.4"})
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, response.DataAlreadyExistCodeError, body.Code)
+	assert.Equal(t, 1.2, useCase.presets["golden-hour"].AdjustmentTemperature)
+
+	recorder, body = create(map[string]string{"name": "blue-hour", "adjustment_temperature": "0.8", "denoise": "foo"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.ApiValidationCodeError, body.Code)
+	if assert.Len(t, body.Errors, 1) {
+		assert.Equal(t, "Denoise", body.Errors[0].Field)
+	}
+	assert.NotContains(t, useCase.presets, "blue-hour")
+
+	recorder, body = create(map[string]string{"name": "Blue Hour", "adjustment_temperature": "0.8"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.ApiValidationCodeError, body.Code)
+	assert.Len(t, useCase.presets, 1)
+}
+
+func TestPresetHandlerUpdatePreset(t *testing.T) {
+	useCase := &fakePresetUseCase{presets: map[string]domain.PresetParams{"golden-hour": {AdjustmentTemperature: 1.2, Tint: 15}}}
+	update := func(name string, fields map[string]string) (*httptest.ResponseRecorder, response.ApiResponse) {
+		return servePreset(t, useCase, http.MethodPut, name, fields, func(handler *PresetHandler) { handler.UpdatePreset() })
+	}
+
+	// every parameter is replaced, the tint which was not sent is gone
+	recorder, body := update("golden-hour", map[string]string{"adjustment_temperature": "1.3", "blur_radius": "1.5"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "OK", body.Code)
+	assert.Equal(t, domain.PresetParams{AdjustmentTemperature: 1.3, BlurRadius: 1.5}, useCase.presets["golden-hour"])
+
+	recorder, body = update("golden-hour", map[string]string{"adjustment_temperature": "1.4", "denoise": "foo"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, response.ApiValidationCodeError, body.Code)
+	if assert.Len(t, body.Errors, 1) {
+		assert.Equal(t, "Denoise", body.Errors[0].Field)
+	}
+	assert.Equal(t, 1.3, useCase.presets["golden-hour"].AdjustmentTemperature)
+
+	recorder, body = update("blue-hour", map[string]string{"adjustment_temperature": "0.8"})
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, response.PresetNotFoundErrorCode, body.Code)
+	assert.NotContains(t, useCase.presets, "blue-hour")
+}
+
+func TestPresetHandlerDeletePreset(t *testing.T) {
+	useCase := &fakePresetUseCase{presets: map[string]domain.PresetParams{"golden-hour": {AdjustmentTemperature: 1.2}}}
+	remove := func(name string) (*httptest.ResponseRecorder, response.ApiResponse) {
+		return servePreset(t, useCase, http.MethodDelete, name, nil, func(handler *PresetHandler) { handler.DeletePreset() })
+	}
+
+	recorder, body := remove("golden-hour")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "OK", body.Code)
+	assert.Empty(t, useCase.presets)
+
+	recorder, body = remove("golden-hour")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, response.PresetNotFoundErrorCode, body.Code)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"gorm.io/gorm"
+)
+
+type adjustmentJobRepository struct {
+	db *gorm.DB
+}
+
+func NewAdjustmentJobRepository(db *gorm.DB) domain.AdjustmentJobRepository {
+	return &adjustmentJobRepository{
+		db: db,
+	}
+}
+
+func (r *adjustmentJobRepository) Create(ctx context.Context, job *domain.AdjustmentJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *adjustmentJobRepository) Update(ctx context.Context, job *domain.AdjustmentJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *adjustmentJobRepository) GetByID(ctx context.Context, id string) (job domain.AdjustmentJob, err error) {
+	err = r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	return job, err
+}
+
+func (r *adjustmentJobRepository) FetchByStatus(ctx context.Context, statuses []string, limit int) (jobs []domain.AdjustmentJob, err error) {
+	err = r.db.WithContext(ctx).
+		Where("status IN ?", statuses).
+		Order("queued_at asc").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *adjustmentJobRepository) FetchByCallbackStatus(ctx context.Context, status string, limit int) (jobs []domain.AdjustmentJob, err error) {
+	err = r.db.WithContext(ctx).
+		Where("callback_status = ?", status).
+		Order("finished_at asc").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *adjustmentJobRepository) FetchByImageID(ctx context.Context, imageID, clientID string, limit int) (jobs []domain.AdjustmentJob, err error) {
+	err = r.db.WithContext(ctx).
+		Where("image_id = ? AND client_id = ?", imageID, clientID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func adjustmentJobRows(jobs ...domain.AdjustmentJob) *sqlmock.Rows {
+	_, columns := helper.GetValueAndColumnStructToDriverValue(domain.AdjustmentJob{})
+	rows := sqlmock.NewRows(columns)
+	for _, job := range jobs {
+		values, _ := helper.GetValueAndColumnStructToDriverValue(job)
+		// the driver only takes plain values, nullable times are nil or the time itself
+		for index, value := range values {
+			if at, ok := value.(*time.Time); ok {
+				values[index] = nil
+				if at != nil {
+					values[index] = *at
+				}
+			}
+		}
+		rows.AddRow(values...)
+	}
+	return rows
+}
+
+func sampleAdjustmentJob() domain.AdjustmentJob {
+	queuedAt := time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC)
+	finishedAt := queuedAt.Add(2 * time.Second)
+	return domain.AdjustmentJob{
+		ID:               "7d3c3a4e-5b1f-4f7a-9a53-0c1f0c7a2b11",
+		ImageID:          "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		ClientID:         "client-1",
+		Operation:        domain.OperationTemperature,
+		Params:           `{"adjustment_temperature":1.2}`,
+		Status:           domain.JobStatusDone,
+		OutputKey:        "output/abc.jpg",
+		CallbackUrl:      "https://example.com/hook",
+		CallbackStatus:   domain.CallbackStatusDelivered,
+		CallbackAttempts: 1,
+		DurationMs:       2000,
+		QueuedAt:         queuedAt,
+		StartedAt:        &queuedAt,
+		FinishedAt:       &finishedAt,
+		CreatedAt:        queuedAt,
+		UpdatedAt:        finishedAt,
+	}
+}
+
+func TestAdjustmentJobRepositoryCreate(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	job := sampleAdjustmentJob()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "adjustment_jobs"`)).
+		WithArgs(job.ID, job.ImageID, job.ClientID, job.Operation, job.Params, job.Status, job.Error, job.OutputKey,
+			job.Cached, job.Result, job.CallbackUrl, job.CallbackStatus, job.CallbackAttempts, job.CallbackNextAttemptAt, job.DurationMs, job.QueuedAt, job.StartedAt, job.FinishedAt,
+			sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Create(context.Background(), &job))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryCreateError(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	job := sampleAdjustmentJob()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "adjustment_jobs"`)).WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	assert.EqualError(t, repository.Create(context.Background(), &job), "duplicate key")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryUpdate(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	job := sampleAdjustmentJob()
+	job.Status = domain.JobStatusFailed
+	job.Error = "boom"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "adjustment_jobs" SET "image_id"=$1,"client_id"=$2,"operation"=$3,"params"=$4,"status"=$5,"error"=$6`)).
+		WithArgs(job.ImageID, job.ClientID, job.Operation, job.Params, domain.JobStatusFailed, "boom", job.OutputKey,
+			job.Cached, job.Result, job.CallbackUrl, job.CallbackStatus, job.CallbackAttempts, job.CallbackNextAttemptAt, job.DurationMs, job.QueuedAt, job.StartedAt, job.FinishedAt,
+			job.CreatedAt, sqlmock.AnyArg(), job.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Update(context.Background(), &job))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryGetByID(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	job := sampleAdjustmentJob()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE id = $1 ORDER BY "adjustment_jobs"."id" LIMIT 1`)).
+		WithArgs(job.ID).
+		WillReturnRows(adjustmentJobRows(job))
+
+	result, err := repository.GetByID(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, result.ID)
+	assert.Equal(t, job.ImageID, result.ImageID)
+	assert.Equal(t, job.Status, result.Status)
+	assert.Equal(t, job.Params, result.Params)
+	assert.Equal(t, *job.FinishedAt, *result.FinishedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryGetByIDNotFound(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE id = $1`)).
+		WithArgs("missing").
+		WillReturnRows(adjustmentJobRows())
+
+	_, err = repository.GetByID(context.Background(), "missing")
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryFetchByImageID(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	latest := sampleAdjustmentJob()
+	older := sampleAdjustmentJob()
+	older.ID = "0b8e2b55-0e1a-4a8e-8d0c-3a5f5f1e9c22"
+	older.CreatedAt = latest.CreatedAt.Add(-time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE image_id = $1 AND client_id = $2 ORDER BY created_at desc LIMIT 100`)).
+		WithArgs(latest.ImageID, latest.ClientID).
+		WillReturnRows(adjustmentJobRows(latest, older))
+
+	jobs, err := repository.FetchByImageID(context.Background(), latest.ImageID, latest.ClientID, 100)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, []string{latest.ID, older.ID}, []string{jobs[0].ID, jobs[1].ID})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryFetchByStatus(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	queued := sampleAdjustmentJob()
+	queued.Status = domain.JobStatusQueued
+	queued.StartedAt = nil
+	queued.FinishedAt = nil
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE status IN ($1,$2) ORDER BY queued_at asc LIMIT 10`)).
+		WithArgs(domain.JobStatusQueued, domain.JobStatusRunning).
+		WillReturnRows(adjustmentJobRows(queued))
+
+	jobs, err := repository.FetchByStatus(context.Background(), []string{domain.JobStatusQueued, domain.JobStatusRunning}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Nil(t, jobs[0].StartedAt)
+
+	// a limit of -1 reads every job
+	mock.ExpectQuery(`^` + regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE status IN ($1) ORDER BY queued_at asc`) + `$`).
+		WithArgs(domain.JobStatusQueued).
+		WillReturnRows(adjustmentJobRows())
+
+	jobs, err = repository.FetchByStatus(context.Background(), []string{domain.JobStatusQueued}, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdjustmentJobRepositoryFetchByCallbackStatus(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewAdjustmentJobRepository(db)
+	pending := sampleAdjustmentJob()
+	pending.CallbackStatus = domain.CallbackStatusPending
+	nextAttemptAt := pending.FinishedAt.Add(4 * time.Second)
+	pending.CallbackNextAttemptAt = &nextAttemptAt
+
+	mock.ExpectQuery(`^` + regexp.QuoteMeta(`SELECT * FROM "adjustment_jobs" WHERE callback_status = $1 ORDER BY finished_at asc`) + `$`).
+		WithArgs(domain.CallbackStatusPending).
+		WillReturnRows(adjustmentJobRows(pending))
+
+	jobs, err := repository.FetchByCallbackStatus(context.Background(), domain.CallbackStatusPending, -1)
+	assert.NoError(t, err)
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, domain.CallbackStatusPending, jobs[0].CallbackStatus)
+		assert.Equal(t, 1, jobs[0].CallbackAttempts)
+		assert.Equal(t, nextAttemptAt, *jobs[0].CallbackNextAttemptAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
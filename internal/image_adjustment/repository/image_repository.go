@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type imageRepository struct {
+	db *gorm.DB
+}
+
+func NewImageRepository(db *gorm.DB) domain.ImageRepository {
+	return &imageRepository{
+		db: db,
+	}
+}
+
+func (r *imageRepository) Save(ctx context.Context, image *domain.Image) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(image).Error
+}
+
+func (r *imageRepository) GetByID(ctx context.Context, id string) (image domain.Image, err error) {
+	err = r.db.WithContext(ctx).Where("id = ?", id).First(&image).Error
+	return image, err
+}
+
+func (r *imageRepository) FetchByIDs(ctx context.Context, ids []string) (images []domain.Image, err error) {
+	if len(ids) == 0 {
+		return images, nil
+	}
+	err = r.db.WithContext(ctx).Where("id IN ?", ids).Find(&images).Error
+	return images, err
+}
+
+func (r *imageRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.Image{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func sampleImage() domain.Image {
+	return domain.Image{
+		ID:         "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		ClientID:   "client-1",
+		Width:      640,
+		Height:     480,
+		SizeBytes:  52011,
+		StorageKey: "input/b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9.jpg",
+		CreatedAt:  time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func imageRows(images ...domain.Image) *sqlmock.Rows {
+	_, columns := helper.GetValueAndColumnStructToDriverValue(domain.Image{})
+	rows := sqlmock.NewRows(columns)
+	for _, image := range images {
+		values, _ := helper.GetValueAndColumnStructToDriverValue(image)
+		rows.AddRow(values...)
+	}
+	return rows
+}
+
+func TestImageRepositorySaveKeepsTheFirstRecord(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewImageRepository(db)
+	image := sampleImage()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "images" ("id","client_id","width","height","size_bytes","storage_key","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT DO NOTHING`)).
+		WithArgs(image.ID, image.ClientID, image.Width, image.Height, image.SizeBytes, image.StorageKey, image.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Save(context.Background(), &image))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImageRepositoryGetByID(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewImageRepository(db)
+	image := sampleImage()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "images" WHERE id = $1 ORDER BY "images"."id" LIMIT 1`)).
+		WithArgs(image.ID).
+		WillReturnRows(imageRows(image))
+
+	result, err := repository.GetByID(context.Background(), image.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, image, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImageRepositoryFetchByIDs(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewImageRepository(db)
+	image := sampleImage()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "images" WHERE id IN ($1,$2)`)).
+		WithArgs(image.ID, "unknown").
+		WillReturnRows(imageRows(image))
+
+	images, err := repository.FetchByIDs(context.Background(), []string{image.ID, "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Image{image}, images)
+
+	// no ids, no query
+	images, err = repository.FetchByIDs(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImageRepositoryDelete(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewImageRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "images" WHERE id = $1`)).
+		WithArgs("a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "images" WHERE id = $1`)).
+		WithArgs("b").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Delete(context.Background(), "a"))
+	assert.True(t, errors.Is(repository.Delete(context.Background(), "b"), gorm.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
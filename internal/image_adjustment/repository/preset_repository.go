@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"gorm.io/gorm"
+)
+
+type presetRepository struct {
+	db *gorm.DB
+}
+
+func NewPresetRepository(db *gorm.DB) domain.PresetRepository {
+	return &presetRepository{
+		db: db,
+	}
+}
+
+func (r *presetRepository) Create(ctx context.Context, preset *domain.Preset) error {
+	return r.db.WithContext(ctx).Create(preset).Error
+}
+
+func (r *presetRepository) Update(ctx context.Context, preset *domain.Preset) error {
+	return r.db.WithContext(ctx).Save(preset).Error
+}
+
+func (r *presetRepository) GetByName(ctx context.Context, name string) (preset domain.Preset, err error) {
+	err = r.db.WithContext(ctx).Where("name = ?", name).First(&preset).Error
+	return preset, err
+}
+
+func (r *presetRepository) Fetch(ctx context.Context) (presets []domain.Preset, err error) {
+	err = r.db.WithContext(ctx).
+		Order("name asc").
+		Find(&presets).Error
+	return presets, err
+}
+
+func (r *presetRepository) Delete(ctx context.Context, name string) error {
+	result := r.db.WithContext(ctx).Where("name = ?", name).Delete(&domain.Preset{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
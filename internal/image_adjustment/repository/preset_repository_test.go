@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func samplePreset() domain.Preset {
+	createdAt := time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC)
+	return domain.Preset{
+		Name:        "golden-hour",
+		Description: "warm evening light",
+		Params:      `{"adjustment_temperature":1.2,"tint":15}`,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
+
+func presetRows(presets ...domain.Preset) *sqlmock.Rows {
+	_, columns := helper.GetValueAndColumnStructToDriverValue(domain.Preset{})
+	rows := sqlmock.NewRows(columns)
+	for _, preset := range presets {
+		values, _ := helper.GetValueAndColumnStructToDriverValue(preset)
+		rows.AddRow(values...)
+	}
+	return rows
+}
+
+func TestPresetRepositoryCreate(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+	preset := samplePreset()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "presets" ("name","description","params","created_at","updated_at") VALUES ($1,$2,$3,$4,$5)`)).
+		WithArgs(preset.Name, preset.Description, preset.Params, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Create(context.Background(), &preset))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryCreateError(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+	preset := samplePreset()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "presets"`)).WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	assert.EqualError(t, repository.Create(context.Background(), &preset), "duplicate key")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryUpdate(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+	preset := samplePreset()
+	preset.Description = "warmer"
+	preset.Params = `{"adjustment_temperature":1.4}`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "presets" SET "description"=$1,"params"=$2,"created_at"=$3,"updated_at"=$4 WHERE "name" = $5`)).
+		WithArgs("warmer", `{"adjustment_temperature":1.4}`, preset.CreatedAt, sqlmock.AnyArg(), preset.Name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Update(context.Background(), &preset))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryGetByName(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+	preset := samplePreset()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "presets" WHERE name = $1 ORDER BY "presets"."name" LIMIT 1`)).
+		WithArgs(preset.Name).
+		WillReturnRows(presetRows(preset))
+
+	result, err := repository.GetByName(context.Background(), preset.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, preset, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryGetByNameNotFound(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "presets" WHERE name = $1`)).
+		WithArgs("missing").
+		WillReturnRows(presetRows())
+
+	_, err = repository.GetByName(context.Background(), "missing")
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryFetch(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+	blueHour := samplePreset()
+	blueHour.Name = "blue-hour"
+	goldenHour := samplePreset()
+
+	mock.ExpectQuery(`^` + regexp.QuoteMeta(`SELECT * FROM "presets" ORDER BY name asc`) + `$`).
+		WillReturnRows(presetRows(blueHour, goldenHour))
+
+	presets, err := repository.Fetch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Preset{blueHour, goldenHour}, presets)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPresetRepositoryDelete(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewPresetRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "presets" WHERE name = $1`)).
+		WithArgs("golden-hour").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "presets" WHERE name = $1`)).
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Delete(context.Background(), "golden-hour"))
+	assert.True(t, errors.Is(repository.Delete(context.Background(), "missing"), gorm.ErrRecordNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
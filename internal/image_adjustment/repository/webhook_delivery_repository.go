@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"gorm.io/gorm"
+)
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) domain.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{
+		db: db,
+	}
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) FetchByJobID(ctx context.Context, jobID string) (deliveries []domain.WebhookDelivery, err error) {
+	err = r.db.WithContext(ctx).
+		Where("job_id = ?", jobID).
+		Order("id asc").
+		Find(&deliveries).Error
+	return deliveries, err
+}
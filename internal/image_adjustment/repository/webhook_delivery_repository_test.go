@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeliveryRepositoryCreate(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewWebhookDeliveryRepository(db)
+	delivery := domain.WebhookDelivery{JobID: "job-1", Url: "https://example.com/hook", Attempt: 1, StatusCode: 500, Error: "status 500"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhook_deliveries"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.Create(context.Background(), &delivery))
+	assert.Equal(t, uint(7), delivery.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryRepositoryFetchByJobID(t *testing.T) {
+	db, mock, err := helper.NewMockDB("")
+	assert.NoError(t, err)
+	repository := NewWebhookDeliveryRepository(db)
+	createdAt := time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "webhook_deliveries" WHERE job_id = $1 ORDER BY id asc`)).
+		WithArgs("job-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "job_id", "attempt", "delivered", "created_at"}).
+			AddRow(1, "job-1", 1, false, createdAt).
+			AddRow(2, "job-1", 2, true, createdAt.Add(time.Second)))
+
+	deliveries, err := repository.FetchByJobID(context.Background(), "job-1")
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+	assert.True(t, deliveries[1].Delivered)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
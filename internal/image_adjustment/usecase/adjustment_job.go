@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+// adjustmentHistoryLimit bounds the jobs returned for an image
+const adjustmentHistoryLimit = 100
+
+// requestParams is the json of the parameters recorded with a job, a job is never recorded without them
+func requestParams(request domain.ImageAdjustmentRequest) (string, error) {
+	request.File = nil
+	request.FileHeader = nil
+	request.ClientID = ""
+	request.CallbackUrl = ""
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// resultOf is the json of res kept with a job, signed urls expire so only the keys are kept
+func resultOf(res domain.ImageAdjustmentResponse) string {
+	res.InputFileImage = ""
+	res.OutputFileImage = ""
+	res.Renditions = append([]domain.ImageRendition(nil), res.Renditions...)
+	for index := range res.Renditions {
+		res.Renditions[index].OutputFileImage = ""
+	}
+	if res.Metrics != nil {
+		// the metrics are shared with the response, the copy keeps its heat map url
+		metrics := *res.Metrics
+		metrics.HeatmapFileImage = ""
+		res.Metrics = &metrics
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// imageIDOfKey returns the image id of an input key, empty for any other key
+func imageIDOfKey(key string) string {
+	id := strings.TrimSuffix(strings.TrimPrefix(key, inputPrefix), ".jpg")
+	if !isContentHash(id) {
+		return ""
+	}
+	return id
+}
+
+// recordContext outlives the request so the audit trail is written after a timeout too
+func (i imageAdjustmentUseCase) recordContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), i.contextTimeout)
+}
+
+// startJob records a running adjustment, recording is best effort and only parameters
+// which cannot be recorded fail the request
+func (i imageAdjustmentUseCase) startJob(imageID string, request domain.ImageAdjustmentRequest) (*domain.AdjustmentJob, error) {
+	params, err := requestParams(request)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &domain.AdjustmentJob{
+		ID:          uuid.New().String(),
+		ImageID:     imageID,
+		ClientID:    request.ClientID,
+		Operation:   domain.OperationTemperature,
+		Params:      params,
+		Status:      domain.JobStatusRunning,
+		CallbackUrl: request.CallbackUrl,
+		QueuedAt:    now,
+		StartedAt:   &now,
+	}
+
+	ctx, cancel := i.recordContext()
+	defer cancel()
+	if err := i.adjustmentJobRepository.Create(ctx, job); err != nil {
+		i.zapLogger.Errorf("record adjustment job: %s", err.Error())
+	}
+	return job, nil
+}
+
+func (i imageAdjustmentUseCase) finishJob(job *domain.AdjustmentJob, res domain.ImageAdjustmentResponse, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if job.StartedAt != nil {
+		job.DurationMs = now.Sub(*job.StartedAt).Milliseconds()
+	}
+	if err != nil {
+		job.Status = domain.JobStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = domain.JobStatusDone
+		job.OutputKey = res.OutputPathDirImage
+		job.Cached = res.Cached
+		job.Result = resultOf(res)
+		if job.ImageID == "" {
+			job.ImageID = imageIDOfKey(res.InputPathDirImage)
+		}
+	}
+
+	if job.CallbackUrl != "" {
+		job.CallbackStatus = domain.CallbackStatusPending
+	}
+
+	ctx, cancel := i.recordContext()
+	defer cancel()
+	if err := i.adjustmentJobRepository.Update(ctx, job); err != nil {
+		i.zapLogger.Errorf("record adjustment job: %s", err.Error())
+	}
+
+	if job.CallbackUrl != "" {
+		go i.deliverCallback(context.Background(), *job)
+	}
+}
+
+// saveImage records a stored input, an input stored before keeps its first record
+func (i imageAdjustmentUseCase) saveImage(inputHash, clientID string, img image.Image, sizeBytes int64) {
+	ctx, cancel := i.recordContext()
+	defer cancel()
+	err := i.imageRepository.Save(ctx, &domain.Image{
+		ID:         inputHash,
+		ClientID:   clientID,
+		Width:      img.Bounds().Dx(),
+		Height:     img.Bounds().Dy(),
+		SizeBytes:  sizeBytes,
+		StorageKey: inputKeyOf(inputHash),
+	})
+	if err != nil {
+		i.zapLogger.Errorf("record image: %s", err.Error())
+	}
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import "math"
+
+// lab is a CIE L*a*b* colour relative to the D65 white point
+type lab [3]float64
+
+// D65 reference white in XYZ
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// srgbLinear maps an 8 bit sRGB sample to linear light
+var srgbLinear = func() (table [256]float64) {
+	for value := range table {
+		c := float64(value) / 255
+		if c <= 0.04045 {
+			table[value] = c / 12.92
+		} else {
+			table[value] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+func labF(t float64) float64 {
+	if t > 216.0/24389 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27*t + 16) / 116
+}
+
+func labFInverse(t float64) float64 {
+	if cube := t * t * t; cube > 216.0/24389 {
+		return cube
+	}
+	return (116*t - 16) * 27 / 24389
+}
+
+// rgbToLab converts an 8 bit sRGB colour
+func rgbToLab(r, g, b uint8) lab {
+	lr, lg, lb := srgbLinear[r], srgbLinear[g], srgbLinear[b]
+	x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / whiteX
+	y := (0.2126729*lr + 0.7151522*lg + 0.0721750*lb) / whiteY
+	z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / whiteZ
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// labToRGB converts back to 8 bit sRGB, colours outside the gamut are clipped
+func labToRGB(colour lab) (uint8, uint8, uint8) {
+	fy := (colour[0] + 16) / 116
+	fx := fy + colour[1]/500
+	fz := fy - colour[2]/200
+	x, y, z := labFInverse(fx)*whiteX, labFInverse(fy)*whiteY, labFInverse(fz)*whiteZ
+
+	encode := func(linear float64) uint8 {
+		var c float64
+		if linear <= 0.0031308 {
+			c = linear * 12.92
+		} else {
+			c = 1.055*math.Pow(linear, 1/2.4) - 0.055
+		}
+		return uint8(math.Max(0, math.Min(255, math.Round(c*255))))
+	}
+	return encode(3.2404542*x - 1.5371385*y - 0.4985314*z),
+		encode(-0.9692660*x + 1.8760108*y + 0.0415560*z),
+		encode(0.0556434*x - 0.2040259*y + 1.0572252*z)
+}
+
+// ciede2000 is the CIEDE2000 colour difference of two colours
+func ciede2000(first, second lab) float64 {
+	l1, a1, b1 := first[0], first[1], first[2]
+	l2, a2, b2 := second[0], second[1], second[2]
+
+	c1 := math.Hypot(a1, b1)
+	c2 := math.Hypot(a2, b2)
+	meanC := (c1 + c2) / 2
+	meanC7 := math.Pow(meanC, 7)
+	g := 0.5 * (1 - math.Sqrt(meanC7/(meanC7+6103515625))) // 25^7
+	a1p, a2p := (1+g)*a1, (1+g)*a2
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+
+	hue := func(a, b float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) * 180 / math.Pi
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1p, h2p := hue(a1p, b1), hue(a2p, b2)
+
+	deltaL := l2 - l1
+	deltaC := c2p - c1p
+	var deltaH float64
+	if c1p*c2p != 0 {
+		deltaH = h2p - h1p
+		if deltaH > 180 {
+			deltaH -= 360
+		} else if deltaH < -180 {
+			deltaH += 360
+		}
+	}
+	deltaHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(deltaH/2*math.Pi/180)
+
+	meanL := (l1 + l2) / 2
+	meanCp := (c1p + c2p) / 2
+	meanH := h1p + h2p
+	if c1p*c2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180:
+			meanH /= 2
+		case h1p+h2p < 360:
+			meanH = (meanH + 360) / 2
+		default:
+			meanH = (meanH - 360) / 2
+		}
+	}
+
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	t := 1 - 0.17*math.Cos(radians(meanH-30)) + 0.24*math.Cos(radians(2*meanH)) +
+		0.32*math.Cos(radians(3*meanH+6)) - 0.20*math.Cos(radians(4*meanH-63))
+	deltaTheta := 30 * math.Exp(-math.Pow((meanH-275)/25, 2))
+	meanCp7 := math.Pow(meanCp, 7)
+	rc := 2 * math.Sqrt(meanCp7/(meanCp7+6103515625))
+	meanL50 := (meanL - 50) * (meanL - 50)
+	sl := 1 + 0.015*meanL50/math.Sqrt(20+meanL50)
+	sc := 1 + 0.045*meanCp
+	sh := 1 + 0.015*meanCp*t
+	rt := -math.Sin(radians(2*deltaTheta)) * rc
+
+	termL := deltaL / sl
+	termC := deltaC / sc
+	termH := deltaHp / sh
+	return math.Sqrt(termL*termL + termC*termC + termH*termH + rt*termC*termH)
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+const (
+	// defaultComparePosition puts the divider of a split or wipe in the middle
+	defaultComparePosition = 0.5
+	defaultBeforeLabel     = "Before"
+	defaultAfterLabel      = "After"
+)
+
+var (
+	dividerColor    = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	labelBackground = color.RGBA{A: 160}
+)
+
+// rgbaOf returns img as RGBA with its origin at 0,0
+func rgbaOf(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// compareLabelsOf reads compare_labels, "true" gives Before and After and
+// "Original,Graded" sets both texts
+func compareLabelsOf(value string) (before, after string, ok bool) {
+	if value == "" || value == "false" {
+		return "", "", false
+	}
+	parts := strings.SplitN(value, ",", 2)
+	if value == "true" || len(parts) != 2 {
+		return defaultBeforeLabel, defaultAfterLabel, true
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// compareImage renders before and after into one image: split shows before left
+// of a vertical divider, wipe above a diagonal one and side_by_side puts the two next to each other.
+// before and after have the same size, after is returned as is without compare.
+func compareImage(before, after image.Image, request domain.ImageAdjustmentRequest) image.Image {
+	if request.Compare == "" {
+		return after
+	}
+	position := request.ComparePosition
+	if position <= 0 {
+		position = defaultComparePosition
+	}
+
+	beforeImg, afterImg := rgbaOf(before), rgbaOf(after)
+	width, height := afterImg.Rect.Dx(), afterImg.Rect.Dy()
+	lineWidth := width / 400
+	if lineWidth < 2 {
+		lineWidth = 2
+	}
+	scale := labelScale(width)
+	margin := labelPadding * 2 * scale
+	beforeLabel, afterLabel, labelled := compareLabelsOf(request.CompareLabels)
+
+	var result *image.RGBA
+	switch request.Compare {
+	case domain.CompareSideBySide:
+		result = image.NewRGBA(image.Rect(0, 0, width*2+lineWidth, height))
+		draw.Draw(result, beforeImg.Rect, beforeImg, image.Point{}, draw.Src)
+		draw.Draw(result, image.Rect(width, 0, width+lineWidth, height), image.NewUniform(dividerColor), image.Point{}, draw.Src)
+		draw.Draw(result, image.Rect(width+lineWidth, 0, width*2+lineWidth, height), afterImg, image.Point{}, draw.Src)
+		if labelled {
+			drawLabelBox(result, beforeLabel, margin, margin, scale)
+			drawLabelBox(result, afterLabel, width+lineWidth+margin, margin, scale)
+		}
+
+	case domain.CompareSplit:
+		cut := int(position * float64(width))
+		result = image.NewRGBA(afterImg.Rect)
+		draw.Draw(result, result.Rect, afterImg, image.Point{}, draw.Src)
+		draw.Draw(result, image.Rect(0, 0, cut, height), beforeImg, image.Point{}, draw.Src)
+		draw.Draw(result, image.Rect(cut-lineWidth/2, 0, cut-lineWidth/2+lineWidth, height), image.NewUniform(dividerColor), image.Point{}, draw.Src)
+		if labelled {
+			drawLabelBox(result, beforeLabel, margin, margin, scale)
+			afterWidth, _ := labelBoxSize(afterLabel, scale)
+			drawLabelBox(result, afterLabel, width-margin-afterWidth, margin, scale)
+		}
+
+	case domain.CompareWipe:
+		// the divider is the line x/width + y/height = 2*position, corner to corner at 0.5, before is above it
+		result = image.NewRGBA(afterImg.Rect)
+		threshold := 2 * position
+		halfLine := float64(lineWidth) / 2 * math.Sqrt(1/float64(width*width)+1/float64(height*height))
+		parallelTiles(height, func(startY, endY int) {
+			for y := startY; y < endY; y++ {
+				for x := 0; x < width; x++ {
+					distance := float64(x)/float64(width) + float64(y)/float64(height) - threshold
+					offset := y*result.Stride + x*4
+					switch {
+					case math.Abs(distance) < halfLine:
+						copy(result.Pix[offset:offset+4], []uint8{dividerColor.R, dividerColor.G, dividerColor.B, 255})
+					case distance < 0:
+						copy(result.Pix[offset:offset+4], beforeImg.Pix[y*beforeImg.Stride+x*4:])
+					default:
+						copy(result.Pix[offset:offset+4], afterImg.Pix[y*afterImg.Stride+x*4:])
+					}
+				}
+			}
+		})
+		if labelled {
+			drawLabelBox(result, beforeLabel, margin, margin, scale)
+			afterWidth, afterHeight := labelBoxSize(afterLabel, scale)
+			drawLabelBox(result, afterLabel, width-margin-afterWidth, height-margin-afterHeight, scale)
+		}
+
+	default:
+		return after
+	}
+	return result
+}
+
+// labelBoxSize is the size of a label with its background
+func labelBoxSize(text string, scale int) (int, int) {
+	width, height := labelSize(text, scale)
+	return width + labelPadding*2*scale, height + labelPadding*2*scale
+}
+
+// drawLabelBox draws text in white on a translucent black box with its top left corner at x,y
+func drawLabelBox(dst draw.Image, text string, x, y, scale int) {
+	if text == "" {
+		return
+	}
+	width, height := labelBoxSize(text, scale)
+	draw.Draw(dst, image.Rect(x, y, x+width, y+height), image.NewUniform(labelBackground), image.Point{}, draw.Over)
+	drawLabel(dst, text, x+labelPadding*scale, y+labelPadding*scale, scale, color.White)
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	compareRed   = color.RGBA{R: 255, A: 255}
+	compareBlue  = color.RGBA{B: 255, A: 255}
+	compareWhite = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+func TestCompareLabelsOf(t *testing.T) {
+	tests := []struct {
+		value         string
+		before, after string
+		ok            bool
+	}{
+		{value: ""},
+		{value: "false"},
+		{value: "true", before: "Before", after: "After", ok: true},
+		{value: "Original, Graded", before: "Original", after: "Graded", ok: true},
+		{value: "only one", before: "Before", after: "After", ok: true},
+	}
+	for _, test := range tests {
+		before, after, ok := compareLabelsOf(test.value)
+		assert.Equal(t, []interface{}{test.before, test.after, test.ok}, []interface{}{before, after, ok}, test.value)
+	}
+}
+
+func TestRgbaOf(t *testing.T) {
+	img := solidImage(4, 4, compareRed)
+	assert.Same(t, img, rgbaOf(img))
+
+	// a sub image is moved to the origin
+	sub := img.SubImage(image.Rect(1, 1, 3, 4))
+	moved := rgbaOf(sub)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), moved.Rect)
+	assert.Equal(t, compareRed, moved.RGBAAt(0, 0))
+}
+
+func TestCompareImage(t *testing.T) {
+	before := solidImage(100, 50, compareRed)
+	after := solidImage(100, 50, compareBlue)
+
+	assert.Same(t, image.Image(after), compareImage(before, after, domain.ImageAdjustmentRequest{}))
+	assert.Same(t, image.Image(after), compareImage(before, after, domain.ImageAdjustmentRequest{Compare: "unknown"}))
+
+	t.Run("split", func(t *testing.T) {
+		result := compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareSplit, ComparePosition: 0.25}).(*image.RGBA)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), result.Rect)
+		assert.Equal(t, compareRed, result.RGBAAt(10, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(24, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(25, 25))
+		assert.Equal(t, compareBlue, result.RGBAAt(26, 25))
+		assert.Equal(t, compareBlue, result.RGBAAt(90, 25))
+
+		// without a position the divider is in the middle
+		result = compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareSplit}).(*image.RGBA)
+		assert.Equal(t, compareRed, result.RGBAAt(48, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(50, 25))
+		assert.Equal(t, compareBlue, result.RGBAAt(52, 25))
+	})
+
+	t.Run("side by side", func(t *testing.T) {
+		result := compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareSideBySide}).(*image.RGBA)
+		assert.Equal(t, image.Rect(0, 0, 202, 50), result.Rect)
+		assert.Equal(t, compareRed, result.RGBAAt(99, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(100, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(101, 25))
+		assert.Equal(t, compareBlue, result.RGBAAt(102, 25))
+		assert.Equal(t, compareBlue, result.RGBAAt(201, 49))
+	})
+
+	t.Run("wipe", func(t *testing.T) {
+		result := compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareWipe}).(*image.RGBA)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), result.Rect)
+		// the divider runs from the top right to the bottom left corner, before is above it
+		assert.Equal(t, compareRed, result.RGBAAt(5, 5))
+		assert.Equal(t, compareWhite, result.RGBAAt(50, 25))
+		assert.Equal(t, compareWhite, result.RGBAAt(90, 5))
+		assert.Equal(t, compareBlue, result.RGBAAt(95, 45))
+
+		// a smaller position moves the divider towards the top left corner
+		result = compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareWipe, ComparePosition: 0.2}).(*image.RGBA)
+		assert.Equal(t, compareRed, result.RGBAAt(5, 5))
+		assert.Equal(t, compareBlue, result.RGBAAt(50, 25))
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		plain := compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareSplit}).(*image.RGBA)
+		labelled := compareImage(before, after, domain.ImageAdjustmentRequest{Compare: domain.CompareSplit, CompareLabels: "true"}).(*image.RGBA)
+
+		// the label boxes darken the corners, the rest is left as is
+		scale := labelScale(100)
+		margin := labelPadding * 2 * scale
+		afterWidth, _ := labelBoxSize(defaultAfterLabel, scale)
+		assert.Less(t, labelled.RGBAAt(margin+1, margin+1).R, plain.RGBAAt(margin+1, margin+1).R)
+		assert.Less(t, labelled.RGBAAt(100-margin-afterWidth+1, margin+1).B, plain.RGBAAt(100-margin-afterWidth+1, margin+1).B)
+		assert.Equal(t, plain.RGBAAt(10, 45), labelled.RGBAAt(10, 45))
+		assert.Equal(t, plain.RGBAAt(90, 45), labelled.RGBAAt(90, 45))
+	})
+}
+
+func TestImageAdjustmentTemperatureCompare(t *testing.T) {
+	useCase := newTestUseCase(t)
+	res, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 32, 16, 51)),
+		AdjustmentTemperature: 1.2,
+		Compare:               domain.CompareSideBySide,
+		Preview:               "true",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*32+2, res.Width)
+	assert.Equal(t, 16, res.Height)
+
+	// compare is part of the output, the same adjustment without it is another output
+	compared, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 32, 16, 51)),
+		AdjustmentTemperature: 1.2,
+		Compare:               domain.CompareSplit,
+	})
+	assert.NoError(t, err)
+	plain, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 32, 16, 51)),
+		AdjustmentTemperature: 1.2,
+	})
+	assert.NoError(t, err)
+	assert.False(t, plain.Cached)
+	assert.NotEqual(t, compared.OutputPathDirImage, plain.OutputPathDirImage)
+	assert.Equal(t, 32, compared.Width)
+}
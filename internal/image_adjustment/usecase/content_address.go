@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+)
+
+const (
+	jsonContentType = "application/json"
+	// maxStoredInputBytes bounds the copy of the upload kept for storing the original
+	maxStoredInputBytes = 64 << 20
+)
+
+// boundedBuffer keeps a copy of the upload and fails once it grows past limit
+type boundedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *boundedBuffer) Write(data []byte) (int, error) {
+	if b.Len()+len(data) > b.limit {
+		return 0, response.ErrUploadTooLarge
+	}
+	return b.Buffer.Write(data)
+}
+
+// decodeUpload decodes the image straight from the upload stream while hashing
+// it, the original bytes are only kept (up to maxStoredInputBytes) when keepOriginal.
+func decodeUpload(file io.Reader, keepOriginal bool) (img image.Image, original []byte, inputHash string, err error) {
+	hasher := sha256.New()
+	writers := []io.Writer{hasher}
+	buffer := &boundedBuffer{limit: maxStoredInputBytes}
+	if keepOriginal {
+		writers = append(writers, buffer)
+	}
+	stream := io.TeeReader(file, io.MultiWriter(writers...))
+
+	img, _, err = image.Decode(stream)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// the decoder may stop before the end of the file, the hash needs every byte
+	if _, err = io.Copy(ioutil.Discard, stream); err != nil {
+		return nil, nil, "", err
+	}
+
+	if keepOriginal {
+		original = buffer.Bytes()
+	}
+	return img, original, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// paramsHash hashes every parameter which changes the output image, the upload
+// itself and response only options such as preview are left out.
+func paramsHash(request domain.ImageAdjustmentRequest) (string, error) {
+	request.File = nil
+	request.FileHeader = nil
+	request.Preview = ""
+	request.ClientID = ""
+	request.StoreInput = ""
+	request.Async = ""
+	request.CallbackUrl = ""
+	request.Preset = ""
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func inputKeyOf(inputHash string) string {
+	return fmt.Sprintf("%s%s.jpg", inputPrefix, inputHash)
+}
+
+// outputNameOf is the key prefix of every artifact produced for the input and parameters
+func outputNameOf(inputHash, paramsHash string) string {
+	return fmt.Sprintf("%s%s-%s", outputPrefix, inputHash, paramsHash)
+}
+
+// putIfAbsent stores data under key unless an object already exists there
+func putIfAbsent(ctx context.Context, objectStorage storage.Storage, key string, data []byte, contentType string) error {
+	_, err := objectStorage.Stat(ctx, key)
+	if err == nil {
+		return nil
+	}
+	if err != storage.ErrObjectNotFound {
+		return err
+	}
+	return objectStorage.Put(ctx, key, bytes.NewReader(data), contentType)
+}
+
+// loadManifest reads the response stored for an earlier identical request
+func loadManifest(ctx context.Context, objectStorage storage.Storage, manifestKey string) (res domain.ImageAdjustmentResponse, err error) {
+	reader, err := objectStorage.Get(ctx, manifestKey)
+	if err != nil {
+		return res, err
+	}
+	defer reader.Close()
+
+	err = json.NewDecoder(reader).Decode(&res)
+	return res, err
+}
+
+func saveManifest(ctx context.Context, objectStorage storage.Storage, manifestKey string, res domain.ImageAdjustmentResponse) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return objectStorage.Put(ctx, manifestKey, bytes.NewReader(data), jsonContentType)
+}
+
+func readObject(ctx context.Context, objectStorage storage.Storage, key string) ([]byte, error) {
+	reader, err := objectStorage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+const (
+	inputPrefix  = "input/"
+	outputPrefix = "output/"
+	clientPrefix = "clients/"
+	// sha256HexLength is the length of a hex encoded content hash
+	sha256HexLength = sha256.Size * 2
+	// outputGroupLength is the length of "<input hash>-<params hash>"
+	outputGroupLength = sha256HexLength*2 + 1
+)
+
+// outputGroupOf returns the output name shared by an output, its manifest and renditions
+func outputGroupOf(key string) string {
+	name := strings.TrimPrefix(key, outputPrefix)
+	if len(name) < outputGroupLength {
+		return key
+	}
+	return outputPrefix + name[:outputGroupLength]
+}
+
+func clientMarkerPrefixOf(clientID string) string {
+	sum := sha256.Sum256([]byte(clientID))
+	return clientPrefix + hex.EncodeToString(sum[:8]) + "/"
+}
+
+// clientMarkerKeyOf records that a client requested outputName, the nano timestamp keeps markers sorted by age
+func clientMarkerKeyOf(clientID, outputName string, now time.Time) string {
+	return fmt.Sprintf("%s%020d-%s", clientMarkerPrefixOf(clientID), now.UnixNano(), strings.TrimPrefix(outputName, outputPrefix))
+}
+
+// imageMarkerKeyOf records that a client uploaded an image through the image api,
+// the janitor keeps the input as long as the marker is among the client's last N
+func imageMarkerKeyOf(clientID, inputHash string, now time.Time) string {
+	return fmt.Sprintf("%s%020d-%s", clientMarkerPrefixOf(clientID), now.UnixNano(), inputHash)
+}
+
+// isContentHash reports whether value is a hex encoded sha256, the form of every image id
+func isContentHash(value string) bool {
+	if len(value) != sha256HexLength {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil && strings.ToLower(value) == value
+}
+
+// inputHashOfMarker returns the image a client marker refers to, output reports
+// whether the marker records an output of it rather than the upload itself
+func inputHashOfMarker(markerKey string) (inputHash string, output bool) {
+	name := strings.TrimPrefix(outputNameOfMarker(markerKey), outputPrefix)
+	if len(name) < sha256HexLength {
+		return "", false
+	}
+	return name[:sha256HexLength], len(name) > sha256HexLength
+}
+
+// imageOwnerSubjectOf is what the owner token of an uploaded image signs
+func imageOwnerSubjectOf(clientID, inputHash string) string {
+	return "image\n" + clientID + "\n" + inputHash
+}
+
+// outputNameOfMarker returns the output name a client marker points to
+func outputNameOfMarker(markerKey string) string {
+	name := markerKey[strings.LastIndex(markerKey, "/")+1:]
+	if index := strings.Index(name, "-"); index >= 0 {
+		return outputPrefix + name[index+1:]
+	}
+	return ""
+}
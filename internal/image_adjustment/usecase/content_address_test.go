@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeUploadHashesTheWholeFile(t *testing.T) {
+	upload := gradientJpeg(t, 16, 8, 1)
+	// trailing bytes after the jpeg end marker still belong to the file
+	upload = append(upload, []byte("trailer")...)
+	sum := sha256.Sum256(upload)
+
+	img, original, inputHash, err := decodeUpload(bytes.NewReader(upload), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, img.Bounds().Dx())
+	assert.Equal(t, upload, original)
+	assert.Equal(t, hex.EncodeToString(sum[:]), inputHash)
+
+	_, original, sameHash, err := decodeUpload(bytes.NewReader(upload), false)
+	assert.NoError(t, err)
+	assert.Nil(t, original)
+	assert.Equal(t, inputHash, sameHash)
+
+	_, _, _, err = decodeUpload(strings.NewReader("not an image"), true)
+	assert.Error(t, err)
+}
+
+func TestBoundedBuffer(t *testing.T) {
+	buffer := &boundedBuffer{limit: 4}
+	_, err := buffer.Write([]byte("abc"))
+	assert.NoError(t, err)
+	_, err = buffer.Write([]byte("de"))
+	assert.Equal(t, response.ErrUploadTooLarge, err)
+	assert.Equal(t, "abc", buffer.String())
+}
+
+func TestParamsHashIgnoresResponseOnlyOptions(t *testing.T) {
+	base, err := paramsHash(domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.1})
+	assert.NoError(t, err)
+
+	same, err := paramsHash(domain.ImageAdjustmentRequest{
+		AdjustmentTemperature: 1.1,
+		Preview:               "true",
+		ClientID:              "client",
+		StoreInput:            "false",
+		Async:                 "true",
+		CallbackUrl:           "https://example.com/hook",
+		Preset:                "golden-hour",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, base, same)
+
+	other, err := paramsHash(domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.2})
+	assert.NoError(t, err)
+	assert.NotEqual(t, base, other)
+}
+
+func TestContentKeys(t *testing.T) {
+	input, params := hashOf("input"), hashOf("params")
+	outputName := outputNameOf(input, params)
+
+	assert.Equal(t, "input/"+input+".jpg", inputKeyOf(input))
+	assert.Equal(t, input, imageIDOfKey(inputKeyOf(input)))
+	assert.Empty(t, imageIDOfKey("input/not-a-hash.jpg"))
+	assert.Equal(t, outputName, outputGroupOf(outputName+"-320.jpg"))
+	assert.Equal(t, outputName, outputGroupOf(outputName+".json"))
+	assert.True(t, isContentHash(input))
+	assert.False(t, isContentHash(strings.ToUpper(input)))
+
+	marker := clientMarkerKeyOf("client", outputName, time.Unix(0, 42))
+	assert.True(t, strings.HasPrefix(marker, clientMarkerPrefixOf("client")))
+	assert.Equal(t, outputName, outputNameOfMarker(marker))
+	assert.Equal(t, outputPrefix+input, outputNameOfMarker(imageMarkerKeyOf("client", input, time.Unix(0, 42))))
+}
+
+func TestPreviewStoresNothing(t *testing.T) {
+	useCase := newTestUseCase(t)
+	request := domainRequest(gradientJpeg(t, 16, 16, 4), 1.1)
+	request.Preview = "true"
+
+	res, err := useCase.ImageAdjustmentTemperature(newTestContext(), request)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.OutputImage)
+	assert.Empty(t, res.OutputPathDirImage)
+	assert.Empty(t, res.InputPathDirImage)
+	assert.Empty(t, keysOf(t, useCase.storage))
+	assert.Empty(t, useCase.images.images)
+}
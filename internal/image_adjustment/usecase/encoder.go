@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+
+	"github.com/nfnt/resize"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+const (
+	// defaultJpegQuality is used when the caller has no byte budget
+	defaultJpegQuality = 100
+	// minJpegQuality is the lowest quality the budget search may pick
+	minJpegQuality = 1
+	// downscaleStep shrinks each side by this factor per downscale attempt
+	downscaleStep = 0.8
+	// minDownscaleSide stops downscaling once an image side gets this small
+	minDownscaleSide = 16
+)
+
+type encodedJpeg struct {
+	Data    []byte
+	Quality int
+	Image   image.Image
+}
+
+func encodeJpeg(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// searchJpegQuality binary searches the highest quality which fits maxBytes,
+// ok is false when even minJpegQuality is too large.
+func searchJpegQuality(img image.Image, maxBytes int) (res encodedJpeg, ok bool, err error) {
+	low, high := minJpegQuality, defaultJpegQuality
+	for low <= high {
+		quality := (low + high) / 2
+		data, err := encodeJpeg(img, quality)
+		if err != nil {
+			return encodedJpeg{}, false, err
+		}
+		if len(data) <= maxBytes {
+			res = encodedJpeg{Data: data, Quality: quality, Image: img}
+			ok = true
+			low = quality + 1
+		} else {
+			high = quality - 1
+		}
+	}
+	return res, ok, nil
+}
+
+// encodeJpegWithinBudget encodes img as JPEG, when maxBytes is set the quality is
+// lowered until the output fits and, if allowDownscale, the image is shrunk as a last resort.
+func encodeJpegWithinBudget(img image.Image, maxBytes int, allowDownscale bool) (encodedJpeg, error) {
+	if maxBytes <= 0 {
+		data, err := encodeJpeg(img, defaultJpegQuality)
+		if err != nil {
+			return encodedJpeg{}, err
+		}
+		return encodedJpeg{Data: data, Quality: defaultJpegQuality, Image: img}, nil
+	}
+
+	current := img
+	for {
+		res, ok, err := searchJpegQuality(current, maxBytes)
+		if err != nil {
+			return encodedJpeg{}, err
+		}
+		if ok {
+			return res, nil
+		}
+		if !allowDownscale {
+			return encodedJpeg{}, response.ErrOutputSizeUnreachable
+		}
+
+		bounds := current.Bounds()
+		width := uint(float64(bounds.Dx()) * downscaleStep)
+		height := uint(float64(bounds.Dy()) * downscaleStep)
+		if width < minDownscaleSide || height < minDownscaleSide {
+			return encodedJpeg{}, response.ErrOutputSizeUnreachable
+		}
+		current = resize.Resize(width, height, current, resize.Bilinear)
+	}
+}
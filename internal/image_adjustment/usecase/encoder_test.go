@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// noisyImage is hard to compress so every quality step changes the encoded size.
+func noisyImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			seed = seed*1664525 + 1013904223
+			img.Set(x, y, color.RGBA{R: uint8(seed >> 24), G: uint8(seed >> 16), B: uint8(seed >> 8), A: 255})
+		}
+	}
+	return img
+}
+
+func TestSearchJpegQualityPicksHighestFittingQuality(t *testing.T) {
+	img := noisyImage(64, 64)
+	q80, err := encodeJpeg(img, 80)
+	assert.NoError(t, err)
+	q81, err := encodeJpeg(img, 81)
+	assert.NoError(t, err)
+	assert.Less(t, len(q80), len(q81))
+
+	res, ok, err := searchJpegQuality(img, len(q80))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, len(res.Data), len(q80))
+	assert.GreaterOrEqual(t, res.Quality, 80)
+	assert.Less(t, res.Quality, 81)
+	assert.Equal(t, q80, res.Data)
+}
+
+func TestSearchJpegQualityBounds(t *testing.T) {
+	img := noisyImage(32, 32)
+	best, err := encodeJpeg(img, defaultJpegQuality)
+	assert.NoError(t, err)
+	worst, err := encodeJpeg(img, minJpegQuality)
+	assert.NoError(t, err)
+
+	res, ok, err := searchJpegQuality(img, len(best))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, defaultJpegQuality, res.Quality)
+
+	res, ok, err = searchJpegQuality(img, len(worst))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, len(worst), len(res.Data))
+
+	_, ok, err = searchJpegQuality(img, len(worst)-1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestEncodeJpegWithinBudget(t *testing.T) {
+	img := noisyImage(128, 128)
+	worst, err := encodeJpeg(img, minJpegQuality)
+	assert.NoError(t, err)
+
+	t.Run("no budget", func(t *testing.T) {
+		res, err := encodeJpegWithinBudget(img, 0, false)
+		assert.NoError(t, err)
+		assert.Equal(t, defaultJpegQuality, res.Quality)
+		assert.Equal(t, img, res.Image)
+	})
+
+	t.Run("unreachable without downscale", func(t *testing.T) {
+		_, err := encodeJpegWithinBudget(img, len(worst)/2, false)
+		assert.Equal(t, response.ErrOutputSizeUnreachable, err)
+	})
+
+	t.Run("downscale until it fits", func(t *testing.T) {
+		res, err := encodeJpegWithinBudget(img, len(worst)/2, true)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(res.Data), len(worst)/2)
+		assert.Less(t, res.Image.Bounds().Dx(), 128)
+
+		decoded, err := jpeg.Decode(bytes.NewReader(res.Data))
+		assert.NoError(t, err)
+		assert.Equal(t, res.Image.Bounds().Size(), decoded.Bounds().Size())
+	})
+
+	t.Run("unreachable at minimum side", func(t *testing.T) {
+		_, err := encodeJpegWithinBudget(img, 1, true)
+		assert.Equal(t, response.ErrOutputSizeUnreachable, err)
+	})
+}
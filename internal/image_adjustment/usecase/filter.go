@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+const (
+	// tileRows is the band height each worker convolves at a time
+	tileRows = 64
+	// defaultSharpenRadius is used when only sharpen amount is given
+	defaultSharpenRadius = 1.0
+	// defaultDenoiseRadius is used when only denoise mode is given
+	defaultDenoiseRadius = 1
+	// bilateralRangeSigma weights neighbours by colour distance (0-255 scale)
+	bilateralRangeSigma = 25.0
+)
+
+// floatImage holds interleaved RGB samples in the 0-255 range, alpha is dropped
+// since the service only writes JPEG.
+type floatImage struct {
+	width  int
+	height int
+	pix    []float32
+}
+
+func newFloatImage(width, height int) *floatImage {
+	return &floatImage{width: width, height: height, pix: make([]float32, width*height*3)}
+}
+
+func toFloatImage(img image.Image) *floatImage {
+	bounds := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	result := newFloatImage(bounds.Dx(), bounds.Dy())
+	for y := 0; y < result.height; y++ {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < result.width; x++ {
+			offset := (y*result.width + x) * 3
+			result.pix[offset] = float32(row[x*4])
+			result.pix[offset+1] = float32(row[x*4+1])
+			result.pix[offset+2] = float32(row[x*4+2])
+		}
+	}
+	return result
+}
+
+func (f *floatImage) toRGBA() *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+	for y := 0; y < f.height; y++ {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < f.width; x++ {
+			offset := (y*f.width + x) * 3
+			row[x*4] = clampUint8(f.pix[offset])
+			row[x*4+1] = clampUint8(f.pix[offset+1])
+			row[x*4+2] = clampUint8(f.pix[offset+2])
+			row[x*4+3] = 255
+		}
+	}
+	return rgba
+}
+
+func clampUint8(value float32) uint8 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= 255 {
+		return 255
+	}
+	return uint8(value + 0.5)
+}
+
+// parallelTiles splits height rows into bands of tileRows and runs fn for each
+// band on a pool of one worker per CPU.
+func parallelTiles(height int, fn func(startY, endY int)) {
+	tiles := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for startY := range tiles {
+				endY := startY + tileRows
+				if endY > height {
+					endY = height
+				}
+				fn(startY, endY)
+			}
+		}()
+	}
+	for startY := 0; startY < height; startY += tileRows {
+		tiles <- startY
+	}
+	close(tiles)
+	wg.Wait()
+}
+
+func gaussianKernel(sigma float64) []float32 {
+	radius := int(math.Ceil(sigma * 3))
+	if radius < 1 {
+		radius = 1
+	}
+	kernel := make([]float32, radius*2+1)
+	var sum float64
+	for index := range kernel {
+		distance := float64(index - radius)
+		weight := math.Exp(-(distance * distance) / (2 * sigma * sigma))
+		kernel[index] = float32(weight)
+		sum += weight
+	}
+	for index := range kernel {
+		kernel[index] /= float32(sum)
+	}
+	return kernel
+}
+
+// convolveSeparable applies the 1D kernel horizontally then vertically,
+// edges are clamped.
+func convolveSeparable(src *floatImage, kernel []float32) *floatImage {
+	radius := len(kernel) / 2
+	horizontal := newFloatImage(src.width, src.height)
+	parallelTiles(src.height, func(startY, endY int) {
+		for y := startY; y < endY; y++ {
+			for x := 0; x < src.width; x++ {
+				var r, g, b float32
+				for k, weight := range kernel {
+					sx := clampInt(x+k-radius, 0, src.width-1)
+					offset := (y*src.width + sx) * 3
+					r += src.pix[offset] * weight
+					g += src.pix[offset+1] * weight
+					b += src.pix[offset+2] * weight
+				}
+				offset := (y*src.width + x) * 3
+				horizontal.pix[offset], horizontal.pix[offset+1], horizontal.pix[offset+2] = r, g, b
+			}
+		}
+	})
+
+	result := newFloatImage(src.width, src.height)
+	parallelTiles(src.height, func(startY, endY int) {
+		for y := startY; y < endY; y++ {
+			for x := 0; x < src.width; x++ {
+				var r, g, b float32
+				for k, weight := range kernel {
+					sy := clampInt(y+k-radius, 0, src.height-1)
+					offset := (sy*src.width + x) * 3
+					r += horizontal.pix[offset] * weight
+					g += horizontal.pix[offset+1] * weight
+					b += horizontal.pix[offset+2] * weight
+				}
+				offset := (y*src.width + x) * 3
+				result.pix[offset], result.pix[offset+1], result.pix[offset+2] = r, g, b
+			}
+		}
+	})
+	return result
+}
+
+func gaussianBlur(img image.Image, sigma float64) image.Image {
+	return convolveSeparable(toFloatImage(img), gaussianKernel(sigma)).toRGBA()
+}
+
+// unsharpMask adds amount times the difference between the image and its
+// gaussian blur, differences below threshold are left alone to spare noise.
+func unsharpMask(img image.Image, amount, radius, threshold float64) image.Image {
+	if radius <= 0 {
+		radius = defaultSharpenRadius
+	}
+	src := toFloatImage(img)
+	blurred := convolveSeparable(src, gaussianKernel(radius))
+
+	result := newFloatImage(src.width, src.height)
+	parallelTiles(src.height, func(startY, endY int) {
+		for offset := startY * src.width * 3; offset < endY*src.width*3; offset++ {
+			diff := src.pix[offset] - blurred.pix[offset]
+			if math.Abs(float64(diff)) < threshold {
+				result.pix[offset] = src.pix[offset]
+				continue
+			}
+			result.pix[offset] = src.pix[offset] + diff*float32(amount)
+		}
+	})
+	return result.toRGBA()
+}
+
+// medianDenoise replaces every sample with the median of its (2r+1)^2 window.
+// Samples are whole 0-255 values, so each row slides a 256 bin histogram per
+// channel along x instead of sorting the window for every pixel. ctx is
+// checked once per row.
+func medianDenoise(ctx context.Context, img image.Image, radius int) (image.Image, error) {
+	src := toFloatImage(img)
+	result := newFloatImage(src.width, src.height)
+	half := (radius*2 + 1) * (radius*2 + 1) / 2
+	parallelTiles(src.height, func(startY, endY int) {
+		var histogram [3][256]int
+		var median [3]int
+		var below [3]int
+
+		// column adds (delta 1) or removes (delta -1) the window column at x
+		column := func(y, x, delta int) {
+			sx := clampInt(x, 0, src.width-1)
+			for wy := y - radius; wy <= y+radius; wy++ {
+				offset := (clampInt(wy, 0, src.height-1)*src.width + sx) * 3
+				for channel := 0; channel < 3; channel++ {
+					value := int(src.pix[offset+channel])
+					histogram[channel][value] += delta
+					if value < median[channel] {
+						below[channel] += delta
+					}
+				}
+			}
+		}
+
+		for y := startY; y < endY; y++ {
+			if ctx.Err() != nil {
+				return
+			}
+			histogram = [3][256]int{}
+			median, below = [3]int{}, [3]int{}
+			for wx := -radius; wx <= radius; wx++ {
+				column(y, wx, 1)
+			}
+			for x := 0; x < src.width; x++ {
+				if x > 0 {
+					column(y, x-radius-1, -1)
+					column(y, x+radius, 1)
+				}
+				for channel := 0; channel < 3; channel++ {
+					// move the median until exactly half of the window lies below it
+					for below[channel] > half {
+						median[channel]--
+						below[channel] -= histogram[channel][median[channel]]
+					}
+					for below[channel]+histogram[channel][median[channel]] <= half {
+						below[channel] += histogram[channel][median[channel]]
+						median[channel]++
+					}
+					result.pix[(y*src.width+x)*3+channel] = float32(median[channel])
+				}
+			}
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result.toRGBA(), nil
+}
+
+// bilateralDenoise uses the separable approximation of the bilateral filter,
+// one horizontal and one vertical pass weighted by distance and colour difference.
+func bilateralDenoise(img image.Image, radius int) image.Image {
+	spatial := gaussianKernel(float64(radius))
+	src := toFloatImage(img)
+	horizontal := bilateralPass(src, spatial, 1, 0)
+	return bilateralPass(horizontal, spatial, 0, 1).toRGBA()
+}
+
+func bilateralPass(src *floatImage, spatial []float32, stepX, stepY int) *floatImage {
+	radius := len(spatial) / 2
+	rangeDenominator := float32(2 * bilateralRangeSigma * bilateralRangeSigma)
+	result := newFloatImage(src.width, src.height)
+	parallelTiles(src.height, func(startY, endY int) {
+		for y := startY; y < endY; y++ {
+			for x := 0; x < src.width; x++ {
+				center := (y*src.width + x) * 3
+				var r, g, b, total float32
+				for k, spatialWeight := range spatial {
+					sx := clampInt(x+(k-radius)*stepX, 0, src.width-1)
+					sy := clampInt(y+(k-radius)*stepY, 0, src.height-1)
+					offset := (sy*src.width + sx) * 3
+					dr := src.pix[offset] - src.pix[center]
+					dg := src.pix[offset+1] - src.pix[center+1]
+					db := src.pix[offset+2] - src.pix[center+2]
+					weight := spatialWeight * float32(math.Exp(float64(-(dr*dr+dg*dg+db*db)/rangeDenominator)))
+					r += src.pix[offset] * weight
+					g += src.pix[offset+1] * weight
+					b += src.pix[offset+2] * weight
+					total += weight
+				}
+				result.pix[center], result.pix[center+1], result.pix[center+2] = r/total, g/total, b/total
+			}
+		}
+	})
+	return result
+}
+
+// denoiseImage runs before the temperature pass so warming does not amplify chroma noise.
+func denoiseImage(ctx context.Context, img image.Image, request domain.ImageAdjustmentRequest) (image.Image, error) {
+	radius := request.DenoiseRadius
+	if radius <= 0 {
+		radius = defaultDenoiseRadius
+	}
+	switch request.Denoise {
+	case domain.DenoiseMedian:
+		return medianDenoise(ctx, img, radius)
+	case domain.DenoiseBilateral:
+		return bilateralDenoise(img, radius), nil
+	}
+	return img, nil
+}
+
+// blurAndSharpenImage runs after the temperature pass.
+func blurAndSharpenImage(img image.Image, request domain.ImageAdjustmentRequest) image.Image {
+	result := img
+	if request.BlurRadius > 0 {
+		result = gaussianBlur(result, request.BlurRadius)
+	}
+	if request.SharpenAmount > 0 {
+		result = unsharpMask(result, request.SharpenAmount, request.SharpenRadius, request.SharpenThreshold)
+	}
+	return result
+}
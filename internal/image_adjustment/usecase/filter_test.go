@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// bruteForceMedian sorts every window, the reference medianDenoise must match.
+func bruteForceMedian(img image.Image, radius int) *image.RGBA {
+	src := toFloatImage(img)
+	result := newFloatImage(src.width, src.height)
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			for channel := 0; channel < 3; channel++ {
+				var window []float32
+				for wy := y - radius; wy <= y+radius; wy++ {
+					for wx := x - radius; wx <= x+radius; wx++ {
+						sx, sy := clampInt(wx, 0, src.width-1), clampInt(wy, 0, src.height-1)
+						window = append(window, src.pix[(sy*src.width+sx)*3+channel])
+					}
+				}
+				sort.Slice(window, func(a, b int) bool { return window[a] < window[b] })
+				result.pix[(y*src.width+x)*3+channel] = window[len(window)/2]
+			}
+		}
+	}
+	return result.toRGBA()
+}
+
+func TestMedianDenoiseMatchesSortedWindow(t *testing.T) {
+	img := noisyImage(37, 150)
+	for radius := 1; radius <= 5; radius++ {
+		got, err := medianDenoise(context.Background(), img, radius)
+		assert.NoError(t, err)
+		assert.Equal(t, bruteForceMedian(img, radius).Pix, got.(*image.RGBA).Pix, "radius %d", radius)
+	}
+}
+
+func TestMedianDenoiseRemovesImpulseNoise(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: 100, G: 100, B: 100, A: 255})
+		}
+	}
+	img.SetRGBA(4, 4, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	got, err := medianDenoise(context.Background(), img, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 100, G: 100, B: 100, A: 255}, got.At(4, 4))
+}
+
+func TestMedianDenoiseCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := medianDenoise(ctx, noisyImage(16, 16), 2)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, got)
+
+	_, err = denoiseImage(ctx, noisyImage(16, 16), domain.ImageAdjustmentRequest{Denoise: domain.DenoiseMedian})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestDenoiseImageNone(t *testing.T) {
+	img := noisyImage(8, 8)
+	got, err := denoiseImage(context.Background(), img, domain.ImageAdjustmentRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, img, got)
+}
+
+func TestGaussianKernelIsNormalised(t *testing.T) {
+	for _, sigma := range []float64{0.1, 1, 2.5} {
+		kernel := gaussianKernel(sigma)
+		var sum float32
+		for _, weight := range kernel {
+			sum += weight
+		}
+		assert.InDelta(t, 1, sum, 1e-5)
+		assert.Equal(t, 1, len(kernel)%2)
+	}
+}
@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+const (
+	histogramBins = 256
+	// a rendered histogram is histogramBinWidth pixels per bin wide and histogramRenderHeight high
+	histogramBinWidth     = 2
+	histogramRenderHeight = 200
+	// histogramRenderGap separates the histogram of the adjusted image below the original one
+	histogramRenderGap = 8
+)
+
+var (
+	histogramBackground = color.RGBA{R: 32, G: 32, B: 32, A: 255}
+	histogramLuminance  = color.RGBA{R: 230, G: 230, B: 230, A: 255}
+)
+
+// histogramCounts holds the bins of r, g, b and luminance and the clipped pixels of an image
+type histogramCounts struct {
+	bins              [4][histogramBins]int
+	shadowsClipped    int
+	highlightsClipped int
+}
+
+func (c *histogramCounts) add(other *histogramCounts) {
+	for channel := range c.bins {
+		for bin := range c.bins[channel] {
+			c.bins[channel][bin] += other.bins[channel][bin]
+		}
+	}
+	c.shadowsClipped += other.shadowsClipped
+	c.highlightsClipped += other.highlightsClipped
+}
+
+// countHistogram counts every pixel of img, luminance is Rec. 709 of the sRGB values
+func countHistogram(img image.Image) *histogramCounts {
+	rgba := rgbaOf(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+
+	var lock sync.Mutex
+	counts := &histogramCounts{}
+	parallelTiles(height, func(startY, endY int) {
+		tile := &histogramCounts{}
+		for y := startY; y < endY; y++ {
+			row := rgba.Pix[y*rgba.Stride:]
+			for x := 0; x < width; x++ {
+				r, g, b := row[x*4], row[x*4+1], row[x*4+2]
+				tile.bins[0][r]++
+				tile.bins[1][g]++
+				tile.bins[2][b]++
+				tile.bins[3][uint8(math.Round(0.2126*float64(r)+0.7152*float64(g)+0.0722*float64(b)))]++
+				if r == 0 && g == 0 && b == 0 {
+					tile.shadowsClipped++
+				}
+				if r == 255 || g == 255 || b == 255 {
+					tile.highlightsClipped++
+				}
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		counts.add(tile)
+	})
+	return counts
+}
+
+// channelStatistics summarizes the bins of one channel of an image of pixels pixels
+func channelStatistics(bins [histogramBins]int, pixels int) domain.ChannelStatistics {
+	var sum float64
+	for value, count := range bins {
+		sum += float64(value * count)
+	}
+
+	// percentile is the lowest value at or below which the fraction of the pixels lies
+	percentile := func(fraction float64) int {
+		target := int(math.Ceil(fraction * float64(pixels)))
+		if target < 1 {
+			target = 1
+		}
+		seen := 0
+		for value, count := range bins {
+			seen += count
+			if seen >= target {
+				return value
+			}
+		}
+		return histogramBins - 1
+	}
+
+	return domain.ChannelStatistics{
+		Histogram: append([]int(nil), bins[:]...),
+		Mean:      math.Round(sum/float64(pixels)*100) / 100,
+		Median:    percentile(0.5),
+		Percentiles: domain.HistogramPercentiles{
+			P1:  percentile(0.01),
+			P5:  percentile(0.05),
+			P25: percentile(0.25),
+			P75: percentile(0.75),
+			P95: percentile(0.95),
+			P99: percentile(0.99),
+		},
+		ShadowsClippedPercent:    clippedPercent(bins[0], pixels),
+		HighlightsClippedPercent: clippedPercent(bins[histogramBins-1], pixels),
+	}
+}
+
+func clippedPercent(count, pixels int) float64 {
+	return math.Round(float64(count)/float64(pixels)*10000) / 100
+}
+
+// renderHistogram draws the r, g and b bins added on top of each other with the luminance as a
+// line. The scale leaves out the clipped end bins so a spike at 0 or 255 does not flatten the rest.
+func renderHistogram(counts *histogramCounts) image.Image {
+	peak := 1
+	for channel := range counts.bins {
+		for bin := 1; bin < histogramBins-1; bin++ {
+			if counts.bins[channel][bin] > peak {
+				peak = counts.bins[channel][bin]
+			}
+		}
+	}
+	barHeight := func(count int) int {
+		height := int(math.Round(float64(count) / float64(peak) * histogramRenderHeight))
+		if height > histogramRenderHeight {
+			return histogramRenderHeight
+		}
+		return height
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, histogramBins*histogramBinWidth, histogramRenderHeight))
+	draw.Draw(result, result.Bounds(), image.NewUniform(histogramBackground), image.Point{}, draw.Src)
+	for bin := 0; bin < histogramBins; bin++ {
+		for channel := 0; channel < 3; channel++ {
+			top := histogramRenderHeight - barHeight(counts.bins[channel][bin])
+			for y := top; y < histogramRenderHeight; y++ {
+				for x := bin * histogramBinWidth; x < (bin+1)*histogramBinWidth; x++ {
+					offset := y*result.Stride + x*4 + channel
+					// the channels add up, e.g. red and green overlap as yellow
+					if result.Pix[offset] < 200 {
+						result.Pix[offset] = 200
+					}
+				}
+			}
+		}
+	}
+
+	// the luminance is a line through the tops of its bins
+	previous := histogramRenderHeight - barHeight(counts.bins[3][0])
+	for bin := 0; bin < histogramBins; bin++ {
+		top := histogramRenderHeight - barHeight(counts.bins[3][bin])
+		from, to := previous, top
+		if from > to {
+			from, to = to, from
+		}
+		for y := from; y <= to && y < histogramRenderHeight; y++ {
+			for x := bin * histogramBinWidth; x < (bin+1)*histogramBinWidth; x++ {
+				result.Set(x, y, histogramLuminance)
+			}
+		}
+		previous = top
+	}
+	return result
+}
+
+// histogramStatisticsOf counts img and summarizes the counts
+func histogramStatisticsOf(img image.Image) (domain.HistogramStatistics, *histogramCounts) {
+	bounds := img.Bounds()
+	pixels := bounds.Dx() * bounds.Dy()
+	counts := countHistogram(img)
+	return domain.HistogramStatistics{
+		Width:                    bounds.Dx(),
+		Height:                   bounds.Dy(),
+		Red:                      channelStatistics(counts.bins[0], pixels),
+		Green:                    channelStatistics(counts.bins[1], pixels),
+		Blue:                     channelStatistics(counts.bins[2], pixels),
+		Luminance:                channelStatistics(counts.bins[3], pixels),
+		ShadowsClippedPercent:    clippedPercent(counts.shadowsClipped, pixels),
+		HighlightsClippedPercent: clippedPercent(counts.highlightsClipped, pixels),
+	}, counts
+}
+
+// renderHistograms draws every histogram of counts below the previous one
+func renderHistograms(counts ...*histogramCounts) image.Image {
+	height := len(counts)*(histogramRenderHeight+histogramRenderGap) - histogramRenderGap
+	result := image.NewRGBA(image.Rect(0, 0, histogramBins*histogramBinWidth, height))
+	for index, histogram := range counts {
+		top := index * (histogramRenderHeight + histogramRenderGap)
+		draw.Draw(result, image.Rect(0, top, result.Rect.Dx(), top+histogramRenderHeight), renderHistogram(histogram), image.Point{}, draw.Src)
+	}
+	return result
+}
+
+func (i imageAdjustmentUseCase) ImageHistogram(beegoCtx *beegoContext.Context, request domain.HistogramRequest) (res domain.HistogramResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	var img image.Image
+	format := "jpeg"
+	if request.File != nil {
+		img, format, err = image.Decode(request.File)
+	} else {
+		img, err = i.loadImage(ctx, request.ImageID)
+	}
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	statistics, counts := histogramStatisticsOf(img)
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	res = domain.HistogramResponse{
+		HistogramStatistics: statistics,
+		Format:              format,
+		ContentType:         "image/" + format,
+	}
+	rendered := []*histogramCounts{counts}
+
+	// The adjusted image stays in memory, nothing is stored for it
+	if request.Adjustment != nil {
+		_, adjustedImg, err := i.renderAdjustment(ctx, beegoCtx, img, *request.Adjustment)
+		if err != nil {
+			return domain.HistogramResponse{}, err
+		}
+		adjusted, adjustedCounts := histogramStatisticsOf(adjustedImg)
+		if err := ctx.Err(); err != nil {
+			return domain.HistogramResponse{}, err
+		}
+		res.Adjusted = &adjusted
+		rendered = append(rendered, adjustedCounts)
+	}
+
+	if request.Render == domain.HistogramRenderPNG {
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, renderHistograms(rendered...)); err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return domain.HistogramResponse{}, err
+		}
+		res.Image = buffer.Bytes()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountHistogram(t *testing.T) {
+	img := solidImage(4, 2, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+	img.SetRGBA(0, 0, color.RGBA{A: 255})
+	img.SetRGBA(1, 0, color.RGBA{R: 255, G: 10, B: 10, A: 255})
+
+	counts := countHistogram(img)
+	assert.Equal(t, 6, counts.bins[0][200])
+	assert.Equal(t, 1, counts.bins[0][255])
+	assert.Equal(t, 1, counts.bins[3][0])
+	// Rec. 709 of 200, 100, 50
+	assert.Equal(t, 6, counts.bins[3][118])
+	assert.Equal(t, 1, counts.shadowsClipped)
+	assert.Equal(t, 1, counts.highlightsClipped)
+}
+
+func TestChannelStatistics(t *testing.T) {
+	var bins [histogramBins]int
+	for value := 0; value < 100; value++ {
+		bins[value] = 1
+	}
+	bins[255] = 100
+
+	statistics := channelStatistics(bins, 200)
+	assert.Equal(t, 152.25, statistics.Mean)
+	assert.Equal(t, 99, statistics.Median)
+	assert.Equal(t, domain.HistogramPercentiles{P1: 1, P5: 9, P25: 49, P75: 255, P95: 255, P99: 255}, statistics.Percentiles)
+	assert.Equal(t, 0.5, statistics.ShadowsClippedPercent)
+	assert.Equal(t, 50.0, statistics.HighlightsClippedPercent)
+}
+
+func TestImageHistogramAdjusted(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := solidJpeg(t, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+
+	res, err := useCase.ImageHistogram(newTestContext(), domain.HistogramRequest{File: uploadOf(upload)})
+	assert.NoError(t, err)
+	assert.Equal(t, 16, res.Width)
+	assert.Equal(t, "jpeg", res.Format)
+	assert.Nil(t, res.Adjusted)
+
+	// the adjusted image is measured before it is encoded, a crop changes its dimensions
+	res, err = useCase.ImageHistogram(newTestContext(), domain.HistogramRequest{
+		File:       uploadOf(upload),
+		Render:     domain.HistogramRenderPNG,
+		Adjustment: &domain.ImageAdjustmentRequest{AdjustmentTemperature: 0.5, Crop: "0,0,8,4"},
+	})
+	assert.NoError(t, err)
+	assert.InDelta(t, 128, res.Red.Mean, 1)
+	assert.Equal(t, 0.0, res.HighlightsClippedPercent)
+	if assert.NotNil(t, res.Adjusted) {
+		assert.Equal(t, []int{8, 4}, []int{res.Adjusted.Width, res.Adjusted.Height})
+		assert.InDelta(t, 64, res.Adjusted.Red.Mean, 2)
+		assert.Equal(t, 32, res.Adjusted.Luminance.Histogram[res.Adjusted.Luminance.Median])
+	}
+
+	// the png has the histogram of the adjusted image below the original one
+	rendered, err := png.Decode(bytes.NewReader(res.Image))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, histogramBins*histogramBinWidth, 2*histogramRenderHeight+histogramRenderGap), rendered.Bounds())
+
+	// nothing is stored for the adjusted image
+	keys, err := useCase.storage.List(newTestContext().Request.Context(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
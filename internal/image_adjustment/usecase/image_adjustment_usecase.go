@@ -1,70 +1,171 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	beegoContext "github.com/beego/beego/v2/server/web/context"
-	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
 	"image"
 	"image/color"
-	"image/jpeg"
+	_ "image/jpeg"
 	"io"
-	"os"
+	"runtime/debug"
+	"strings"
 	"time"
 
-	"github.com/nfnt/resize"
 	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/signer"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/webhook"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"gorm.io/gorm"
 )
 
+const jpegContentType = "image/jpeg"
+
 type imageAdjustmentUseCase struct {
 	zapLogger                  zaplogger.Logger
 	contextTimeout             time.Duration
+	storage                    storage.Storage
+	urlSigner                  *signer.URLSigner
+	configHelper               helper.ConfigHelper
+	imageCache                 *decodedImageCache
+	imageRepository            domain.ImageRepository
+	adjustmentJobRepository    domain.AdjustmentJobRepository
+	jobQueue                   *adjustmentJobQueue
+	webhookDeliveryRepository  domain.WebhookDeliveryRepository
+	webhookSender              *webhook.Sender
+	batchLimits                domain.BatchLimits
+	storageKeys                *storageKeyRegistry
 }
 
 
 func NewImageAdjustmentUseCase(timeout time.Duration,
-	zapLogger zaplogger.Logger) domain.ImageAdjustmentUseCase {
+	zapLogger zaplogger.Logger,
+	storage storage.Storage,
+	urlSigner *signer.URLSigner,
+	configHelper helper.ConfigHelper,
+	imageCacheMaxBytes int64,
+	imageRepository domain.ImageRepository,
+	adjustmentJobRepository domain.AdjustmentJobRepository,
+	jobQueueSize int,
+	webhookDeliveryRepository domain.WebhookDeliveryRepository,
+	webhookSender *webhook.Sender,
+	batchLimits domain.BatchLimits) domain.ImageAdjustmentUseCase {
 	return &imageAdjustmentUseCase{
 		contextTimeout:             timeout,
 		zapLogger:                  zapLogger,
+		storage:                    storage,
+		urlSigner:                  urlSigner,
+		configHelper:               configHelper,
+		imageCache:                 newDecodedImageCache(imageCacheMaxBytes),
+		imageRepository:            imageRepository,
+		adjustmentJobRepository:    adjustmentJobRepository,
+		jobQueue:                   newAdjustmentJobQueue(jobQueueSize),
+		webhookDeliveryRepository:  webhookDeliveryRepository,
+		webhookSender:              webhookSender,
+		batchLimits:                batchLimits,
+		storageKeys:                newStorageKeyRegistry(),
 	}
 }
 
-func(i imageAdjustmentUseCase) adjustTemperature(beegoCtx *beegoContext.Context,file io.Reader, adjustment float64) (input,output *string,err error) {
-	nameOfFile := helper.RandomString(10)
-	outputPath := fmt.Sprintf("external/storage/%s-output.jpg",nameOfFile)
-	inputPath := fmt.Sprintf("external/storage/%s-input.jpg",nameOfFile)
+// publicURL returns an expiring signed link to key served by the file download endpoint
+func (i imageAdjustmentUseCase) publicURL(beegoCtx *beegoContext.Context, key string) string {
+	return fmt.Sprint(i.configHelper.FileBaseUrl(beegoCtx.Request), domain.FileDownloadPath, key, "?", i.urlSigner.Sign(key, time.Now()).Encode())
+}
+
+// writeOutput encodes img and stores it under outputKey, an empty outputKey only encodes
+func(i imageAdjustmentUseCase) writeOutput(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, outputKey string, img image.Image, request domain.ImageAdjustmentRequest) (*encodedJpeg, error) {
+	// Encode the adjusted image as JPEG, within the byte budget when requested
+	result, err := encodeJpegWithinBudget(img, request.MaxOutputBytes, request.AllowDownscale == "true")
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return nil,err
+	}
+
+	// Give up before writing when the request already timed out
+	if err := ctx.Err(); err != nil {
+		return nil,err
+	}
+	if outputKey == "" {
+		return &result,nil
+	}
 
-	out, err := os.Create(inputPath)
+	// Store the output image
+	err = tx.Put(ctx, outputKey, bytes.NewReader(result.Data), jpegContentType)
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return nil,err
 	}
-	defer out.Close()
 
-	// Copy the uploaded file data to the new file
-	_, err = io.Copy(out, file)
+	return &result,nil
+}
+
+func(i imageAdjustmentUseCase) adjustTemperature(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction,file io.Reader, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse,err error) {
+	// Only a caller who wants retrievable urls gets anything persisted
+	storeInput := request.Preview != "true" && request.StoreInput != "false"
+
+	// Decode straight from the upload, inputs are stored under their content hash
+	img, original, inputHash, err := decodeUpload(file, storeInput)
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return res,err
 	}
 
-	// Open the input image file
-	fileOriginal, err := os.Open(inputPath)
+	return i.storeAndAdjust(ctx, beegoCtx, tx, img, original, inputHash, request)
+}
+
+// storeAndAdjust stores the decoded upload unless a preview or store_input=false asked not to, then adjusts it
+func(i imageAdjustmentUseCase) storeAndAdjust(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, img image.Image, original []byte, inputHash string, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse,err error) {
+	storeInput, err := i.storeUpload(ctx, beegoCtx, tx, img, original, inputHash, request)
+	if err != nil {
+		return res,err
+	}
+
+	return i.adjustDecoded(ctx, beegoCtx, tx, img, inputHash, storeInput, request)
+}
+
+// storeUpload stores the uploaded file data once per content and reports whether it was stored
+func(i imageAdjustmentUseCase) storeUpload(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, img image.Image, original []byte, inputHash string, request domain.ImageAdjustmentRequest) (bool, error) {
+	storeInput := request.Preview != "true" && request.StoreInput != "false"
+	if !storeInput {
+		return false,nil
+	}
+
+	err := putIfAbsent(ctx, tx, inputKeyOf(inputHash), original, jpegContentType)
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return false,err
 	}
-	defer fileOriginal.Close()
+	i.saveImage(inputHash, request.ClientID, img, int64(len(original)))
+	return true,nil
+}
+
+// renderAdjustment runs the pixel passes of the adjustment pipeline in memory. It answers the
+// transformed input next to the finished image, neither is compared, resized or encoded yet.
+func(i imageAdjustmentUseCase) renderAdjustment(ctx context.Context, beegoCtx *beegoContext.Context, img image.Image, request domain.ImageAdjustmentRequest) (transformedImg, finishedImg image.Image, err error) {
+	adjustment := request.AdjustmentTemperature
 
-	// Decode the input image
-	img, _, err := image.Decode(fileOriginal)
+	// Crop, rotate and flip before the temperature pass
+	img, err = transformImage(img, request)
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return nil, nil, err
+	}
+	transformedImg = img
+
+	// Denoise first, warming amplifies chroma noise
+	img, err = denoiseImage(ctx, img, request)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// Shared white balance gains, e.g. from the white balance endpoint, with the tint on top
+	img = whiteBalanceImage(img, tintGains(request.WhiteBalanceGains, request.Tint))
+
 	// Create a new image with the same bounds as the original image
 	bounds := img.Bounds()
 	adjustedImg := image.NewRGBA(bounds)
@@ -91,41 +192,545 @@ func(i imageAdjustmentUseCase) adjustTemperature(beegoCtx *beegoContext.Context,
 		}
 	}
 
-	// Resize the image to the original dimensions
-	resizedImg := resize.Resize(uint(bounds.Dx()), uint(bounds.Dy()), adjustedImg, resize.NearestNeighbor)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Blur and sharpen the adjusted image
+	return transformedImg, blurAndSharpenImage(adjustedImg, request), nil
+}
 
-	// Create the output file
-	outFile, err := os.Create(outputPath)
+// adjustDecoded runs the adjustment pipeline on an already decoded input, img is never modified
+func(i imageAdjustmentUseCase) adjustDecoded(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, img image.Image, inputHash string, inputStored bool, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse,err error) {
+	persist := request.Preview != "true"
+
+	requestHash, err := paramsHash(request)
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return res,err
+	}
+	inputKey := inputKeyOf(inputHash)
+	outputName := outputNameOf(inputHash, requestHash)
+	outputKey := outputName + ".jpg"
+	manifestKey := outputName + ".json"
+	if !persist {
+		outputKey = ""
+	}
+
+	// Identical request already processed, return the existing output
+	res, err = loadManifest(ctx, i.storage, manifestKey)
+	if err == nil {
+		res.Cached = true
+		// the manifest may come from a request which stored the input, this one only links it when it did too
+		res.InputPathDirImage = helper.InlineConditionString(inputStored, inputKey, "")
+		if !persist {
+			res.OutputImage, err = readObject(ctx, i.storage, res.OutputPathDirImage)
+			if err != nil {
+				beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+				return res,err
+			}
+		}
+		return res,nil
+	}
+	if !errors.Is(err, storage.ErrObjectNotFound) {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res,err
+	}
+	res = domain.ImageAdjustmentResponse{}
+
+	if err := ctx.Err(); err != nil {
+		return res,err
+	}
+
+	transformedImg, finishedImg, err := i.renderAdjustment(ctx, beegoCtx, img, request)
+	if err != nil {
+		return res,err
+	}
+
+	// Measure how far the adjustment moved the image, before compare and resize change its layout
+	var metrics *domain.ImageMetrics
+	var heatmapImg *image.RGBA
+	if request.Metrics == "true" {
+		metrics, heatmapImg = imageMetrics(transformedImg, finishedImg, request.MetricsHeatmap == "true")
+	}
+
+	// Put the original next to the adjusted image when a comparison was asked for
+	finishedImg = compareImage(transformedImg, finishedImg, request)
+
+	// Resize the image when the caller asked for other dimensions
+	resizedImg := resizeImage(finishedImg, request.Width, request.Height, request.Fit, request.Interpolation)
+
+	encoded, err := i.writeOutput(ctx, beegoCtx, tx, outputKey, resizedImg, request)
+	if err != nil {
+		return res,err
+	}
+
+	res = domain.ImageAdjustmentResponse{
+		InputPathDirImage: helper.InlineConditionString(inputStored, inputKey, ""),
+		OutputPathDirImage: outputKey,
+		OutputImage: encoded.Data,
+		Quality: encoded.Quality,
+		OutputSizeBytes: len(encoded.Data),
+		Width: encoded.Image.Bounds().Dx(),
+		Height: encoded.Image.Bounds().Dy(),
+		Metrics: metrics,
+	}
+
+	// The heat map is stored next to the output, a preview has no place to keep it
+	if heatmapImg != nil && persist {
+		heatmapKey := outputName + "-heatmap.jpg"
+		_, err = i.writeOutput(ctx, beegoCtx, tx, heatmapKey, resizeImage(heatmapImg, request.Width, request.Height, request.Fit, request.Interpolation), request)
+		if err != nil {
+			return res,err
+		}
+		res.Metrics.HeatmapPathDirImage = heatmapKey
+	}
+
+	// Render one rendition per requested width, e.g. for srcset
+	for _, size := range request.Sizes {
+		renditionKey := helper.InlineConditionString(persist, fmt.Sprintf("%s-%d.jpg",outputName,size), "")
+		renditionImg := resizeImage(finishedImg, renditionWidth(finishedImg, size), 0, request.Fit, request.Interpolation)
+
+		encoded, err := i.writeOutput(ctx, beegoCtx, tx, renditionKey, renditionImg, request)
+		if err != nil {
+			return res,err
+		}
+
+		res.Renditions = append(res.Renditions, domain.ImageRendition{
+			Size: size,
+			Width: encoded.Image.Bounds().Dx(),
+			Height: encoded.Image.Bounds().Dy(),
+			OutputPathDirImage: renditionKey,
+			Quality: encoded.Quality,
+			OutputSizeBytes: len(encoded.Data),
+		})
+	}
+
+	// Remember the result so an identical request is served from storage
+	if persist {
+		err = saveManifest(ctx, tx, manifestKey, res)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return res,err
+		}
+	}
+
+	return res,nil
+}
+
+// inTransaction runs fn within timeout, every object created through
+// tx is removed again when fn fails or times out
+func (i imageAdjustmentUseCase) inTransaction(beegoCtx *beegoContext.Context, timeout time.Duration, fn func(ctx context.Context, tx *storageTransaction) error) (err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), timeout)
+	defer cancel()
+	beegoCtx.Request.WithContext(ctx)
+
+	tx := newStorageTransaction(i.storage, i.storageKeys)
+	defer func() {
+		// a panic would otherwise commit whatever was written before it
+		if recovered := recover(); recovered != nil {
+			err = i.panicError(recovered)
+		}
+		if err == nil {
+			tx.Commit()
+			return
+		}
+		rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), i.contextTimeout)
+		defer rollbackCancel()
+		if rollbackErr := tx.Rollback(rollbackCtx); rollbackErr != nil {
+			i.zapLogger.Errorf("rollback image adjustment: %s", rollbackErr.Error())
+		}
+	}()
+
+	return fn(ctx, tx)
+}
+
+// panicError logs a recovered panic with its stack and returns it as an error
+func (i imageAdjustmentUseCase) panicError(recovered interface{}) error {
+	i.zapLogger.Errorf("panic: %v\n%s", recovered, debug.Stack())
+	return fmt.Errorf("panic: %v", recovered)
+}
+
+// publish records the output for the client and fills the signed urls of res
+func (i imageAdjustmentUseCase) publish(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, clientID string, res *domain.ImageAdjustmentResponse) error {
+	err := i.recordClientMarker(ctx, beegoCtx, tx, clientID, *res)
+	if err != nil {
+		return err
+	}
+	i.signResponse(beegoCtx, res)
+	return nil
+}
+
+// recordClientMarker records the output for the client so the retention janitor can keep its last N
+func (i imageAdjustmentUseCase) recordClientMarker(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, clientID string, res domain.ImageAdjustmentResponse) error {
+	// Nothing was persisted for a preview
+	if res.OutputPathDirImage == "" {
+		return nil
 	}
-	defer outFile.Close()
 
-	// Encode the adjusted image as JPEG
-	err = jpeg.Encode(outFile, resizedImg, &jpeg.Options{Quality: 100})
+	markerKey := clientMarkerKeyOf(clientID, outputGroupOf(res.OutputPathDirImage), time.Now())
+	err := tx.Put(ctx, markerKey, bytes.NewReader(nil), "")
 	if err != nil {
 		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
-		return nil,nil,err
+		return err
+	}
+	return nil
+}
+
+// signResponse fills the signed urls of every stored object in res
+func (i imageAdjustmentUseCase) signResponse(beegoCtx *beegoContext.Context, res *domain.ImageAdjustmentResponse) {
+	if res.OutputPathDirImage == "" {
+		return
 	}
 
-	return &inputPath,&outputPath,nil
+	if res.InputPathDirImage != "" {
+		res.InputFileImage = i.publicURL(beegoCtx, res.InputPathDirImage)
+	}
+	res.OutputFileImage = i.publicURL(beegoCtx, res.OutputPathDirImage)
+	for index := range res.Renditions {
+		res.Renditions[index].OutputFileImage = i.publicURL(beegoCtx, res.Renditions[index].OutputPathDirImage)
+	}
+	if res.Metrics != nil && res.Metrics.HeatmapPathDirImage != "" {
+		res.Metrics.HeatmapFileImage = i.publicURL(beegoCtx, res.Metrics.HeatmapPathDirImage)
+	}
 }
 
 func (i imageAdjustmentUseCase) ImageAdjustmentTemperature(beegoCtx *beegoContext.Context, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse, err error) {
+	job, err := i.startJob("", request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	defer func() { i.finishJob(job, res, err) }()
+
+	err = i.inTransaction(beegoCtx, i.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		res, err = i.adjustTemperature(ctx,beegoCtx,tx,request.File,request)
+		if err != nil {
+			return err
+		}
+		return i.publish(ctx, beegoCtx, tx, request.ClientID, &res)
+	})
+	if err != nil {
+		return domain.ImageAdjustmentResponse{},err
+	}
+
+	return res,nil
+}
+
+func (i imageAdjustmentUseCase) GetFile(beegoCtx *beegoContext.Context, request domain.FileRequest) (res domain.FileResponse, err error) {
 	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
 	defer cancel()
-	beegoCtx.Request.WithContext(ctx)
 
-	inputFile,outputFile, err := i.adjustTemperature(beegoCtx,request.File,request.AdjustmentTemperature)
+	err = i.urlSigner.Verify(request.Key, request.Expires, request.KeyID, request.Signature, time.Now())
 	if err != nil {
-		return domain.ImageAdjustmentResponse{},err
+		return res,err
+	}
+
+	info, err := i.storage.Stat(ctx, request.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return res,response.ErrFileNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res,err
+	}
+
+	// the content outlives this call, it is streamed by the handler and closed there
+	content, err := i.storage.Get(context.Background(), request.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return res,response.ErrFileNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res,err
+	}
+
+	return domain.FileResponse{
+		Content: content,
+		ContentType: info.ContentType,
+		Size: info.Size,
+	},nil
+}
+
+// loadImage returns the decoded input stored under id, from the cache when possible
+func (i imageAdjustmentUseCase) loadImage(ctx context.Context, id string) (image.Image, error) {
+	if !isContentHash(id) {
+		return nil, response.ErrImageNotFound
+	}
+	if img, ok := i.imageCache.Get(id); ok {
+		return img, nil
+	}
+
+	reader, err := i.storage.Get(ctx, inputKeyOf(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, response.ErrImageNotFound
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, err
+	}
+	i.imageCache.Add(id, img)
+	return img, nil
+}
+
+func (i imageAdjustmentUseCase) CreateImage(beegoCtx *beegoContext.Context, request domain.ImageRequest) (res domain.ImageResponse, err error) {
+	err = i.inTransaction(beegoCtx, i.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		img, original, inputHash, err := decodeUpload(request.File, true)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+
+		err = putIfAbsent(ctx, tx, inputKeyOf(inputHash), original, jpegContentType)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+
+		// Count the image as used by the client until it is adjusted
+		err = tx.Put(ctx, imageMarkerKeyOf(request.ClientID, inputHash, time.Now()), bytes.NewReader(nil), "")
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+		i.imageCache.Add(inputHash, img)
+		i.saveImage(inputHash, request.ClientID, img, int64(len(original)))
+
+		res = domain.ImageResponse{
+			ID: inputHash,
+			Width: img.Bounds().Dx(),
+			Height: img.Bounds().Dy(),
+			SizeBytes: int64(len(original)),
+			ContentType: jpegContentType,
+			FileImage: i.publicURL(beegoCtx, inputKeyOf(inputHash)),
+			PathDirImage: inputKeyOf(inputHash),
+			OwnerToken: i.urlSigner.Token(imageOwnerSubjectOf(request.ClientID, inputHash)),
+			CreatedAt: time.Now(),
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.ImageResponse{},err
+	}
+
+	return res,nil
+}
+
+func (i imageAdjustmentUseCase) GetImage(beegoCtx *beegoContext.Context, id string) (res domain.ImageResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	if !isContentHash(id) {
+		return res,response.ErrImageNotFound
+	}
+
+	inputKey := inputKeyOf(id)
+	info, err := i.storage.Stat(ctx, inputKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return res,response.ErrImageNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res,err
 	}
 
-	return domain.ImageAdjustmentResponse{
-		InputPathDirImage: *inputFile,
-		InputFileImage:  fmt.Sprint("http://", beegoCtx.Request.Host ,"/", *inputFile),
-		OutputPathDirImage: *outputFile,
-		OutputFileImage: fmt.Sprint("http://", beegoCtx.Request.Host ,"/", *outputFile),
+	// Only the header is decoded for the dimensions when the image is not cached
+	var width, height int
+	if img, ok := i.imageCache.Get(id); ok {
+		width, height = img.Bounds().Dx(), img.Bounds().Dy()
+	} else {
+		reader, err := i.storage.Get(ctx, inputKey)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return res,err
+		}
+		config, _, err := image.DecodeConfig(reader)
+		reader.Close()
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return res,err
+		}
+		width, height = config.Width, config.Height
+	}
+
+	return domain.ImageResponse{
+		ID: id,
+		Width: width,
+		Height: height,
+		SizeBytes: info.Size,
+		ContentType: jpegContentType,
+		FileImage: i.publicURL(beegoCtx, inputKey),
+		PathDirImage: inputKey,
+		CreatedAt: info.ModTime,
 	},nil
-}
\ No newline at end of file
+}
+
+// verifyOwner checks that the client was handed the owner token of the image on upload
+func (i imageAdjustmentUseCase) verifyOwner(request domain.ImageOwnerRequest) error {
+	if !isContentHash(request.ID) {
+		return response.ErrImageNotFound
+	}
+	return i.urlSigner.VerifyToken(imageOwnerSubjectOf(request.ClientID, request.ID), request.OwnerToken)
+}
+
+// DeleteImage removes the markers of the owner and every object of the image no
+// other client or pending job still uses. Storage is cleaned before the record
+// is removed so a failure leaves the image registered and the delete can be retried.
+func (i imageAdjustmentUseCase) DeleteImage(beegoCtx *beegoContext.Context, request domain.ImageOwnerRequest) error {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	if err := i.verifyOwner(request); err != nil {
+		return err
+	}
+	id := request.ID
+
+	markers, err := i.storage.List(ctx, clientPrefix)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return err
+	}
+
+	ownerPrefix := clientMarkerPrefixOf(request.ClientID)
+	var ownerMarkers []string
+	owned := false
+	inputShared := false
+	sharedOutputs := map[string]bool{}
+	for _, marker := range markers {
+		inputHash, output := inputHashOfMarker(marker.Key)
+		if inputHash != id {
+			continue
+		}
+		if strings.HasPrefix(marker.Key, ownerPrefix) {
+			ownerMarkers = append(ownerMarkers, marker.Key)
+			owned = owned || !output
+			continue
+		}
+		inputShared = true
+		if output {
+			sharedOutputs[outputNameOfMarker(marker.Key)] = true
+		}
+	}
+	if !owned {
+		return response.ErrImageNotFound
+	}
+
+	// a queued or running job still reads the input
+	pending, err := i.adjustmentJobRepository.FetchByStatus(ctx, []string{domain.JobStatusQueued, domain.JobStatusRunning}, -1)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return err
+	}
+	for _, job := range pending {
+		inputShared = inputShared || job.ImageID == id
+	}
+
+	// outputs of a shared input are only removed when the owner requested them
+	ownerOutputs := map[string]bool{}
+	for _, markerKey := range ownerMarkers {
+		ownerOutputs[outputNameOfMarker(markerKey)] = true
+	}
+	outputs, err := i.storage.List(ctx, outputPrefix+id+"-")
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return err
+	}
+	for _, output := range outputs {
+		group := outputGroupOf(output.Key)
+		if sharedOutputs[group] || (inputShared && !ownerOutputs[group]) {
+			continue
+		}
+		err = i.storage.Delete(ctx, output.Key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+	}
+
+	if !inputShared {
+		err = i.storage.Delete(ctx, inputKeyOf(id))
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+	}
+
+	// the markers go last, until then the owner can retry a failed delete
+	for _, markerKey := range ownerMarkers {
+		err = i.storage.Delete(ctx, markerKey)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+	}
+
+	if inputShared {
+		return nil
+	}
+	i.imageCache.Remove(id)
+
+	// the adjustment history of the image is kept
+	err = i.imageRepository.Delete(ctx, id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return err
+	}
+
+	return nil
+}
+
+func (i imageAdjustmentUseCase) ImageTemperature(beegoCtx *beegoContext.Context, id string, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse, err error) {
+	if !isContentHash(id) {
+		return res,response.ErrImageNotFound
+	}
+	job, err := i.startJob(id, request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	defer func() { i.finishJob(job, res, err) }()
+
+	err = i.inTransaction(beegoCtx, i.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		img, err := i.loadImage(ctx, id)
+		if err != nil {
+			if !errors.Is(err, response.ErrImageNotFound) {
+				beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			}
+			return err
+		}
+
+		res, err = i.adjustDecoded(ctx, beegoCtx, tx, img, id, true, request)
+		if err != nil {
+			return err
+		}
+		return i.publish(ctx, beegoCtx, tx, request.ClientID, &res)
+	})
+	if err != nil {
+		return domain.ImageAdjustmentResponse{},err
+	}
+
+	return res,nil
+}
+
+// FetchImageAdjustments returns the adjustments the owner requested of an image, latest first
+func (i imageAdjustmentUseCase) FetchImageAdjustments(beegoCtx *beegoContext.Context, request domain.ImageOwnerRequest) (res []domain.AdjustmentJob, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	if err := i.verifyOwner(request); err != nil {
+		return nil,err
+	}
+
+	res, err = i.adjustmentJobRepository.FetchByImageID(ctx, request.ID, request.ClientID, adjustmentHistoryLimit)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return nil,err
+	}
+
+	return res,nil
+}
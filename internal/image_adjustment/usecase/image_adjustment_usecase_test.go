@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/signer"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/webhook"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fakeImageRepository struct {
+	mutex  sync.Mutex
+	images map[string]domain.Image
+}
+
+func (r *fakeImageRepository) Save(ctx context.Context, image *domain.Image) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.images[image.ID]; !ok {
+		r.images[image.ID] = *image
+	}
+	return nil
+}
+
+func (r *fakeImageRepository) GetByID(ctx context.Context, id string) (domain.Image, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	image, ok := r.images[id]
+	if !ok {
+		return image, gorm.ErrRecordNotFound
+	}
+	return image, nil
+}
+
+func (r *fakeImageRepository) FetchByIDs(ctx context.Context, ids []string) ([]domain.Image, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []domain.Image
+	for _, id := range ids {
+		if image, ok := r.images[id]; ok {
+			result = append(result, image)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeImageRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.images[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.images, id)
+	return nil
+}
+
+type fakeAdjustmentJobRepository struct {
+	mutex sync.Mutex
+	jobs  map[string]domain.AdjustmentJob
+}
+
+func (r *fakeAdjustmentJobRepository) Create(ctx context.Context, job *domain.AdjustmentJob) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job.CreatedAt = time.Now()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *fakeAdjustmentJobRepository) Update(ctx context.Context, job *domain.AdjustmentJob) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *fakeAdjustmentJobRepository) GetByID(ctx context.Context, id string) (domain.AdjustmentJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return job, gorm.ErrRecordNotFound
+	}
+	return job, nil
+}
+
+func (r *fakeAdjustmentJobRepository) FetchByImageID(ctx context.Context, imageID, clientID string, limit int) ([]domain.AdjustmentJob, error) {
+	return r.fetch(func(job domain.AdjustmentJob) bool { return job.ImageID == imageID && job.ClientID == clientID }, limit), nil
+}
+
+func (r *fakeAdjustmentJobRepository) FetchByStatus(ctx context.Context, statuses []string, limit int) ([]domain.AdjustmentJob, error) {
+	return r.fetch(func(job domain.AdjustmentJob) bool {
+		for _, status := range statuses {
+			if job.Status == status {
+				return true
+			}
+		}
+		return false
+	}, limit), nil
+}
+
+func (r *fakeAdjustmentJobRepository) FetchByCallbackStatus(ctx context.Context, status string, limit int) ([]domain.AdjustmentJob, error) {
+	return r.fetch(func(job domain.AdjustmentJob) bool { return job.CallbackStatus == status }, limit), nil
+}
+
+func (r *fakeAdjustmentJobRepository) fetch(match func(job domain.AdjustmentJob) bool, limit int) []domain.AdjustmentJob {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []domain.AdjustmentJob
+	for _, job := range r.jobs {
+		if match(job) {
+			result = append(result, job)
+		}
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].QueuedAt.Before(result[b].QueuedAt) })
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+type fakeWebhookDeliveryRepository struct {
+	mutex      sync.Mutex
+	deliveries []domain.WebhookDelivery
+}
+
+func (r *fakeWebhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *fakeWebhookDeliveryRepository) FetchByJobID(ctx context.Context, jobID string) ([]domain.WebhookDelivery, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.JobID == jobID {
+			result = append(result, delivery)
+		}
+	}
+	return result, nil
+}
+
+type testUseCase struct {
+	*imageAdjustmentUseCase
+	images     *fakeImageRepository
+	jobs       *fakeAdjustmentJobRepository
+	deliveries *fakeWebhookDeliveryRepository
+}
+
+func newTestUseCase(t *testing.T) testUseCase {
+	urlSigner, err := signer.NewURLSigner(map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")}, "k1", time.Hour)
+	assert.NoError(t, err)
+
+	images := &fakeImageRepository{images: map[string]domain.Image{}}
+	jobs := &fakeAdjustmentJobRepository{jobs: map[string]domain.AdjustmentJob{}}
+	deliveries := &fakeWebhookDeliveryRepository{}
+	useCase := NewImageAdjustmentUseCase(
+		10*time.Second,
+		zaplogger.NewZapLogger(filepath.Join(t.TempDir(), "test.log"), ""),
+		storage.NewMemoryStorage("/files/"),
+		urlSigner,
+		helper.ConfigHelper{AppUrl: "http://localhost"},
+		1<<20,
+		images,
+		jobs,
+		4,
+		deliveries,
+		// callbacks go to httptest servers on loopback
+		webhook.NewSender(webhook.Config{Secret: "secret", MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, Timeout: time.Second, AllowPrivateNetworks: true}),
+		domain.BatchLimits{},
+	).(*imageAdjustmentUseCase)
+	return testUseCase{imageAdjustmentUseCase: useCase, images: images, jobs: jobs, deliveries: deliveries}
+}
+
+func newTestContext() *beegoContext.Context {
+	beegoCtx := beegoContext.NewContext()
+	beegoCtx.Reset(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/image_adjustment/temperature", nil))
+	return beegoCtx
+}
+
+type uploadFile struct {
+	*bytes.Reader
+}
+
+func (uploadFile) Close() error { return nil }
+
+func uploadOf(data []byte) multipart.File {
+	return uploadFile{bytes.NewReader(data)}
+}
+
+func domainRequest(upload []byte, adjustment float64) domain.ImageAdjustmentRequest {
+	return domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: adjustment}
+}
+
+// gradientJpeg is a small opaque jpeg upload, seed makes uploads differ
+func gradientJpeg(t *testing.T, width, height int, seed uint8) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: seed, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	return buf.Bytes()
+}
+
+func TestImageAdjustmentTemperatureCacheHitHonoursStoreInput(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 32, 32, 1)
+
+	stored, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		AdjustmentTemperature: 1.1,
+	})
+	assert.NoError(t, err)
+	assert.False(t, stored.Cached)
+	assert.NotEmpty(t, stored.InputPathDirImage)
+	assert.NotEmpty(t, stored.InputFileImage)
+
+	notStored, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		AdjustmentTemperature: 1.1,
+		StoreInput:            "false",
+	})
+	assert.NoError(t, err)
+	assert.True(t, notStored.Cached)
+	assert.Equal(t, stored.OutputPathDirImage, notStored.OutputPathDirImage)
+	assert.Empty(t, notStored.InputPathDirImage)
+	assert.Empty(t, notStored.InputFileImage)
+
+	again, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		AdjustmentTemperature: 1.1,
+	})
+	assert.NoError(t, err)
+	assert.True(t, again.Cached)
+	assert.Equal(t, stored.InputPathDirImage, again.InputPathDirImage)
+}
+
+func TestImageAdjustmentTemperatureManifestWithoutInput(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 32, 32, 2)
+
+	// the first request does not store its input, the manifest has no input key
+	first, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		AdjustmentTemperature: 0.9,
+		StoreInput:            "false",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, first.InputPathDirImage)
+	assert.Empty(t, useCase.images.images)
+
+	second, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		AdjustmentTemperature: 0.9,
+	})
+	assert.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, inputKeyOf(imageIDOfKey(second.InputPathDirImage)), second.InputPathDirImage)
+	assert.Len(t, useCase.images.images, 1)
+}
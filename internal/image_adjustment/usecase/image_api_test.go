@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func createImage(t *testing.T, useCase testUseCase, upload []byte, clientID string) domain.ImageResponse {
+	res, err := useCase.CreateImage(newTestContext(), domain.ImageRequest{File: uploadOf(upload), ClientID: clientID})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.OwnerToken)
+	return res
+}
+
+func adjustImage(t *testing.T, useCase testUseCase, id, clientID string, adjustment float64) domain.ImageAdjustmentResponse {
+	res, err := useCase.ImageTemperature(newTestContext(), id, domain.ImageAdjustmentRequest{AdjustmentTemperature: adjustment, ClientID: clientID})
+	assert.NoError(t, err)
+	return res
+}
+
+func stored(useCase testUseCase, key string) bool {
+	_, err := useCase.storage.Stat(context.Background(), key)
+	return err == nil
+}
+
+func TestDeleteImageRequiresTheOwnerToken(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 1), "alice")
+	other := createImage(t, useCase, gradientJpeg(t, 16, 16, 2), "bob")
+
+	tests := []struct {
+		name    string
+		request domain.ImageOwnerRequest
+		err     error
+	}{
+		{name: "no token", request: domain.ImageOwnerRequest{ID: image.ID, ClientID: "alice"}, err: response.ErrMissingToken},
+		{name: "other client", request: domain.ImageOwnerRequest{ID: image.ID, ClientID: "bob", OwnerToken: image.OwnerToken}, err: response.ErrInvalidToken},
+		{name: "token of another image", request: domain.ImageOwnerRequest{ID: image.ID, ClientID: "alice", OwnerToken: other.OwnerToken}, err: response.ErrInvalidToken},
+		{name: "forged token", request: domain.ImageOwnerRequest{ID: image.ID, ClientID: "alice", OwnerToken: "k1.forged"}, err: response.ErrInvalidToken},
+		{name: "invalid id", request: domain.ImageOwnerRequest{ID: "../input", ClientID: "alice", OwnerToken: image.OwnerToken}, err: response.ErrImageNotFound},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.err, useCase.DeleteImage(newTestContext(), test.request), test.name)
+		_, err := useCase.FetchImageAdjustments(newTestContext(), test.request)
+		assert.Equal(t, test.err, err, test.name)
+	}
+	assert.True(t, stored(useCase, image.PathDirImage))
+	assert.Contains(t, useCase.images.images, image.ID)
+}
+
+func TestDeleteImageKeepsWhatOtherClientsUse(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 16, 16, 3)
+
+	alice := createImage(t, useCase, upload, "alice")
+	bob := createImage(t, useCase, upload, "bob")
+	assert.Equal(t, alice.ID, bob.ID)
+	assert.NotEqual(t, alice.OwnerToken, bob.OwnerToken)
+
+	aliceOutput := adjustImage(t, useCase, alice.ID, "alice", 1.1)
+	bobOutput := adjustImage(t, useCase, bob.ID, "bob", 0.9)
+	sharedOutput := adjustImage(t, useCase, alice.ID, "alice", 1.3)
+	adjustImage(t, useCase, bob.ID, "bob", 1.3)
+
+	err := useCase.DeleteImage(newTestContext(), domain.ImageOwnerRequest{ID: alice.ID, ClientID: "alice", OwnerToken: alice.OwnerToken})
+	assert.NoError(t, err)
+
+	// bob still uses the input and the output both requested
+	assert.True(t, stored(useCase, alice.PathDirImage))
+	assert.Contains(t, useCase.images.images, alice.ID)
+	assert.False(t, stored(useCase, aliceOutput.OutputPathDirImage))
+	assert.True(t, stored(useCase, bobOutput.OutputPathDirImage))
+	assert.True(t, stored(useCase, sharedOutput.OutputPathDirImage))
+
+	// alice has nothing left to delete
+	err = useCase.DeleteImage(newTestContext(), domain.ImageOwnerRequest{ID: alice.ID, ClientID: "alice", OwnerToken: alice.OwnerToken})
+	assert.Equal(t, response.ErrImageNotFound, err)
+
+	err = useCase.DeleteImage(newTestContext(), domain.ImageOwnerRequest{ID: bob.ID, ClientID: "bob", OwnerToken: bob.OwnerToken})
+	assert.NoError(t, err)
+	assert.False(t, stored(useCase, bob.PathDirImage))
+	assert.NotContains(t, useCase.images.images, bob.ID)
+
+	remaining, err := useCase.storage.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestDeleteImageKeepsTheInputOfPendingJobs(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 4), "alice")
+	output := adjustImage(t, useCase, image.ID, "alice", 1.1)
+	useCase.jobs.jobs["queued"] = domain.AdjustmentJob{ID: "queued", ImageID: image.ID, ClientID: "bob", Status: domain.JobStatusQueued}
+
+	err := useCase.DeleteImage(newTestContext(), domain.ImageOwnerRequest{ID: image.ID, ClientID: "alice", OwnerToken: image.OwnerToken})
+	assert.NoError(t, err)
+	assert.True(t, stored(useCase, image.PathDirImage))
+	assert.False(t, stored(useCase, output.OutputPathDirImage))
+	assert.Contains(t, useCase.images.images, image.ID)
+}
+
+// failingDeleteStorage fails every delete, the record of the image must survive it
+type failingDeleteStorage struct {
+	storage.Storage
+}
+
+func (failingDeleteStorage) Delete(ctx context.Context, key string) error {
+	return assert.AnError
+}
+
+func TestDeleteImageKeepsTheRecordWhenStorageFails(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 5), "alice")
+	useCase.storage = failingDeleteStorage{useCase.storage}
+
+	err := useCase.DeleteImage(newTestContext(), domain.ImageOwnerRequest{ID: image.ID, ClientID: "alice", OwnerToken: image.OwnerToken})
+	assert.Equal(t, assert.AnError, err)
+	assert.Contains(t, useCase.images.images, image.ID)
+}
+
+func TestFetchImageAdjustmentsIsScopedToTheOwner(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 16, 16, 6)
+	alice := createImage(t, useCase, upload, "alice")
+	bob := createImage(t, useCase, upload, "bob")
+
+	adjustImage(t, useCase, alice.ID, "alice", 1.1)
+	adjustImage(t, useCase, alice.ID, "alice", 1.2)
+	adjustImage(t, useCase, bob.ID, "bob", 0.9)
+
+	jobs, err := useCase.FetchImageAdjustments(newTestContext(), domain.ImageOwnerRequest{ID: alice.ID, ClientID: "alice", OwnerToken: alice.OwnerToken})
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	for _, job := range jobs {
+		assert.Equal(t, "alice", job.ClientID)
+	}
+
+	jobs, err = useCase.FetchImageAdjustments(newTestContext(), domain.ImageOwnerRequest{ID: bob.ID, ClientID: "bob", OwnerToken: bob.OwnerToken})
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
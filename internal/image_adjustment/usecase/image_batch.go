@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+// batchArchiveEntries returns the images of an uploaded archive, directories and
+// files added by archivers such as __MACOSX/ or .DS_Store are left out
+func batchArchiveEntries(archive *zip.Reader) []*zip.File {
+	entries := make([]*zip.File, 0, len(archive.File))
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") || strings.HasPrefix(path.Base(file.Name), ".") {
+			continue
+		}
+		entries = append(entries, file)
+	}
+	return entries
+}
+
+// batchOutputNames names the output of every entry after its input with a .jpg
+// extension, names are kept inside the archive root and made unique
+func batchOutputNames(entries []*zip.File) []string {
+	names := make([]string, len(entries))
+	used := map[string]bool{domain.BatchManifestName: true}
+	for index, entry := range entries {
+		name := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(entry.Name, "\\", "/")), "/")
+		base := strings.TrimSuffix(name, path.Ext(name))
+		name = base + ".jpg"
+		for suffix := 2; used[name]; suffix++ {
+			name = fmt.Sprintf("%s-%d.jpg", base, suffix)
+		}
+		used[name] = true
+		names[index] = name
+	}
+	return names
+}
+
+func (i imageAdjustmentUseCase) ImageAdjustmentBatch(beegoCtx *beegoContext.Context, request domain.ImageAdjustmentRequest, openOutput func() io.Writer) (res domain.BatchManifest, err error) {
+	started := time.Now()
+
+	archive, err := zip.NewReader(request.File, request.FileHeader.Size)
+	if err != nil {
+		return res, response.ErrInvalidArchive
+	}
+	entries := batchArchiveEntries(archive)
+	if len(entries) == 0 || (i.batchLimits.MaxEntries > 0 && len(entries) > i.batchLimits.MaxEntries) {
+		return res, response.ErrInvalidArchive
+	}
+
+	params, err := requestParams(request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	res.Params = json.RawMessage(params)
+
+	// Outputs only go into the returned archive, nothing of a batch is stored
+	request.Preview = "true"
+	request.StoreInput = "false"
+	request.Sizes = nil
+	request.Async = ""
+	entryParams, err := requestParams(request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.batchLimits.Timeout)
+	defer cancel()
+
+	output := zip.NewWriter(openOutput())
+	var outputLock sync.Mutex
+	outputNames := batchOutputNames(entries)
+	res.Entries = make([]domain.BatchEntryResult, len(entries))
+
+	i.forEachConcurrently(len(entries), func(index int) {
+		res.Entries[index] = i.adjustBatchEntry(ctx, entries[index], outputNames[index], request, entryParams, output, &outputLock)
+	}, func(index int, err error) {
+		res.Entries[index] = domain.BatchEntryResult{
+			Name:      entries[index].Name,
+			Status:    domain.BatchEntryFailed,
+			ErrorCode: response.ErrorCodeOf(err),
+			Error:     err.Error(),
+			Params:    json.RawMessage(entryParams),
+		}
+	})
+
+	res.Total = len(entries)
+	for _, entry := range res.Entries {
+		if entry.Status == domain.BatchEntryDone {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+	}
+	res.DurationMs = time.Since(started).Milliseconds()
+
+	manifest, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	if err := writeBatchOutput(output, &outputLock, domain.BatchManifestName, manifest, zip.Deflate); err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	if err := output.Close(); err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	return res, nil
+}
+
+// adjustBatchEntry adjusts one image of a batch and adds it to output, a failure is only reported in the result
+func (i imageAdjustmentUseCase) adjustBatchEntry(ctx context.Context, entry *zip.File, outputName string, request domain.ImageAdjustmentRequest, params string, output *zip.Writer, outputLock *sync.Mutex) domain.BatchEntryResult {
+	started := time.Now()
+	result := domain.BatchEntryResult{Name: entry.Name, Params: json.RawMessage(params)}
+
+	adjusted, inputHash, err := i.adjustArchiveEntry(ctx, entry, request)
+	if inputHash != "" {
+		requestHash, hashErr := paramsHash(request)
+		if hashErr == nil {
+			result.OutputKey = outputNameOf(inputHash, requestHash) + ".jpg"
+		}
+	}
+	if err == nil {
+		// JPEG data does not deflate, it is stored as is
+		err = writeBatchOutput(output, outputLock, outputName, adjusted.OutputImage, zip.Store)
+	}
+	result.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Status = domain.BatchEntryFailed
+		result.ErrorCode = response.ErrorCodeOf(err)
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = domain.BatchEntryDone
+	result.Output = outputName
+	result.Quality = adjusted.Quality
+	result.OutputSizeBytes = adjusted.OutputSizeBytes
+	result.Width = adjusted.Width
+	result.Height = adjusted.Height
+	result.Cached = adjusted.Cached
+	result.Metrics = adjusted.Metrics
+	return result
+}
+
+// adjustArchiveEntry adjusts one entry, inputHash is set once the entry decoded
+func (i imageAdjustmentUseCase) adjustArchiveEntry(ctx context.Context, entry *zip.File, request domain.ImageAdjustmentRequest) (res domain.ImageAdjustmentResponse, inputHash string, err error) {
+	if err := ctx.Err(); err != nil {
+		return res, "", err
+	}
+	if entry.UncompressedSize64 > maxStoredInputBytes {
+		return res, "", response.ErrUploadTooLarge
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return res, "", err
+	}
+	defer reader.Close()
+
+	// the declared size may lie, read one byte more than allowed to notice
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxStoredInputBytes+1))
+	if err != nil {
+		return res, "", err
+	}
+	if len(data) > maxStoredInputBytes {
+		return res, "", response.ErrUploadTooLarge
+	}
+	if http.DetectContentType(data) != jpegContentType {
+		return res, "", response.ErrInvalidFormatFileJpeg
+	}
+
+	img, _, inputHash, err := decodeUpload(bytes.NewReader(data), false)
+	if err != nil {
+		return res, "", fmt.Errorf("%w: %v", response.ErrInvalidFormatFileJpeg, err)
+	}
+
+	// every entry gets its own context, the request context is shared by the workers
+	res, err = i.adjustDecoded(ctx, newJobContext(), nil, img, inputHash, false, request)
+	return res, inputHash, err
+}
+
+// writeBatchOutput adds one entry to the output archive, entries are written one at a time
+func writeBatchOutput(output *zip.Writer, outputLock *sync.Mutex, name string, data []byte, method uint16) error {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+
+	writer, err := output.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// forEachConcurrently calls fn for every index below count on a pool of
+// batchLimits.Workers workers and returns once every call is done. A panic of
+// fn is handed to failed with its index, the other indexes carry on
+func (i imageAdjustmentUseCase) forEachConcurrently(count int, fn func(index int), failed func(index int, err error)) {
+	workers := i.batchLimits.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				i.callRecovered(index, fn, failed)
+			}
+		}()
+	}
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+func (i imageAdjustmentUseCase) callRecovered(index int, fn func(index int), failed func(index int, err error)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			failed(index, i.panicError(recovered))
+		}
+	}()
+	fn(index)
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// zipOf builds an archive of the named entries in order
+func zipOf(t *testing.T, entries ...interface{}) []byte {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for index := 0; index < len(entries); index += 2 {
+		writer, err := archive.Create(entries[index].(string))
+		assert.NoError(t, err)
+		_, err = writer.Write(entries[index+1].([]byte))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, archive.Close())
+	return buffer.Bytes()
+}
+
+func batchRequest(archive []byte, adjustment float64) domain.ImageAdjustmentRequest {
+	return domain.ImageAdjustmentRequest{
+		File:                  uploadOf(archive),
+		FileHeader:            &multipart.FileHeader{Size: int64(len(archive))},
+		AdjustmentTemperature: adjustment,
+	}
+}
+
+func TestImageAdjustmentBatch(t *testing.T) {
+	useCase := newTestUseCase(t)
+	useCase.batchLimits = domain.BatchLimits{Workers: 2, MaxEntries: 10, Timeout: 10 * time.Second}
+	archive := zipOf(t,
+		"shoot/a.jpeg", gradientJpeg(t, 16, 12, 21),
+		"shoot/notes.txt", []byte("not an image"),
+		"__MACOSX/shoot/._a.jpeg", []byte("resource fork"),
+		"b.jpg", gradientJpeg(t, 8, 8, 22),
+		"b.JPG", gradientJpeg(t, 8, 8, 23),
+	)
+
+	var output bytes.Buffer
+	manifest, err := useCase.ImageAdjustmentBatch(newTestContext(), batchRequest(archive, 1.1), func() io.Writer { return &output })
+	assert.NoError(t, err)
+	assert.Equal(t, 4, manifest.Total)
+	assert.Equal(t, 3, manifest.Succeeded)
+	assert.Equal(t, 1, manifest.Failed)
+
+	if assert.Len(t, manifest.Entries, 4) {
+		done := manifest.Entries[0]
+		assert.Equal(t, "shoot/a.jpeg", done.Name)
+		assert.Equal(t, domain.BatchEntryDone, done.Status)
+		assert.Equal(t, "shoot/a.jpg", done.Output)
+		assert.Regexp(t, `^output/[0-9a-f]{64}-[0-9a-f]{64}\.jpg$`, done.OutputKey)
+		assert.Equal(t, 16, done.Width)
+		var params domain.ImageAdjustmentRequest
+		assert.NoError(t, json.Unmarshal(done.Params, &params))
+		assert.Equal(t, 1.1, params.AdjustmentTemperature)
+
+		failed := manifest.Entries[1]
+		assert.Equal(t, "shoot/notes.txt", failed.Name)
+		assert.Equal(t, domain.BatchEntryFailed, failed.Status)
+		assert.Equal(t, response.ErrorCodeOf(response.ErrInvalidFormatFileJpeg), failed.ErrorCode)
+		assert.NotEmpty(t, failed.Error)
+		assert.Empty(t, failed.Output)
+		assert.Empty(t, failed.OutputKey)
+		assert.JSONEq(t, string(done.Params), string(failed.Params))
+
+		// names which collide once the extension is replaced are made unique
+		assert.Equal(t, []string{"b.jpg", "b-2.jpg"}, []string{manifest.Entries[2].Output, manifest.Entries[3].Output})
+		assert.NotEqual(t, manifest.Entries[2].OutputKey, manifest.Entries[3].OutputKey)
+	}
+
+	result, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	assert.NoError(t, err)
+	names := map[string]*zip.File{}
+	for _, file := range result.File {
+		names[file.Name] = file
+	}
+	assert.Len(t, names, 4)
+	for _, name := range []string{"shoot/a.jpg", "b.jpg", "b-2.jpg", domain.BatchManifestName} {
+		assert.Contains(t, names, name)
+	}
+	reader, err := names[domain.BatchManifestName].Open()
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		var written domain.BatchManifest
+		assert.NoError(t, json.Unmarshal(data, &written))
+		assert.Equal(t, manifest.Entries[0].OutputKey, written.Entries[0].OutputKey)
+		assert.Equal(t, manifest.Entries[1].ErrorCode, written.Entries[1].ErrorCode)
+	}
+
+	// nothing of a batch is stored
+	_, err = useCase.storage.Stat(context.Background(), manifest.Entries[0].OutputKey)
+	assert.Error(t, err)
+}
+
+func TestImageAdjustmentBatchRejectsArchives(t *testing.T) {
+	useCase := newTestUseCase(t)
+	useCase.batchLimits = domain.BatchLimits{MaxEntries: 1, Timeout: 10 * time.Second}
+	opened := false
+	openOutput := func() io.Writer {
+		opened = true
+		return ioutil.Discard
+	}
+
+	for _, archive := range [][]byte{
+		[]byte("not a zip"),
+		zipOf(t, "__MACOSX/._a.jpg", []byte("x"), ".DS_Store", []byte("x")),
+		zipOf(t, "a.jpg", gradientJpeg(t, 8, 8, 24), "b.jpg", gradientJpeg(t, 8, 8, 25)),
+	} {
+		_, err := useCase.ImageAdjustmentBatch(newTestContext(), batchRequest(archive, 1.1), openOutput)
+		assert.Equal(t, response.ErrInvalidArchive, err)
+	}
+	// archive errors are answered before the output is opened
+	assert.False(t, opened)
+}
+
+func TestForEachConcurrentlyRecoversEveryIndex(t *testing.T) {
+	useCase := newTestUseCase(t)
+	useCase.batchLimits.Workers = 2
+
+	done := make([]bool, 6)
+	failed := map[int]error{}
+	var failedLock sync.Mutex
+	useCase.forEachConcurrently(len(done), func(index int) {
+		if index%3 == 1 {
+			panic("broken entry")
+		}
+		done[index] = true
+	}, func(index int, err error) {
+		failedLock.Lock()
+		defer failedLock.Unlock()
+		failed[index] = err
+	})
+
+	// a panic fails its own index only, the workers go on with the others
+	assert.Equal(t, []bool{true, false, true, true, false, true}, done)
+	if assert.Len(t, failed, 2) {
+		assert.EqualError(t, failed[1], "panic: broken entry")
+		assert.EqualError(t, failed[4], "panic: broken entry")
+	}
+}
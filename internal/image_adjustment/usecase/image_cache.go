@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+// decodedImageCache keeps recently used decoded images by input hash so
+// repeated adjustments of the same image skip reading and decoding it.
+// It is bounded by the approximate RGBA size of the images it holds.
+type decodedImageCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type decodedImageEntry struct {
+	inputHash string
+	img       image.Image
+	size      int64
+}
+
+func newDecodedImageCache(maxBytes int64) *decodedImageCache {
+	return &decodedImageCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func decodedImageSize(img image.Image) int64 {
+	bounds := img.Bounds()
+	return int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+}
+
+// Get returns the image, cached images are shared and must not be modified
+func (c *decodedImageCache) Get(inputHash string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[inputHash]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*decodedImageEntry).img, true
+}
+
+func (c *decodedImageCache) Add(inputHash string, img image.Image) {
+	size := decodedImageSize(img)
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[inputHash]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[inputHash] = c.order.PushFront(&decodedImageEntry{inputHash: inputHash, img: img, size: size})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *decodedImageCache) Remove(inputHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[inputHash]; ok {
+		c.removeElement(element)
+	}
+}
+
+func (c *decodedImageCache) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*decodedImageEntry)
+	delete(c.entries, entry.inputHash)
+	c.bytes -= entry.size
+}
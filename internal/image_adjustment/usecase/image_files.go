@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+// fileContext shares the request of beegoCtx but keeps its own input data, the
+// stack trace of one file must not race with the others
+func fileContext(beegoCtx *beegoContext.Context) *beegoContext.Context {
+	fileCtx := beegoContext.NewContext()
+	fileCtx.Request = beegoCtx.Request
+	return fileCtx
+}
+
+func (i imageAdjustmentUseCase) ImageAdjustmentTemperatureFiles(beegoCtx *beegoContext.Context, requests []domain.ImageAdjustmentRequest) (res []domain.ImageAdjustmentFileResult, err error) {
+	if i.batchLimits.MaxEntries > 0 && len(requests) > i.batchLimits.MaxEntries {
+		return nil, response.ErrTooManyFiles
+	}
+
+	res = make([]domain.ImageAdjustmentFileResult, len(requests))
+	i.forEachConcurrently(len(requests), func(index int) {
+		request := requests[index]
+		res[index].FileName = request.FileHeader.Filename
+
+		var data interface{}
+		var err error
+		if request.Async == "true" {
+			data, err = i.EnqueueImageAdjustmentTemperature(fileContext(beegoCtx), request)
+		} else {
+			data, err = i.ImageAdjustmentTemperature(fileContext(beegoCtx), request)
+		}
+		if err != nil {
+			failFileResult(&res[index], err)
+			return
+		}
+		res[index].Status = domain.BatchEntryDone
+		res[index].Data = data
+	}, func(index int, err error) {
+		res[index].FileName = requests[index].FileHeader.Filename
+		failFileResult(&res[index], err)
+	})
+	return res, nil
+}
+
+// failFileResult reports the file of result as failed with err
+func failFileResult(result *domain.ImageAdjustmentFileResult, err error) {
+	result.Status = domain.BatchEntryFailed
+	result.Code = response.ErrorCodeOf(err)
+	result.Message = err.Error()
+	result.Data = nil
+}
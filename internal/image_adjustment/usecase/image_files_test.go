@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"mime/multipart"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func fileRequest(name string, upload []byte, adjustment float64) domain.ImageAdjustmentRequest {
+	request := domainRequest(upload, adjustment)
+	request.FileHeader = &multipart.FileHeader{Filename: name, Size: int64(len(upload))}
+	request.ClientID = "alice"
+	return request
+}
+
+func TestImageAdjustmentTemperatureFiles(t *testing.T) {
+	useCase := newTestUseCase(t)
+	truncated := gradientJpeg(t, 16, 16, 32)
+	truncated = truncated[:len(truncated)/3]
+
+	async := fileRequest("c.jpg", gradientJpeg(t, 16, 16, 33), 1.2)
+	async.Async = "true"
+	results, err := useCase.ImageAdjustmentTemperatureFiles(newTestContext(), []domain.ImageAdjustmentRequest{
+		fileRequest("a.jpg", gradientJpeg(t, 16, 16, 31), 1.2),
+		fileRequest("b.jpg", truncated, 1.2),
+		async,
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "a.jpg", results[0].FileName)
+		assert.Equal(t, domain.BatchEntryDone, results[0].Status)
+		if data, ok := results[0].Data.(domain.ImageAdjustmentResponse); assert.True(t, ok) {
+			assert.NotEmpty(t, data.OutputPathDirImage)
+			assert.Contains(t, data.OutputFileImage, "signature=")
+		}
+
+		// a file which fails does not fail the others
+		assert.Equal(t, "b.jpg", results[1].FileName)
+		assert.Equal(t, domain.BatchEntryFailed, results[1].Status)
+		assert.NotEmpty(t, results[1].Code)
+		assert.NotEmpty(t, results[1].Message)
+		assert.Nil(t, results[1].Data)
+
+		assert.Equal(t, "c.jpg", results[2].FileName)
+		assert.Equal(t, domain.BatchEntryDone, results[2].Status)
+		if job, ok := results[2].Data.(domain.JobResponse); assert.True(t, ok) {
+			assert.Equal(t, domain.JobStatusQueued, job.Status)
+		}
+	}
+}
+
+func TestImageAdjustmentTemperatureFilesReportsAPanic(t *testing.T) {
+	useCase := newTestUseCase(t)
+	useCase.storage = panickingStorage{Storage: useCase.storage, clientID: "bob"}
+
+	broken := fileRequest("b.jpg", gradientJpeg(t, 16, 16, 36), 1.2)
+	broken.ClientID = "bob"
+	results, err := useCase.ImageAdjustmentTemperatureFiles(newTestContext(), []domain.ImageAdjustmentRequest{
+		fileRequest("a.jpg", gradientJpeg(t, 16, 16, 35), 1.2),
+		broken,
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, domain.BatchEntryDone, results[0].Status)
+		assert.Equal(t, "b.jpg", results[1].FileName)
+		assert.Equal(t, domain.BatchEntryFailed, results[1].Status)
+		assert.Equal(t, response.ServerErrorCode, results[1].Code)
+		assert.Equal(t, "panic: marker storage broke", results[1].Message)
+	}
+}
+
+func TestImageAdjustmentTemperatureFilesLimit(t *testing.T) {
+	useCase := newTestUseCase(t)
+	useCase.batchLimits.MaxEntries = 1
+	upload := gradientJpeg(t, 8, 8, 34)
+
+	_, err := useCase.ImageAdjustmentTemperatureFiles(newTestContext(), []domain.ImageAdjustmentRequest{
+		fileRequest("a.jpg", upload, 1.2),
+		fileRequest("b.jpg", upload, 1.2),
+	})
+	assert.Equal(t, response.ErrTooManyFiles, err)
+	assert.Empty(t, useCase.jobs.jobs)
+}
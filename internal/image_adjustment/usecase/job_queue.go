@@ -0,0 +1,300 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+)
+
+// adjustmentJobQueue hands job ids to the workers, the job state itself lives in the repository
+type adjustmentJobQueue struct {
+	jobs    chan string
+	timeout time.Duration
+}
+
+func newAdjustmentJobQueue(size int) *adjustmentJobQueue {
+	return &adjustmentJobQueue{
+		jobs: make(chan string, size),
+	}
+}
+
+func jobResponseOf(job domain.AdjustmentJob) domain.JobResponse {
+	return domain.JobResponse{
+		ID:         job.ID,
+		ImageID:    job.ImageID,
+		Operation:  job.Operation,
+		Status:     job.Status,
+		Error:      job.Error,
+		DurationMs: job.DurationMs,
+		QueuedAt:   job.QueuedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// enqueue persists a queued job for the stored image id and hands it to the workers
+func (i imageAdjustmentUseCase) enqueue(ctx context.Context, imageID string, request domain.ImageAdjustmentRequest) (domain.JobResponse, error) {
+	if len(i.jobQueue.jobs) >= cap(i.jobQueue.jobs) {
+		return domain.JobResponse{}, response.ErrJobQueueFull
+	}
+
+	params, err := requestParams(request)
+	if err != nil {
+		return domain.JobResponse{}, err
+	}
+
+	job := domain.AdjustmentJob{
+		ID:          uuid.New().String(),
+		ImageID:     imageID,
+		ClientID:    request.ClientID,
+		Operation:   domain.OperationTemperature,
+		Params:      params,
+		Status:      domain.JobStatusQueued,
+		CallbackUrl: request.CallbackUrl,
+		QueuedAt:    time.Now(),
+	}
+	if err := i.adjustmentJobRepository.Create(ctx, &job); err != nil {
+		return domain.JobResponse{}, err
+	}
+
+	select {
+	case i.jobQueue.jobs <- job.ID:
+	default:
+		// the queue filled up in the meantime, the job would otherwise only run after a restart.
+		// The client is answered 503 right away, so no callback follows
+		job.CallbackUrl = ""
+		i.finishJob(&job, domain.ImageAdjustmentResponse{}, response.ErrJobQueueFull)
+		return domain.JobResponse{}, response.ErrJobQueueFull
+	}
+
+	return jobResponseOf(job), nil
+}
+
+func (i imageAdjustmentUseCase) EnqueueImageAdjustmentTemperature(beegoCtx *beegoContext.Context, request domain.ImageAdjustmentRequest) (res domain.JobResponse, err error) {
+	if request.Preview == "true" {
+		return res, response.ErrAsyncPreview
+	}
+	if request.Variants > 0 {
+		return res, response.ErrAsyncVariants
+	}
+
+	// The worker reads the input from storage, so it is stored even with store_input=false
+	err = i.inTransaction(beegoCtx, i.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		img, original, inputHash, err := decodeUpload(request.File, true)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+
+		err = putIfAbsent(ctx, tx, inputKeyOf(inputHash), original, jpegContentType)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+		i.imageCache.Add(inputHash, img)
+		i.saveImage(inputHash, request.ClientID, img, int64(len(original)))
+
+		res, err = i.enqueue(ctx, inputHash, request)
+		if err != nil && !errors.Is(err, response.ErrJobQueueFull) {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		}
+		return err
+	})
+	if err != nil {
+		return domain.JobResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (i imageAdjustmentUseCase) EnqueueImageTemperature(beegoCtx *beegoContext.Context, id string, request domain.ImageAdjustmentRequest) (res domain.JobResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	if request.Preview == "true" {
+		return res, response.ErrAsyncPreview
+	}
+	if request.Variants > 0 {
+		return res, response.ErrAsyncVariants
+	}
+	if !isContentHash(id) {
+		return res, response.ErrImageNotFound
+	}
+
+	_, err = i.storage.Stat(ctx, inputKeyOf(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return res, response.ErrImageNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	res, err = i.enqueue(ctx, id, request)
+	if err != nil {
+		if !errors.Is(err, response.ErrJobQueueFull) {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		}
+		return domain.JobResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (i imageAdjustmentUseCase) GetJob(beegoCtx *beegoContext.Context, id string) (res domain.JobResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	job, err := i.adjustmentJobRepository.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, response.ErrJobNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	res = jobResponseOf(job)
+	if job.Status == domain.JobStatusDone && job.Result != "" {
+		var result domain.ImageAdjustmentResponse
+		if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return res, err
+		}
+		i.signResponse(beegoCtx, &result)
+		res.Result = &result
+	}
+
+	return res, nil
+}
+
+func (i imageAdjustmentUseCase) StartWorkers(ctx context.Context, workers int, timeout time.Duration) error {
+	i.jobQueue.timeout = timeout
+
+	// jobs left running by a previous process were interrupted, run them again
+	jobs, err := i.adjustmentJobRepository.FetchByStatus(ctx, []string{domain.JobStatusQueued, domain.JobStatusRunning}, -1)
+	if err != nil {
+		return err
+	}
+	recovered := make([]string, 0, len(jobs))
+	for index := range jobs {
+		if jobs[index].Status == domain.JobStatusRunning {
+			jobs[index].Status = domain.JobStatusQueued
+			jobs[index].StartedAt = nil
+			if err := i.adjustmentJobRepository.Update(ctx, &jobs[index]); err != nil {
+				return err
+			}
+		}
+		recovered = append(recovered, jobs[index].ID)
+	}
+	if len(recovered) > 0 {
+		i.zapLogger.Infof("resuming %d adjustment jobs", len(recovered))
+	}
+	if err := i.resumeCallbacks(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		for _, id := range recovered {
+			select {
+			case i.jobQueue.jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for worker := 0; worker < workers; worker++ {
+		go func() {
+			for {
+				select {
+				case id := <-i.jobQueue.jobs:
+					i.runJob(id)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// newJobContext stands in for the http request of a job processed in the background
+func newJobContext() *beegoContext.Context {
+	beegoCtx := beegoContext.NewContext()
+	beegoCtx.Request = (&http.Request{}).WithContext(context.Background())
+	return beegoCtx
+}
+
+// runJob processes a queued job, a panic fails the job and leaves the worker running
+func (i imageAdjustmentUseCase) runJob(id string) {
+	var running *domain.AdjustmentJob
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err := i.panicError(recovered)
+			if running != nil {
+				i.finishJob(running, domain.ImageAdjustmentResponse{}, err)
+			}
+		}
+	}()
+
+	ctx, cancel := i.recordContext()
+	job, err := i.adjustmentJobRepository.GetByID(ctx, id)
+	cancel()
+	if err != nil {
+		i.zapLogger.Errorf("load adjustment job %s: %s", id, err.Error())
+		return
+	}
+	if job.Status != domain.JobStatusQueued {
+		return
+	}
+
+	var request domain.ImageAdjustmentRequest
+	if err := json.Unmarshal([]byte(job.Params), &request); err != nil {
+		i.finishJob(&job, domain.ImageAdjustmentResponse{}, err)
+		return
+	}
+	request.ClientID = job.ClientID
+	request.CallbackUrl = job.CallbackUrl
+
+	now := time.Now()
+	job.Status = domain.JobStatusRunning
+	job.StartedAt = &now
+	running = &job
+	ctx, cancel = i.recordContext()
+	err = i.adjustmentJobRepository.Update(ctx, &job)
+	cancel()
+	if err != nil {
+		i.zapLogger.Errorf("record adjustment job: %s", err.Error())
+	}
+
+	var res domain.ImageAdjustmentResponse
+	beegoCtx := newJobContext()
+	err = i.inTransaction(beegoCtx, i.jobQueue.timeout, func(ctx context.Context, tx *storageTransaction) error {
+		img, err := i.loadImage(ctx, job.ImageID)
+		if err != nil {
+			return err
+		}
+
+		res, err = i.adjustDecoded(ctx, beegoCtx, tx, img, job.ImageID, true, request)
+		if err != nil {
+			return err
+		}
+		return i.recordClientMarker(ctx, beegoCtx, tx, request.ClientID, res)
+	})
+	if err != nil {
+		i.zapLogger.Errorf("adjustment job %s: %s", job.ID, err.Error())
+	}
+	running = nil
+	i.finishJob(&job, res, err)
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForJob polls the job until it finished or the test gives up
+func waitForJob(t *testing.T, useCase testUseCase, id string) domain.JobResponse {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := useCase.GetJob(newTestContext(), id)
+		assert.NoError(t, err)
+		if job.Status == domain.JobStatusDone || job.Status == domain.JobStatusFailed || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEnqueueRejectsPreviewAndVariants(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 16, 16, 1)
+
+	_, err := useCase.EnqueueImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1.1, Preview: "true"})
+	assert.Equal(t, response.ErrAsyncPreview, err)
+
+	_, err = useCase.EnqueueImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1.1, Variants: 3})
+	assert.Equal(t, response.ErrAsyncVariants, err)
+
+	image := createImage(t, useCase, upload, "alice")
+	_, err = useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.1, Variants: 3})
+	assert.Equal(t, response.ErrAsyncVariants, err)
+	assert.Empty(t, useCase.jobs.jobs)
+}
+
+func TestVariantsRejectAsyncAndCallback(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 16, 16, 2)
+
+	for _, request := range []domain.ImageAdjustmentRequest{
+		{File: uploadOf(upload), AdjustmentTemperature: 1, Variants: 3, Async: "true"},
+		{File: uploadOf(upload), AdjustmentTemperature: 1, Variants: 3, CallbackUrl: "https://example.com/hook"},
+	} {
+		_, err := useCase.ImageAdjustmentVariants(newTestContext(), request)
+		assert.Equal(t, response.ErrAsyncVariants, err)
+	}
+	assert.Empty(t, useCase.jobs.jobs)
+}
+
+func TestEnqueuedJobIsProcessed(t *testing.T) {
+	useCase := newTestUseCase(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 2, 5*time.Second))
+
+	queued, err := useCase.EnqueueImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 16, 16, 3)),
+		AdjustmentTemperature: 1.2,
+		StoreInput:            "false",
+		ClientID:              "alice",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobStatusQueued, queued.Status)
+	assert.NotEmpty(t, queued.ImageID)
+
+	job := waitForJob(t, useCase, queued.ID)
+	assert.Equal(t, domain.JobStatusDone, job.Status)
+	if assert.NotNil(t, job.Result) {
+		assert.NotEmpty(t, job.Result.OutputPathDirImage)
+		assert.Contains(t, job.Result.OutputFileImage, "signature=")
+		// the worker reads the input from storage, so it is stored despite store_input=false
+		assert.Equal(t, inputKeyOf(queued.ImageID), job.Result.InputPathDirImage)
+	}
+	assert.NotNil(t, job.StartedAt)
+	assert.NotNil(t, job.FinishedAt)
+
+	_, err = useCase.GetJob(newTestContext(), "missing")
+	assert.Equal(t, response.ErrJobNotFound, err)
+}
+
+func TestEnqueueAnswersQueueFull(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 4), "alice")
+
+	// no workers run, the queue of the test use case holds 4 jobs
+	for index := 0; index < cap(useCase.jobQueue.jobs); index++ {
+		_, err := useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1 + float64(index)/10})
+		assert.NoError(t, err)
+	}
+	_, err := useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 2})
+	assert.Equal(t, response.ErrJobQueueFull, err)
+	assert.Len(t, useCase.jobs.jobs, cap(useCase.jobQueue.jobs))
+}
+
+func TestJobIsNotRecordedWithoutParams(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 16, 16, 6)
+	image := createImage(t, useCase, upload, "alice")
+
+	// NaN has no json, the job would be recorded without the parameters it ran with
+	_, err := useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.1, Rotate: math.NaN()})
+	assert.Error(t, err)
+	_, err = useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1.1, Rotate: math.NaN()})
+	assert.Error(t, err)
+	_, err = useCase.ImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.1, Rotate: math.NaN()})
+	assert.Error(t, err)
+
+	assert.Empty(t, useCase.jobs.jobs)
+	assert.Empty(t, useCase.jobQueue.jobs)
+}
+
+func TestStartWorkersResumesInterruptedJobs(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 5), "alice")
+
+	startedAt := time.Now().Add(-time.Minute)
+	useCase.jobs.jobs["interrupted"] = domain.AdjustmentJob{
+		ID:        "interrupted",
+		ImageID:   image.ID,
+		ClientID:  "alice",
+		Operation: domain.OperationTemperature,
+		Params:    `{"adjustment_temperature":1.1}`,
+		Status:    domain.JobStatusRunning,
+		QueuedAt:  startedAt,
+		StartedAt: &startedAt,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 1, 5*time.Second))
+
+	job := waitForJob(t, useCase, "interrupted")
+	assert.Equal(t, domain.JobStatusDone, job.Status)
+	assert.True(t, job.StartedAt.After(startedAt))
+}
+
+// panickingStorage panics on the put of a marker of the client, the last write of an adjustment
+type panickingStorage struct {
+	storage.Storage
+	clientID string
+}
+
+func (s panickingStorage) Put(ctx context.Context, key string, content io.Reader, contentType string) error {
+	if strings.HasPrefix(key, clientMarkerPrefixOf(s.clientID)) {
+		panic("marker storage broke")
+	}
+	return s.Storage.Put(ctx, key, content, contentType)
+}
+
+func TestWorkerFailsAPanickingJob(t *testing.T) {
+	useCase := newTestUseCase(t)
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 7), "alice")
+	workingStorage := useCase.storage
+	useCase.storage = panickingStorage{Storage: workingStorage, clientID: "bob"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 1, 5*time.Second))
+
+	queued, err := useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.2, ClientID: "bob"})
+	assert.NoError(t, err)
+	job := waitForJob(t, useCase, queued.ID)
+	assert.Equal(t, domain.JobStatusFailed, job.Status)
+	assert.Equal(t, "panic: marker storage broke", job.Error)
+
+	// the output written before the panic was rolled back
+	outputs, err := workingStorage.List(context.Background(), outputPrefix)
+	assert.NoError(t, err)
+	assert.Empty(t, outputs)
+
+	// the worker is still running
+	queued, err = useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.3, ClientID: "alice"})
+	assert.NoError(t, err)
+	job = waitForJob(t, useCase, queued.ID)
+	assert.Equal(t, domain.JobStatusDone, job.Status)
+}
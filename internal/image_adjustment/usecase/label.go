@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// labelGlyphHeight is the line height of the label font before scaling
+	labelGlyphHeight = 13
+	// labelPadding surrounds a label band, before scaling
+	labelPadding = 4
+)
+
+// labelScale grows labels with the image they are drawn on so they stay readable
+func labelScale(width int) int {
+	scale := width / 240
+	if scale < 1 {
+		return 1
+	}
+	return scale
+}
+
+// labelSize is the width and height text takes when drawn at scale
+func labelSize(text string, scale int) (int, int) {
+	width := font.MeasureString(basicfont.Face7x13, text).Ceil()
+	return width * scale, labelGlyphHeight * scale
+}
+
+// drawLabel draws text with its top left corner at x,y, every pixel of the
+// 7x13 font becomes a scale x scale square
+func drawLabel(dst draw.Image, text string, x, y, scale int, ink color.Color) {
+	width, _ := labelSize(text, 1)
+	mask := image.NewAlpha(image.Rect(0, 0, width, labelGlyphHeight))
+	drawer := font.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(0, basicfont.Face7x13.Ascent),
+	}
+	drawer.DrawString(text)
+
+	src := image.NewUniform(ink)
+	for my := 0; my < labelGlyphHeight; my++ {
+		for mx := 0; mx < width; mx++ {
+			if mask.AlphaAt(mx, my).A < 128 {
+				continue
+			}
+			square := image.Rect(x+mx*scale, y+my*scale, x+(mx+1)*scale, y+(my+1)*scale)
+			draw.Draw(dst, square, src, image.Point{}, draw.Src)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"image"
+	"math"
+	"sync"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+const (
+	// maxPSNR stands in for the infinite PSNR of identical images
+	maxPSNR = 100
+	// ssimWindow is the side of the square windows SSIM is averaged over, ssimStride the step between two of them
+	ssimWindow = 8
+	ssimStride = 4
+	// heatmapMaxDeltaE is the delta e shown at full red on the heat map
+	heatmapMaxDeltaE = 20
+)
+
+// heatmapRamp runs from black for no difference over blue, cyan and yellow to red
+var heatmapRamp = [][3]float64{{0, 0, 0}, {0, 0, 255}, {0, 255, 255}, {255, 255, 0}, {255, 0, 0}}
+
+// imageMetrics compares after with before, both of the same size. The delta e of every
+// pixel is drawn into a heat map when heatmap is set.
+func imageMetrics(before, after image.Image, heatmap bool) (*domain.ImageMetrics, *image.RGBA) {
+	beforeImg, afterImg := rgbaOf(before), rgbaOf(after)
+	width, height := afterImg.Rect.Dx(), afterImg.Rect.Dy()
+
+	var heatmapImg *image.RGBA
+	if heatmap {
+		heatmapImg = image.NewRGBA(afterImg.Rect)
+	}
+
+	var lock sync.Mutex
+	var squaredError, deltaESum, deltaEMax float64
+	parallelTiles(height, func(startY, endY int) {
+		var tileSquaredError, tileDeltaESum, tileDeltaEMax float64
+		for y := startY; y < endY; y++ {
+			for x := 0; x < width; x++ {
+				beforePixel := beforeImg.Pix[y*beforeImg.Stride+x*4:]
+				afterPixel := afterImg.Pix[y*afterImg.Stride+x*4:]
+				for channel := 0; channel < 3; channel++ {
+					difference := float64(afterPixel[channel]) - float64(beforePixel[channel])
+					tileSquaredError += difference * difference
+				}
+
+				deltaE := ciede2000(
+					rgbToLab(beforePixel[0], beforePixel[1], beforePixel[2]),
+					rgbToLab(afterPixel[0], afterPixel[1], afterPixel[2]),
+				)
+				tileDeltaESum += deltaE
+				if deltaE > tileDeltaEMax {
+					tileDeltaEMax = deltaE
+				}
+				if heatmapImg != nil {
+					r, g, b := heatmapColor(deltaE)
+					copy(heatmapImg.Pix[y*heatmapImg.Stride+x*4:], []uint8{r, g, b, 255})
+				}
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		squaredError += tileSquaredError
+		deltaESum += tileDeltaESum
+		deltaEMax = math.Max(deltaEMax, tileDeltaEMax)
+	})
+
+	pixels := float64(width * height)
+	psnr := float64(maxPSNR)
+	if squaredError > 0 {
+		psnr = math.Min(maxPSNR, 10*math.Log10(255*255/(squaredError/(pixels*3))))
+	}
+
+	return &domain.ImageMetrics{
+		PSNR:       math.Round(psnr*100) / 100,
+		SSIM:       math.Round(ssim(beforeImg, afterImg)*10000) / 10000,
+		DeltaEMean: math.Round(deltaESum/pixels*100) / 100,
+		DeltaEMax:  math.Round(deltaEMax*100) / 100,
+	}, heatmapImg
+}
+
+// ssim is the mean structural similarity of the luma of before and after over
+// overlapping windows, an image smaller than a window is compared as one window
+func ssim(before, after *image.RGBA) float64 {
+	width, height := after.Rect.Dx(), after.Rect.Dy()
+	windowWidth, windowHeight := ssimWindow, ssimWindow
+	if width < windowWidth {
+		windowWidth = width
+	}
+	if height < windowHeight {
+		windowHeight = height
+	}
+	columns := (width-windowWidth)/ssimStride + 1
+	rows := (height-windowHeight)/ssimStride + 1
+
+	luma := func(img *image.RGBA) []float64 {
+		result := make([]float64, width*height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				pixel := img.Pix[y*img.Stride+x*4:]
+				result[y*width+x] = 0.299*float64(pixel[0]) + 0.587*float64(pixel[1]) + 0.114*float64(pixel[2])
+			}
+		}
+		return result
+	}
+	beforeLuma, afterLuma := luma(before), luma(after)
+
+	const c1 = (0.01 * 255) * (0.01 * 255)
+	const c2 = (0.03 * 255) * (0.03 * 255)
+	count := float64(windowWidth * windowHeight)
+
+	var lock sync.Mutex
+	var sum float64
+	parallelTiles(rows, func(startRow, endRow int) {
+		var tileSum float64
+		for row := startRow; row < endRow; row++ {
+			for column := 0; column < columns; column++ {
+				var sumX, sumY, sumXX, sumYY, sumXY float64
+				for y := row * ssimStride; y < row*ssimStride+windowHeight; y++ {
+					for x := column * ssimStride; x < column*ssimStride+windowWidth; x++ {
+						valueX, valueY := beforeLuma[y*width+x], afterLuma[y*width+x]
+						sumX += valueX
+						sumY += valueY
+						sumXX += valueX * valueX
+						sumYY += valueY * valueY
+						sumXY += valueX * valueY
+					}
+				}
+				meanX, meanY := sumX/count, sumY/count
+				varianceX := sumXX/count - meanX*meanX
+				varianceY := sumYY/count - meanY*meanY
+				covariance := sumXY/count - meanX*meanY
+				tileSum += (2*meanX*meanY + c1) * (2*covariance + c2) /
+					((meanX*meanX + meanY*meanY + c1) * (varianceX + varianceY + c2))
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		sum += tileSum
+	})
+
+	return sum / float64(rows*columns)
+}
+
+// heatmapColor maps a delta e onto heatmapRamp
+func heatmapColor(deltaE float64) (uint8, uint8, uint8) {
+	position := math.Min(deltaE/heatmapMaxDeltaE, 1) * float64(len(heatmapRamp)-1)
+	index := int(position)
+	if index >= len(heatmapRamp)-1 {
+		last := heatmapRamp[len(heatmapRamp)-1]
+		return uint8(last[0]), uint8(last[1]), uint8(last[2])
+	}
+	fraction := position - float64(index)
+	from, to := heatmapRamp[index], heatmapRamp[index+1]
+	mix := func(channel int) uint8 {
+		return uint8(math.Round(from[channel] + (to[channel]-from[channel])*fraction))
+	}
+	return mix(0), mix(1), mix(2)
+}
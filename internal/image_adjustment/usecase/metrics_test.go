@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCiede2000(t *testing.T) {
+	// pairs of the CIEDE2000 test data of Sharma, Wu and Dalal
+	tests := []struct {
+		first, second lab
+		deltaE        float64
+	}{
+		{lab{50, 2.6772, -79.7751}, lab{50, 0, -82.7485}, 2.0425},
+		{lab{50, 0, 0}, lab{50, -1, 2}, 2.3669},
+		{lab{50, 2.49, -0.001}, lab{50, -2.49, 0.0009}, 7.1792},
+		{lab{50, 2.49, -0.001}, lab{50, -2.49, 0.0011}, 7.2195},
+		{lab{50, 2.5, 0}, lab{73, 25, -18}, 27.1492},
+		{lab{50, 2.5, 0}, lab{50, 3.1736, 0.5854}, 1.0000},
+		{lab{60.2574, -34.0099, 36.2677}, lab{60.4626, -34.1751, 39.4387}, 1.2644},
+	}
+	for _, test := range tests {
+		assert.InDelta(t, test.deltaE, ciede2000(test.first, test.second), 1e-4, test.first)
+		assert.InDelta(t, test.deltaE, ciede2000(test.second, test.first), 1e-4, test.second)
+	}
+	assert.Equal(t, 0.0, ciede2000(lab{50, 10, -10}, lab{50, 10, -10}))
+}
+
+func TestRgbToLab(t *testing.T) {
+	red := rgbToLab(255, 0, 0)
+	assert.InDeltaSlice(t, []float64{53.24, 80.09, 67.20}, red[:], 0.01)
+	white := rgbToLab(255, 255, 255)
+	assert.InDeltaSlice(t, []float64{100, 0, 0}, white[:], 0.01)
+
+	r, g, b := labToRGB(rgbToLab(200, 120, 40))
+	assert.Equal(t, []uint8{200, 120, 40}, []uint8{r, g, b})
+}
+
+func TestImageMetrics(t *testing.T) {
+	grey := solidImage(32, 32, color.RGBA{R: 100, G: 100, B: 100, A: 255})
+
+	metrics, heatmap := imageMetrics(grey, grey, false)
+	assert.Nil(t, heatmap)
+	assert.Equal(t, domain.ImageMetrics{PSNR: maxPSNR, SSIM: 1}, *metrics)
+
+	// every channel 10 off is a mean squared error of 100
+	lighter := solidImage(32, 32, color.RGBA{R: 110, G: 110, B: 110, A: 255})
+	metrics, heatmap = imageMetrics(grey, lighter, true)
+	assert.Equal(t, 28.13, metrics.PSNR)
+	// flat windows only differ in their means, (2*100*110+c1)/(100²+110²+c1)
+	assert.Equal(t, 0.9955, metrics.SSIM)
+	deltaE := ciede2000(rgbToLab(100, 100, 100), rgbToLab(110, 110, 110))
+	assert.InDelta(t, deltaE, metrics.DeltaEMean, 0.005)
+	assert.Equal(t, metrics.DeltaEMean, metrics.DeltaEMax)
+
+	if assert.NotNil(t, heatmap) {
+		r, g, b := heatmapColor(deltaE)
+		assert.Equal(t, color.RGBA{R: r, G: g, B: b, A: 255}, heatmap.RGBAAt(31, 31))
+	}
+}
+
+func TestHeatmapColor(t *testing.T) {
+	tests := []struct {
+		deltaE  float64
+		r, g, b uint8
+	}{
+		{deltaE: 0},
+		{deltaE: heatmapMaxDeltaE / 4, b: 255},
+		{deltaE: 2.5, b: 128},
+		{deltaE: heatmapMaxDeltaE / 2, g: 255, b: 255},
+		{deltaE: heatmapMaxDeltaE * 3 / 4, r: 255, g: 255},
+		{deltaE: heatmapMaxDeltaE, r: 255},
+		{deltaE: heatmapMaxDeltaE * 5, r: 255},
+	}
+	for _, test := range tests {
+		r, g, b := heatmapColor(test.deltaE)
+		assert.Equal(t, []uint8{test.r, test.g, test.b}, []uint8{r, g, b}, test.deltaE)
+	}
+}
+
+func TestJobResultLeavesOutTheHeatmapUrl(t *testing.T) {
+	useCase := newTestUseCase(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 1, 5*time.Second))
+
+	queued, err := useCase.EnqueueImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 16, 16, 47)),
+		AdjustmentTemperature: 1.2,
+		Metrics:               "true",
+		MetricsHeatmap:        "true",
+		ClientID:              "alice",
+	})
+	assert.NoError(t, err)
+
+	// the stored result keeps the key only, the url is signed again on every read
+	job := waitForJob(t, useCase, queued.ID)
+	if assert.NotNil(t, job.Result) && assert.NotNil(t, job.Result.Metrics) {
+		assert.True(t, stored(useCase, job.Result.Metrics.HeatmapPathDirImage))
+		assert.Contains(t, job.Result.Metrics.HeatmapFileImage, "signature=")
+	}
+	useCase.jobs.mutex.Lock()
+	result := useCase.jobs.jobs[queued.ID].Result
+	useCase.jobs.mutex.Unlock()
+	assert.Contains(t, result, "heatmap_path_dir_image")
+	assert.NotContains(t, result, "heatmap_file_image")
+	assert.NotContains(t, result, "signature=")
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"sort"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+const (
+	// paletteSamples bounds the pixels clustered for a palette
+	paletteSamples = 1 << 16
+	// paletteIterations bounds the k-means passes, most palettes settle far earlier
+	paletteIterations = 30
+	// paletteSeed makes the palette of an image the same on every request
+	paletteSeed = 1
+)
+
+// samplePixels returns the Lab colours of at most paletteSamples pixels spread evenly over img
+func samplePixels(img image.Image) []lab {
+	rgba := rgbaOf(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	step := int(math.Sqrt(float64(width*height) / paletteSamples))
+	if step < 1 {
+		step = 1
+	}
+
+	samples := make([]lab, 0, (width/step+1)*(height/step+1))
+	for y := 0; y < height; y += step {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < width; x += step {
+			samples = append(samples, rgbToLab(row[x*4], row[x*4+1], row[x*4+2]))
+		}
+	}
+	return samples
+}
+
+func labDistance(first, second lab) float64 {
+	dl, da, db := first[0]-second[0], first[1]-second[1], first[2]-second[2]
+	return dl*dl + da*da + db*db
+}
+
+// nearestCentre returns the index of the centre closest to colour
+func nearestCentre(colour lab, centres []lab) int {
+	nearest, nearestDistance := 0, math.MaxFloat64
+	for index, centre := range centres {
+		if distance := labDistance(colour, centre); distance < nearestDistance {
+			nearest, nearestDistance = index, distance
+		}
+	}
+	return nearest
+}
+
+// seedCentres picks up to count centres by k-means++, an image with fewer distinct colours gets fewer
+func seedCentres(samples []lab, count int, random *rand.Rand) []lab {
+	centres := []lab{samples[random.Intn(len(samples))]}
+	distances := make([]float64, len(samples))
+	for len(centres) < count {
+		var total float64
+		for index, sample := range samples {
+			distances[index] = labDistance(sample, centres[nearestCentre(sample, centres)])
+			total += distances[index]
+		}
+		if total == 0 {
+			break
+		}
+
+		// the next centre is a sample picked with a chance in proportion to its squared distance
+		target := random.Float64() * total
+		next := len(samples) - 1
+		for index, distance := range distances {
+			target -= distance
+			if target <= 0 {
+				next = index
+				break
+			}
+		}
+		centres = append(centres, samples[next])
+	}
+	return centres
+}
+
+// dominantColours clusters the pixels of img into count colours by k-means in Lab
+func dominantColours(img image.Image, count int) []domain.PaletteColor {
+	samples := samplePixels(img)
+	centres := seedCentres(samples, count, rand.New(rand.NewSource(paletteSeed)))
+
+	assignments := make([]int, len(samples))
+	sizes := make([]int, len(centres))
+	for iteration := 0; iteration < paletteIterations; iteration++ {
+		changed := false
+		sums := make([]lab, len(centres))
+		for index := range sizes {
+			sizes[index] = 0
+		}
+		for index, sample := range samples {
+			nearest := nearestCentre(sample, centres)
+			if iteration == 0 || assignments[index] != nearest {
+				changed = true
+			}
+			assignments[index] = nearest
+			sizes[nearest]++
+			for channel := range sample {
+				sums[nearest][channel] += sample[channel]
+			}
+		}
+		if !changed {
+			break
+		}
+		for index := range centres {
+			if sizes[index] == 0 {
+				continue
+			}
+			for channel := range centres[index] {
+				centres[index][channel] = sums[index][channel] / float64(sizes[index])
+			}
+		}
+	}
+
+	palette := make([]domain.PaletteColor, 0, len(centres))
+	for index, centre := range centres {
+		if sizes[index] == 0 {
+			continue
+		}
+		r, g, b := labToRGB(centre)
+		palette = append(palette, domain.PaletteColor{
+			Hex: fmt.Sprintf("#%02x%02x%02x", r, g, b),
+			RGB: [3]uint8{r, g, b},
+			Lab: [3]float64{
+				math.Round(centre[0]*100) / 100,
+				math.Round(centre[1]*100) / 100,
+				math.Round(centre[2]*100) / 100,
+			},
+			Proportion: math.Round(float64(sizes[index])/float64(len(samples))*10000) / 10000,
+		})
+	}
+	sort.SliceStable(palette, func(a, b int) bool {
+		return palette[a].Proportion > palette[b].Proportion
+	})
+	return palette
+}
+
+func (i imageAdjustmentUseCase) ImagePalette(beegoCtx *beegoContext.Context, request domain.PaletteRequest) (res domain.PaletteResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	if request.Colors == 0 {
+		request.Colors = domain.DefaultPaletteColors
+	}
+
+	var img image.Image
+	if request.File != nil {
+		img, _, _, err = decodeUpload(request.File, false)
+	} else {
+		img, err = i.loadImage(ctx, request.ImageID)
+	}
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	res.Palette = dominantColours(img, request.Colors)
+	if request.Adjustment == nil {
+		return res, nil
+	}
+
+	// The adjusted image is clustered before it is encoded, so compression artifacts do not add colours
+	_, adjustedImg, err := i.renderAdjustment(ctx, beegoCtx, img, *request.Adjustment)
+	if err != nil {
+		return domain.PaletteResponse{}, err
+	}
+	res.AdjustedPalette = dominantColours(adjustedImg, request.Colors)
+
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDominantColours(t *testing.T) {
+	img := solidImage(40, 10, color.RGBA{R: 255, A: 255})
+	draw.Draw(img, image.Rect(30, 0, 40, 10), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	palette := dominantColours(img, 5)
+	// two distinct colours give two clusters, the larger first
+	if assert.Len(t, palette, 2) {
+		assert.Equal(t, "#ff0000", palette[0].Hex)
+		assert.Equal(t, [3]uint8{255, 0, 0}, palette[0].RGB)
+		assert.Equal(t, 0.75, palette[0].Proportion)
+		assert.InDeltaSlice(t, []float64{53.24, 80.09, 67.2}, palette[0].Lab[:], 0.01)
+		assert.Equal(t, "#0000ff", palette[1].Hex)
+		assert.Equal(t, 0.25, palette[1].Proportion)
+	}
+
+	// the palette of an image is the same every time
+	gradient, _, _, err := decodeUpload(uploadOf(gradientJpeg(t, 64, 64, 49)), false)
+	assert.NoError(t, err)
+	assert.Equal(t, dominantColours(gradient, 4), dominantColours(gradient, 4))
+}
+
+func TestImagePaletteAdjusted(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 48, 32, 49)
+	adjustment := domain.ImageAdjustmentRequest{AdjustmentTemperature: 0.8, SharpenAmount: 1, SharpenRadius: 1}
+
+	res, err := useCase.ImagePalette(newTestContext(), domain.PaletteRequest{File: uploadOf(upload), Colors: 3, Adjustment: &adjustment})
+	assert.NoError(t, err)
+	assert.Len(t, res.Palette, 3)
+
+	// the adjusted palette is clustered from the adjusted pixels, not from an encoded jpeg of them
+	img, _, _, err := decodeUpload(uploadOf(upload), false)
+	assert.NoError(t, err)
+	_, adjustedImg, err := useCase.renderAdjustment(newTestContext().Request.Context(), newTestContext(), img, adjustment)
+	assert.NoError(t, err)
+	assert.Equal(t, dominantColours(adjustedImg, 3), res.AdjustedPalette)
+
+	// nothing is stored for the adjusted image
+	keys, err := useCase.storage.List(newTestContext().Request.Context(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
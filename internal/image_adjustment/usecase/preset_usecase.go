@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"gorm.io/gorm"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+)
+
+type presetUseCase struct {
+	zapLogger        zaplogger.Logger
+	contextTimeout   time.Duration
+	presetRepository domain.PresetRepository
+}
+
+func NewPresetUseCase(timeout time.Duration,
+	zapLogger zaplogger.Logger,
+	presetRepository domain.PresetRepository) domain.PresetUseCase {
+	return &presetUseCase{
+		contextTimeout:   timeout,
+		zapLogger:        zapLogger,
+		presetRepository: presetRepository,
+	}
+}
+
+func presetResponseOf(preset domain.Preset) (res domain.PresetResponse, err error) {
+	res = domain.PresetResponse{
+		Name:        preset.Name,
+		Description: preset.Description,
+		CreatedAt:   preset.CreatedAt,
+		UpdatedAt:   preset.UpdatedAt,
+	}
+	err = json.Unmarshal([]byte(preset.Params), &res.Params)
+	return res, err
+}
+
+func (p presetUseCase) CreatePreset(beegoCtx *beegoContext.Context, request domain.PresetRequest) (res domain.PresetResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), p.contextTimeout)
+	defer cancel()
+
+	_, err = p.presetRepository.GetByName(ctx, request.Name)
+	if err == nil {
+		return res, response.ErrPresetAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	params, err := json.Marshal(request.Params)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	preset := domain.Preset{
+		Name:        request.Name,
+		Description: request.Description,
+		Params:      string(params),
+	}
+	if err := p.presetRepository.Create(ctx, &preset); err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	return presetResponseOf(preset)
+}
+
+func (p presetUseCase) FetchPresets(beegoCtx *beegoContext.Context) (res []domain.PresetResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), p.contextTimeout)
+	defer cancel()
+
+	presets, err := p.presetRepository.Fetch(ctx)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return nil, err
+	}
+
+	res = make([]domain.PresetResponse, len(presets))
+	for index, preset := range presets {
+		res[index], err = presetResponseOf(preset)
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (p presetUseCase) GetPreset(beegoCtx *beegoContext.Context, name string) (res domain.PresetResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), p.contextTimeout)
+	defer cancel()
+
+	preset, err := p.presetRepository.GetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, response.ErrPresetNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	res, err = presetResponseOf(preset)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	return res, nil
+}
+
+func (p presetUseCase) UpdatePreset(beegoCtx *beegoContext.Context, request domain.PresetRequest) (res domain.PresetResponse, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), p.contextTimeout)
+	defer cancel()
+
+	preset, err := p.presetRepository.GetByName(ctx, request.Name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, response.ErrPresetNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	params, err := json.Marshal(request.Params)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	preset.Description = request.Description
+	preset.Params = string(params)
+	if err := p.presetRepository.Update(ctx, &preset); err != nil {
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+
+	return presetResponseOf(preset)
+}
+
+func (p presetUseCase) DeletePreset(beegoCtx *beegoContext.Context, name string) error {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), p.contextTimeout)
+	defer cancel()
+
+	err := p.presetRepository.Delete(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ErrPresetNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", p.zapLogger.SetMessageLog(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fakePresetRepository struct {
+	presets map[string]domain.Preset
+}
+
+func (r *fakePresetRepository) Create(ctx context.Context, preset *domain.Preset) error {
+	r.presets[preset.Name] = *preset
+	return nil
+}
+
+func (r *fakePresetRepository) Update(ctx context.Context, preset *domain.Preset) error {
+	r.presets[preset.Name] = *preset
+	return nil
+}
+
+func (r *fakePresetRepository) GetByName(ctx context.Context, name string) (domain.Preset, error) {
+	preset, ok := r.presets[name]
+	if !ok {
+		return preset, gorm.ErrRecordNotFound
+	}
+	return preset, nil
+}
+
+func (r *fakePresetRepository) Fetch(ctx context.Context) ([]domain.Preset, error) {
+	var presets []domain.Preset
+	for _, preset := range r.presets {
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+func (r *fakePresetRepository) Delete(ctx context.Context, name string) error {
+	if _, ok := r.presets[name]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.presets, name)
+	return nil
+}
+
+func TestPresetKeepsTheTint(t *testing.T) {
+	repository := &fakePresetRepository{presets: map[string]domain.Preset{}}
+	useCase := NewPresetUseCase(time.Second, zaplogger.NewZapLogger(filepath.Join(t.TempDir(), "test.log"), ""), repository)
+
+	created, err := useCase.CreatePreset(newTestContext(), domain.PresetRequest{
+		Name:   "golden-hour",
+		Params: domain.PresetParams{AdjustmentTemperature: 1.2, Tint: 15},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 15.0, created.Params.Tint)
+	assert.Contains(t, repository.presets["golden-hour"].Params, `"tint":15`)
+
+	_, err = useCase.CreatePreset(newTestContext(), domain.PresetRequest{Name: "golden-hour", Params: domain.PresetParams{AdjustmentTemperature: 1}})
+	assert.Equal(t, response.ErrPresetAlreadyExists, err)
+
+	// an update replaces every parameter, the tint too
+	updated, err := useCase.UpdatePreset(newTestContext(), domain.PresetRequest{
+		Name:   "golden-hour",
+		Params: domain.PresetParams{AdjustmentTemperature: 1.1, Tint: -20},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, -20.0, updated.Params.Tint)
+	preset, err := useCase.GetPreset(newTestContext(), "golden-hour")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PresetParams{AdjustmentTemperature: 1.1, Tint: -20}, preset.Params)
+}
+
+func TestPresetParamsApplyTo(t *testing.T) {
+	params := domain.PresetParams{AdjustmentTemperature: 1.2, WhiteBalanceGains: []float64{1, 1, 1.1}, Tint: 30, Denoise: "median"}
+
+	request := domain.ImageAdjustmentRequest{}
+	params.ApplyTo(&request, func(string) bool { return false })
+	assert.Equal(t, 30.0, request.Tint)
+	assert.Equal(t, []float64{1, 1, 1.1}, request.WhiteBalanceGains)
+
+	// a tint sent with the request overrides the preset, even a tint of 0
+	request = domain.ImageAdjustmentRequest{}
+	params.ApplyTo(&request, func(field string) bool { return field == "tint" })
+	assert.Equal(t, 0.0, request.Tint)
+	assert.Equal(t, 1.2, request.AdjustmentTemperature)
+}
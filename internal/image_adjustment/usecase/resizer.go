@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/nfnt/resize"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+)
+
+func resizeInterpolation(interpolation string) resize.InterpolationFunction {
+	switch interpolation {
+	case domain.InterpolationBilinear:
+		return resize.Bilinear
+	default:
+		return resize.Lanczos3
+	}
+}
+
+// resizeImage scales img into a width x height box using the given fit mode,
+// a zero width or height keeps the aspect ratio of the source.
+func resizeImage(img image.Image, width, height int, fit, interpolation string) image.Image {
+	if width <= 0 && height <= 0 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	srcWidth, srcHeight := float64(bounds.Dx()), float64(bounds.Dy())
+	interp := resizeInterpolation(interpolation)
+
+	// only one side given, the other one follows the aspect ratio
+	if width <= 0 || height <= 0 {
+		return resize.Resize(uint(maxInt(width, 0)), uint(maxInt(height, 0)), img, interp)
+	}
+
+	scaleX := float64(width) / srcWidth
+	scaleY := float64(height) / srcHeight
+
+	switch fit {
+	case domain.FitFill:
+		return resize.Resize(uint(width), uint(height), img, interp)
+	case domain.FitCover:
+		scale := math.Max(scaleX, scaleY)
+		scaled := resize.Resize(scaledSide(srcWidth, scale), scaledSide(srcHeight, scale), img, interp)
+		return cropCenter(scaled, width, height)
+	case domain.FitContain:
+		scale := math.Min(scaleX, scaleY)
+		scaled := resize.Resize(scaledSide(srcWidth, scale), scaledSide(srcHeight, scale), img, interp)
+		return padCenter(scaled, width, height, color.Black)
+	default:
+		scale := math.Min(scaleX, scaleY)
+		return resize.Resize(scaledSide(srcWidth, scale), scaledSide(srcHeight, scale), img, interp)
+	}
+}
+
+// renditionWidth clamps a requested rendition width to the width of img,
+// renditions never upscale past the source.
+func renditionWidth(img image.Image, size int) int {
+	if width := img.Bounds().Dx(); size > width {
+		return width
+	}
+	return size
+}
+
+func scaledSide(side, scale float64) uint {
+	result := uint(math.Round(side * scale))
+	if result < 1 {
+		return 1
+	}
+	return result
+}
+
+func cropCenter(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	x := bounds.Min.X + (bounds.Dx()-width)/2
+	y := bounds.Min.Y + (bounds.Dy()-height)/2
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x, y), draw.Src)
+	return dst
+}
+
+func padCenter(img image.Image, width, height int, background color.Color) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	offset := image.Pt((width-bounds.Dx())/2, (height-bounds.Dy())/2)
+	draw.Draw(dst, bounds.Sub(bounds.Min).Add(offset), img, bounds.Min, draw.Src)
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"image"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenditionWidth(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+
+	assert.Equal(t, 320, renditionWidth(img, 320))
+	assert.Equal(t, 400, renditionWidth(img, 400))
+	assert.Equal(t, 400, renditionWidth(img, 1280))
+
+	rendition := resizeImage(img, renditionWidth(img, 1280), 0, "", "")
+	assert.Equal(t, image.Pt(400, 300), rendition.Bounds().Size())
+}
+
+func TestResizeImageFitModes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		fit    string
+		width  int
+		height int
+		want   image.Point
+	}{
+		{fit: "", width: 100, height: 100, want: image.Pt(100, 50)},
+		{fit: domain.FitContain, width: 100, height: 100, want: image.Pt(100, 100)},
+		{fit: domain.FitCover, width: 100, height: 100, want: image.Pt(100, 100)},
+		{fit: domain.FitFill, width: 100, height: 100, want: image.Pt(100, 100)},
+		{fit: "", width: 200, height: 0, want: image.Pt(200, 100)},
+		{fit: "", width: 0, height: 0, want: image.Pt(400, 200)},
+	}
+	for _, test := range tests {
+		got := resizeImage(img, test.width, test.height, test.fit, "")
+		assert.Equal(t, test.want, got.Bounds().Size(), "fit %q %dx%d", test.fit, test.width, test.height)
+	}
+}
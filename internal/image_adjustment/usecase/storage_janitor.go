@@ -0,0 +1,298 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+)
+
+const (
+	// staleTempFileAge is how long an unfinished write may exist before it is treated as abandoned
+	staleTempFileAge = time.Hour
+	// orphanInputGraceAge spares fresh inputs without output, the request storing them may still be running
+	orphanInputGraceAge = time.Hour
+	// imageLookupBatch bounds the ids of one images table lookup
+	imageLookupBatch = 500
+)
+
+// objectGroup is deleted as a whole, e.g. an output with its manifest and renditions
+type objectGroup struct {
+	name    string
+	keys    []string
+	size    int64
+	modTime time.Time
+}
+
+type storageJanitor struct {
+	zapLogger       zaplogger.Logger
+	storage         storage.Storage
+	imageRepository domain.ImageRepository
+	policy          domain.RetentionPolicy
+
+	mutex sync.Mutex
+	stats domain.StorageJanitorStats
+}
+
+func NewStorageJanitor(storage storage.Storage, imageRepository domain.ImageRepository, policy domain.RetentionPolicy,
+	zapLogger zaplogger.Logger) domain.StorageJanitor {
+	return &storageJanitor{
+		zapLogger:       zapLogger,
+		storage:         storage,
+		imageRepository: imageRepository,
+		policy:          policy,
+	}
+}
+
+// Start runs the janitor right away and then every interval until ctx is done
+func (s *storageJanitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.RunOnce(ctx); err != nil {
+				s.zapLogger.Errorf("storage janitor: %s", err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *storageJanitor) Stats() domain.StorageJanitorStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stats
+}
+
+func (s *storageJanitor) RunOnce(ctx context.Context) error {
+	startedAt := time.Now()
+	deletedObjects, deletedBytes, totalObjects, totalBytes, err := s.enforce(ctx, startedAt)
+
+	s.mutex.Lock()
+	s.stats.Runs++
+	s.stats.LastRunAt = startedAt
+	s.stats.LastDuration = time.Since(startedAt).String()
+	s.stats.LastError = ""
+	if err != nil {
+		s.stats.LastError = err.Error()
+	}
+	s.stats.TotalObjects = totalObjects
+	s.stats.TotalBytes = totalBytes
+	s.stats.DeletedObjects += deletedObjects
+	s.stats.DeletedBytes += deletedBytes
+	s.mutex.Unlock()
+
+	if err == nil {
+		s.zapLogger.Infof("storage janitor: deleted %d objects (%d bytes), %d objects (%d bytes) kept in %s",
+			deletedObjects, deletedBytes, totalObjects, totalBytes, time.Since(startedAt).String())
+	}
+	return err
+}
+
+func (s *storageJanitor) enforce(ctx context.Context, now time.Time) (deletedObjects, deletedBytes, totalObjects, totalBytes int64, err error) {
+	objects, err := s.storage.List(ctx, "")
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	groups := map[string]*objectGroup{}
+	markers := map[string][]storage.ObjectInfo{}
+	var staleTempKeys []string
+	for _, object := range objects {
+		var name string
+		switch {
+		case strings.HasPrefix(path.Base(object.Key), storage.TempFilePrefix):
+			// leftovers of writes interrupted by a crash
+			if now.Sub(object.ModTime) > staleTempFileAge {
+				staleTempKeys = append(staleTempKeys, object.Key)
+			}
+			continue
+		case strings.HasPrefix(object.Key, clientPrefix):
+			clientDir := object.Key[:strings.LastIndex(object.Key, "/")+1]
+			markers[clientDir] = append(markers[clientDir], object)
+			continue
+		case strings.HasPrefix(object.Key, outputPrefix):
+			name = outputGroupOf(object.Key)
+		default:
+			name = object.Key
+		}
+
+		group, ok := groups[name]
+		if !ok {
+			group = &objectGroup{name: name}
+			groups[name] = group
+		}
+		group.keys = append(group.keys, object.Key)
+		group.size += object.Size
+		if object.ModTime.After(group.modTime) {
+			group.modTime = object.ModTime
+		}
+	}
+
+	// inputs registered through the image api stay until the image is deleted
+	registered, err := s.registeredInputs(ctx, groups)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	for name, group := range registered {
+		totalObjects += int64(len(group.keys))
+		totalBytes += group.size
+		delete(groups, name)
+	}
+
+	deleteKey := func(key string) error {
+		err := s.storage.Delete(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			return err
+		}
+		return nil
+	}
+	deleteGroup := func(group *objectGroup) error {
+		for _, key := range group.keys {
+			if err := deleteKey(key); err != nil {
+				return err
+			}
+		}
+		deletedObjects += int64(len(group.keys))
+		deletedBytes += group.size
+		delete(groups, group.name)
+		return nil
+	}
+
+	for _, key := range staleTempKeys {
+		if err := deleteKey(key); err != nil {
+			return deletedObjects, deletedBytes, 0, 0, err
+		}
+		deletedObjects++
+	}
+
+	// keep only the last N outputs of every client, outputs still requested by another client stay
+	referenced := map[string]bool{}
+	candidates := map[string]bool{}
+	for _, clientMarkers := range markers {
+		sort.Slice(clientMarkers, func(a, b int) bool { return clientMarkers[a].Key > clientMarkers[b].Key })
+		for index, marker := range clientMarkers {
+			expired := s.policy.MaxAge > 0 && now.Sub(marker.ModTime) > s.policy.MaxAge
+			if expired || (s.policy.KeepLastPerClient > 0 && index >= s.policy.KeepLastPerClient) {
+				if err := deleteKey(marker.Key); err != nil {
+					return deletedObjects, deletedBytes, 0, 0, err
+				}
+				deletedObjects++
+				candidates[outputNameOfMarker(marker.Key)] = true
+				continue
+			}
+			referenced[outputNameOfMarker(marker.Key)] = true
+			totalObjects++
+		}
+	}
+	if s.policy.KeepLastPerClient > 0 {
+		for name := range candidates {
+			if group, ok := groups[name]; ok && !referenced[name] {
+				if err := deleteGroup(group); err != nil {
+					return deletedObjects, deletedBytes, 0, 0, err
+				}
+			}
+		}
+	}
+
+	// drop everything older than max age
+	if s.policy.MaxAge > 0 {
+		for _, group := range groups {
+			if now.Sub(group.modTime) > s.policy.MaxAge {
+				if err := deleteGroup(group); err != nil {
+					return deletedObjects, deletedBytes, 0, 0, err
+				}
+			}
+		}
+	}
+
+	// drop the oldest groups until the storage fits max total bytes
+	var remaining []*objectGroup
+	var remainingBytes int64
+	for _, group := range groups {
+		remaining = append(remaining, group)
+		remainingBytes += group.size
+	}
+	if s.policy.MaxTotalBytes > 0 && remainingBytes > s.policy.MaxTotalBytes {
+		sort.Slice(remaining, func(a, b int) bool { return remaining[a].modTime.Before(remaining[b].modTime) })
+		for _, group := range remaining {
+			if remainingBytes <= s.policy.MaxTotalBytes {
+				break
+			}
+			remainingBytes -= group.size
+			if err := deleteGroup(group); err != nil {
+				return deletedObjects, deletedBytes, 0, 0, err
+			}
+		}
+	}
+
+	// inputs without any output left are not reachable anymore
+	if s.policy.KeepLastPerClient > 0 {
+		inputsInUse := map[string]bool{}
+		for name := range groups {
+			if outputName := strings.TrimPrefix(name, outputPrefix); outputName != name && len(outputName) >= sha256HexLength {
+				inputsInUse[outputName[:sha256HexLength]] = true
+			}
+		}
+		for name := range referenced {
+			// markers of images uploaded through the image api name the input itself
+			if inputHash := strings.TrimPrefix(name, outputPrefix); len(inputHash) == sha256HexLength {
+				inputsInUse[inputHash] = true
+			}
+		}
+		for name, group := range groups {
+			if now.Sub(group.modTime) <= orphanInputGraceAge {
+				continue
+			}
+			if strings.HasPrefix(name, inputPrefix) && !inputsInUse[strings.TrimSuffix(strings.TrimPrefix(name, inputPrefix), ".jpg")] {
+				if err := deleteGroup(group); err != nil {
+					return deletedObjects, deletedBytes, 0, 0, err
+				}
+			}
+		}
+	}
+
+	for _, group := range groups {
+		totalObjects += int64(len(group.keys))
+		totalBytes += group.size
+	}
+	return deletedObjects, deletedBytes, totalObjects, totalBytes, nil
+}
+
+// registeredInputs returns the input groups whose image still has a record in the images table
+func (s *storageJanitor) registeredInputs(ctx context.Context, groups map[string]*objectGroup) (map[string]*objectGroup, error) {
+	var ids []string
+	for name := range groups {
+		if id := imageIDOfKey(name); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	result := map[string]*objectGroup{}
+	for start := 0; start < len(ids); start += imageLookupBatch {
+		end := start + imageLookupBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		images, err := s.imageRepository.FetchByIDs(ctx, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			name := inputKeyOf(image.ID)
+			result[name] = groups[name]
+		}
+	}
+	return result, nil
+}
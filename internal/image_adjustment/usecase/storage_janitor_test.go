@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
+	"github.com/stretchr/testify/assert"
+)
+
+func hashOf(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestJanitor(t *testing.T, policy domain.RetentionPolicy) (*storageJanitor, storage.Storage, *fakeImageRepository) {
+	objectStorage := storage.NewMemoryStorage("/files/")
+	images := &fakeImageRepository{images: map[string]domain.Image{}}
+	janitor := NewStorageJanitor(objectStorage, images, policy,
+		zaplogger.NewZapLogger(filepath.Join(t.TempDir(), "test.log"), "")).(*storageJanitor)
+	return janitor, objectStorage, images
+}
+
+func putObjects(t *testing.T, objectStorage storage.Storage, keys ...string) {
+	for _, key := range keys {
+		assert.NoError(t, objectStorage.Put(context.Background(), key, strings.NewReader("data"), ""))
+	}
+}
+
+func keysOf(t *testing.T, objectStorage storage.Storage) []string {
+	objects, err := objectStorage.List(context.Background(), "")
+	assert.NoError(t, err)
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestStorageJanitorMaxAgeKeepsRegisteredInputs(t *testing.T) {
+	janitor, objectStorage, images := newTestJanitor(t, domain.RetentionPolicy{MaxAge: time.Hour})
+	registered, unregistered := hashOf("registered"), hashOf("unregistered")
+	output := outputNameOf(unregistered, hashOf("params"))
+	putObjects(t, objectStorage, inputKeyOf(registered), inputKeyOf(unregistered), output+".jpg", output+".json")
+	images.images[registered] = domain.Image{ID: registered}
+
+	_, _, totalObjects, _, err := janitor.enforce(context.Background(), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{inputKeyOf(registered)}, keysOf(t, objectStorage))
+	assert.Equal(t, int64(1), totalObjects)
+
+	// once the image record is gone the input ages out too
+	delete(images.images, registered)
+	_, _, _, _, err = janitor.enforce(context.Background(), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, keysOf(t, objectStorage))
+}
+
+func TestStorageJanitorOrphanInputGracePeriod(t *testing.T) {
+	janitor, objectStorage, _ := newTestJanitor(t, domain.RetentionPolicy{KeepLastPerClient: 1})
+	orphan := hashOf("orphan")
+	putObjects(t, objectStorage, inputKeyOf(orphan))
+
+	// a request may still be writing the output of a fresh input
+	_, _, _, _, err := janitor.enforce(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{inputKeyOf(orphan)}, keysOf(t, objectStorage))
+
+	_, _, _, _, err = janitor.enforce(context.Background(), time.Now().Add(orphanInputGraceAge+time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, keysOf(t, objectStorage))
+}
+
+func TestStorageJanitorKeepLastPerClient(t *testing.T) {
+	janitor, objectStorage, _ := newTestJanitor(t, domain.RetentionPolicy{KeepLastPerClient: 1})
+	input := hashOf("input")
+	older, newer := outputNameOf(input, hashOf("older")), outputNameOf(input, hashOf("newer"))
+	now := time.Now()
+	putObjects(t, objectStorage,
+		inputKeyOf(input),
+		older+".jpg", older+".json",
+		newer+".jpg", newer+".json",
+		clientMarkerKeyOf("client", older, now.Add(-time.Minute)),
+		clientMarkerKeyOf("client", newer, now),
+	)
+
+	deletedObjects, _, _, _, err := janitor.enforce(context.Background(), now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deletedObjects)
+	assert.Equal(t, []string{
+		clientMarkerKeyOf("client", newer, now),
+		inputKeyOf(input),
+		newer + ".jpg",
+		newer + ".json",
+	}, keysOf(t, objectStorage))
+}
+
+func TestStorageJanitorStaleTempFiles(t *testing.T) {
+	janitor, objectStorage, _ := newTestJanitor(t, domain.RetentionPolicy{})
+	putObjects(t, objectStorage, "output/"+storage.TempFilePrefix+"abc")
+
+	_, _, _, _, err := janitor.enforce(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, keysOf(t, objectStorage), 1)
+
+	assert.NoError(t, janitor.RunOnce(context.Background()))
+	_, _, _, _, err = janitor.enforce(context.Background(), time.Now().Add(staleTempFileAge+time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, keysOf(t, objectStorage))
+	assert.Equal(t, int64(1), janitor.Stats().Runs)
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+)
+
+// storageKeyRef tracks the transactions writing one key
+type storageKeyRef struct {
+	holders   int
+	committed bool
+}
+
+// storageKeyRegistry is shared by every transaction of the process, keys are
+// content addressed so two identical requests write the same objects.
+type storageKeyRegistry struct {
+	mutex sync.Mutex
+	refs  map[string]*storageKeyRef
+}
+
+func newStorageKeyRegistry() *storageKeyRegistry {
+	return &storageKeyRegistry{refs: map[string]*storageKeyRef{}}
+}
+
+func (r *storageKeyRegistry) acquire(key string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ref, ok := r.refs[key]
+	if !ok {
+		ref = &storageKeyRef{}
+		r.refs[key] = ref
+	}
+	ref.holders++
+}
+
+// release drops one holder of key, committed marks the object as part of a
+// finished response. deletable reports whether the caller was the only holder
+// and nobody committed the key in the meantime.
+func (r *storageKeyRegistry) release(key string, committed bool) (deletable bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ref, ok := r.refs[key]
+	if !ok {
+		return false
+	}
+	deletable = ref.holders == 1 && !ref.committed && !committed
+	ref.committed = ref.committed || committed
+	ref.holders--
+	if ref.holders == 0 {
+		delete(r.refs, key)
+	}
+	return deletable
+}
+
+// storageTransaction records every object created for one request so they can
+// be removed again when the request fails or times out, objects which existed
+// before or which a concurrent identical request also wrote are kept.
+type storageTransaction struct {
+	storage.Storage
+	registry *storageKeyRegistry
+
+	mutex    sync.Mutex
+	acquired []string
+	created  map[string]bool
+}
+
+func newStorageTransaction(objectStorage storage.Storage, registry *storageKeyRegistry) *storageTransaction {
+	return &storageTransaction{Storage: objectStorage, registry: registry, created: map[string]bool{}}
+}
+
+func (t *storageTransaction) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	// the key is acquired before looking at it, a concurrent writer then sees it as shared
+	t.registry.acquire(key)
+	t.mutex.Lock()
+	t.acquired = append(t.acquired, key)
+	t.mutex.Unlock()
+
+	_, err := t.Storage.Stat(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		return err
+	}
+	if err != nil {
+		// recorded before writing, a failed put may still have left something behind
+		t.mutex.Lock()
+		t.created[key] = true
+		t.mutex.Unlock()
+	}
+
+	return t.Storage.Put(ctx, key, reader, contentType)
+}
+
+// Commit keeps every written object and releases the keys
+func (t *storageTransaction) Commit() {
+	acquired, _ := t.take()
+	for _, key := range acquired {
+		t.registry.release(key, true)
+	}
+}
+
+// Rollback deletes the objects this transaction created newest first, it keeps
+// going on errors and returns the first one.
+func (t *storageTransaction) Rollback(ctx context.Context) error {
+	acquired, created := t.take()
+
+	var firstErr error
+	for index := len(acquired) - 1; index >= 0; index-- {
+		key := acquired[index]
+		if !t.registry.release(key, false) || !created[key] {
+			continue
+		}
+		err := t.Storage.Delete(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t *storageTransaction) take() (acquired []string, created map[string]bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	acquired, created = t.acquired, t.created
+	t.acquired, t.created = nil, map[string]bool{}
+	return acquired, created
+}
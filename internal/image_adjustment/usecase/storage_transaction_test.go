@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func exists(objectStorage storage.Storage, key string) bool {
+	_, err := objectStorage.Stat(context.Background(), key)
+	return err == nil
+}
+
+func TestStorageTransactionRollbackDeletesCreatedKeys(t *testing.T) {
+	ctx := context.Background()
+	objectStorage := storage.NewMemoryStorage("/files/")
+	putObjects(t, objectStorage, "input/existing.jpg")
+
+	tx := newStorageTransaction(objectStorage, newStorageKeyRegistry())
+	assert.NoError(t, tx.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+	assert.NoError(t, tx.Put(ctx, "input/existing.jpg", strings.NewReader("b"), ""))
+	assert.NoError(t, putIfAbsent(ctx, tx, "input/existing.jpg", []byte("c"), ""))
+
+	assert.NoError(t, tx.Rollback(ctx))
+	assert.False(t, exists(objectStorage, "output/a.jpg"))
+	// written before the transaction, so it is not this transaction's to delete
+	assert.True(t, exists(objectStorage, "input/existing.jpg"))
+}
+
+func TestStorageTransactionCommitKeepsKeys(t *testing.T) {
+	ctx := context.Background()
+	objectStorage := storage.NewMemoryStorage("/files/")
+	registry := newStorageKeyRegistry()
+
+	tx := newStorageTransaction(objectStorage, registry)
+	assert.NoError(t, tx.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+	tx.Commit()
+	assert.NoError(t, tx.Rollback(ctx))
+
+	assert.True(t, exists(objectStorage, "output/a.jpg"))
+	assert.Empty(t, registry.refs)
+}
+
+func TestStorageTransactionConcurrentIdenticalWrites(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("failed request after a committed one", func(t *testing.T) {
+		objectStorage := storage.NewMemoryStorage("/files/")
+		registry := newStorageKeyRegistry()
+		first := newStorageTransaction(objectStorage, registry)
+		second := newStorageTransaction(objectStorage, registry)
+
+		// both see the key missing and write it
+		registry.acquire("output/a.jpg")
+		second.acquired = append(second.acquired, "output/a.jpg")
+		second.created["output/a.jpg"] = true
+		assert.NoError(t, first.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+
+		first.Commit()
+		assert.NoError(t, second.Rollback(ctx))
+		assert.True(t, exists(objectStorage, "output/a.jpg"))
+		assert.Empty(t, registry.refs)
+	})
+
+	t.Run("failed request while the other is running", func(t *testing.T) {
+		objectStorage := storage.NewMemoryStorage("/files/")
+		registry := newStorageKeyRegistry()
+		first := newStorageTransaction(objectStorage, registry)
+		second := newStorageTransaction(objectStorage, registry)
+
+		assert.NoError(t, first.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+		assert.NoError(t, second.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+
+		assert.NoError(t, first.Rollback(ctx))
+		assert.True(t, exists(objectStorage, "output/a.jpg"))
+		second.Commit()
+		assert.True(t, exists(objectStorage, "output/a.jpg"))
+	})
+
+	t.Run("both requests fail", func(t *testing.T) {
+		objectStorage := storage.NewMemoryStorage("/files/")
+		registry := newStorageKeyRegistry()
+		first := newStorageTransaction(objectStorage, registry)
+		second := newStorageTransaction(objectStorage, registry)
+
+		registry.acquire("output/a.jpg")
+		second.acquired = append(second.acquired, "output/a.jpg")
+		second.created["output/a.jpg"] = true
+		assert.NoError(t, first.Put(ctx, "output/a.jpg", strings.NewReader("a"), ""))
+
+		assert.NoError(t, first.Rollback(ctx))
+		assert.True(t, exists(objectStorage, "output/a.jpg"))
+		assert.NoError(t, second.Rollback(ctx))
+		assert.False(t, exists(objectStorage, "output/a.jpg"))
+	})
+}
+
+func TestInTransactionRollbackKeepsSharedOutput(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := gradientJpeg(t, 32, 32, 3)
+
+	res, err := useCase.ImageAdjustmentTemperature(newTestContext(), domainRequest(upload, 1.2))
+	assert.NoError(t, err)
+
+	// an identical request failing afterwards leaves the committed objects alone
+	err = useCase.inTransaction(newTestContext(), useCase.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		_, err := useCase.adjustTemperature(ctx, newTestContext(), tx, uploadOf(upload), domainRequest(upload, 1.2))
+		assert.NoError(t, err)
+		return context.DeadlineExceeded
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, exists(useCase.storage, res.InputPathDirImage))
+	assert.True(t, exists(useCase.storage, res.OutputPathDirImage))
+}
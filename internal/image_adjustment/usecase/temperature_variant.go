@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+const (
+	// referenceKelvin is the light a variant without offset is balanced for
+	referenceKelvin = 6500
+	// the planckian locus approximation holds from minKelvin to maxKelvin
+	minKelvin = 1667
+	maxKelvin = 25000
+
+	// contactSheetThumbWidth is the width of every variant on the contact sheet
+	contactSheetThumbWidth = 480
+	contactSheetGap        = 8
+)
+
+// kelvinToRGB returns the linear sRGB colour of black body light of the given
+// temperature with luminance 1, from the planckian locus approximation of Kim et al.
+func kelvinToRGB(kelvin float64) [3]float64 {
+	t := kelvin
+	var x float64
+	if t <= 4000 {
+		x = -0.2661239e9/(t*t*t) - 0.2343589e6/(t*t) + 0.8776956e3/t + 0.179910
+	} else {
+		x = -3.0258469e9/(t*t*t) + 2.1070379e6/(t*t) + 0.2226347e3/t + 0.240390
+	}
+	var y float64
+	switch {
+	case t <= 2222:
+		y = -1.1063814*x*x*x - 1.34811020*x*x + 2.18555832*x - 0.20219683
+	case t <= 4000:
+		y = -0.9549476*x*x*x - 1.37418593*x*x + 2.09137015*x - 0.16748867
+	default:
+		y = 3.0817580*x*x*x - 5.87338670*x*x + 3.75112997*x - 0.37001483
+	}
+
+	X, Y, Z := x/y, 1.0, (1-x-y)/y
+	clamp := func(value float64) float64 { return math.Max(0.001, value) }
+	return [3]float64{
+		clamp(3.2406*X - 1.5372*Y - 0.4986*Z),
+		clamp(-0.9689*X + 1.8758*Y + 0.0415*Z),
+		clamp(0.0557*X - 0.2040*Y + 1.0570*Z),
+	}
+}
+
+// kelvinShiftGains returns the r,g,b gains which treat the light as offset kelvin
+// bluer than the reference, a positive offset warms the image
+func kelvinShiftGains(offset float64) []float64 {
+	reference := kelvinToRGB(referenceKelvin)
+	assumed := kelvinToRGB(math.Max(minKelvin, math.Min(maxKelvin, referenceKelvin+offset)))
+	green := reference[1] / assumed[1]
+	return []float64{
+		reference[0] / assumed[0] / green,
+		1,
+		reference[2] / assumed[2] / green,
+	}
+}
+
+// variantOffsets spreads count offsets step apart around zero
+func variantOffsets(count int, step float64) []float64 {
+	offsets := make([]float64, count)
+	for index := range offsets {
+		offsets[index] = (float64(index) - float64(count-1)/2) * step
+	}
+	return offsets
+}
+
+// combineGains applies gains after base, either may be empty
+func combineGains(base, gains []float64) []float64 {
+	if len(base) != 3 {
+		base = []float64{1, 1, 1}
+	}
+	result := make([]float64, 3)
+	for channel := range result {
+		result[channel] = roundGain(base[channel] * gains[channel])
+	}
+	return result
+}
+
+func (i imageAdjustmentUseCase) ImageAdjustmentVariants(beegoCtx *beegoContext.Context, request domain.ImageAdjustmentRequest) (res domain.TemperatureVariantsResponse, err error) {
+	// the job result only holds a single response, variants are answered right away
+	if request.Async == "true" || request.CallbackUrl != "" {
+		return res, response.ErrAsyncVariants
+	}
+
+	var sheet domain.ImageAdjustmentResponse
+	job, err := i.startJob("", request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	defer func() { i.finishJob(job, sheet, err) }()
+
+	if request.StepKelvin == 0 {
+		request.StepKelvin = domain.DefaultStepKelvin
+	}
+	// a preview answers a single image, the contact sheet
+	if request.Preview == "true" {
+		request.ContactSheet = "true"
+	}
+
+	err = i.inTransaction(beegoCtx, i.contextTimeout, func(ctx context.Context, tx *storageTransaction) error {
+		img, original, inputHash, err := decodeUpload(request.File, request.Preview != "true" && request.StoreInput != "false")
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return err
+		}
+		inputStored, err := i.storeUpload(ctx, beegoCtx, tx, img, original, inputHash, request)
+		if err != nil {
+			return err
+		}
+
+		// Every variant is a plain adjustment with its own gains, so it is cached and stored like one
+		offsets := variantOffsets(request.Variants, request.StepKelvin)
+		res.StepKelvin = request.StepKelvin
+		res.Variants = make([]domain.TemperatureVariant, len(offsets))
+		errs := make([]error, len(offsets))
+		i.forEachConcurrently(len(offsets), func(index int) {
+			variantRequest := request
+			variantRequest.Variants = 0
+			variantRequest.StepKelvin = 0
+			variantRequest.ContactSheet = ""
+			variantRequest.WhiteBalanceGains = combineGains(request.WhiteBalanceGains, kelvinShiftGains(offsets[index]))
+
+			variant := &res.Variants[index]
+			variant.OffsetKelvin = offsets[index]
+			variant.Gains = variantRequest.WhiteBalanceGains
+			variant.ImageAdjustmentResponse, errs[index] = i.adjustDecoded(ctx, fileContext(beegoCtx), tx, img, inputHash, inputStored, variantRequest)
+		}, func(index int, err error) {
+			errs[index] = err
+		})
+		for _, err := range errs {
+			if err != nil {
+				beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+				return err
+			}
+		}
+
+		if request.ContactSheet == "true" {
+			sheet, err = i.renderContactSheet(ctx, beegoCtx, tx, inputHash, inputStored, request, res.Variants)
+			if err != nil {
+				return err
+			}
+			res.ContactSheet = &sheet
+		}
+
+		for index := range res.Variants {
+			if err := i.publish(ctx, beegoCtx, tx, request.ClientID, &res.Variants[index].ImageAdjustmentResponse); err != nil {
+				return err
+			}
+		}
+		if res.ContactSheet != nil {
+			return i.publish(ctx, beegoCtx, tx, request.ClientID, res.ContactSheet)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.TemperatureVariantsResponse{}, err
+	}
+
+	return res, nil
+}
+
+// renderContactSheet puts the variants side by side, each labelled with its kelvin offset
+func (i imageAdjustmentUseCase) renderContactSheet(ctx context.Context, beegoCtx *beegoContext.Context, tx *storageTransaction, inputHash string, inputStored bool, request domain.ImageAdjustmentRequest, variants []domain.TemperatureVariant) (res domain.ImageAdjustmentResponse, err error) {
+	thumbs := make([]image.Image, len(variants))
+	thumbHeight := 0
+	for index, variant := range variants {
+		data := variant.OutputImage
+		if len(data) == 0 {
+			// a cached variant is only referenced by its key
+			data, err = readObject(ctx, i.storage, variant.OutputPathDirImage)
+			if err != nil {
+				beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+				return res, err
+			}
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+			return res, err
+		}
+		thumbs[index] = resizeImage(img, contactSheetThumbWidth, 0, domain.FitInside, domain.InterpolationBilinear)
+		if height := thumbs[index].Bounds().Dy(); height > thumbHeight {
+			thumbHeight = height
+		}
+	}
+
+	scale := labelScale(contactSheetThumbWidth)
+	_, labelHeight := labelSize("0", scale)
+	bandHeight := labelHeight + labelPadding*2*scale
+	width := len(thumbs)*(contactSheetThumbWidth+contactSheetGap) + contactSheetGap
+	height := thumbHeight + bandHeight + contactSheetGap*2
+
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for index, thumb := range thumbs {
+		x := contactSheetGap + index*(contactSheetThumbWidth+contactSheetGap)
+		bounds := thumb.Bounds()
+		draw.Draw(sheet, image.Rect(x, contactSheetGap, x+bounds.Dx(), contactSheetGap+bounds.Dy()), thumb, bounds.Min, draw.Src)
+
+		label := fmt.Sprintf("%+.0fK", variants[index].OffsetKelvin)
+		labelWidth, _ := labelSize(label, scale)
+		drawLabel(sheet, label, x+(bounds.Dx()-labelWidth)/2, contactSheetGap+thumbHeight+labelPadding*scale, scale, color.Black)
+	}
+
+	requestHash, err := paramsHash(request)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return res, err
+	}
+	outputKey := ""
+	if request.Preview != "true" {
+		outputKey = outputNameOf(inputHash, requestHash) + ".jpg"
+	}
+	encoded, err := i.writeOutput(ctx, beegoCtx, tx, outputKey, sheet, request)
+	if err != nil {
+		return res, err
+	}
+
+	return domain.ImageAdjustmentResponse{
+		InputPathDirImage:  helper.InlineConditionString(inputStored, inputKeyOf(inputHash), ""),
+		OutputPathDirImage: outputKey,
+		OutputImage:        encoded.Data,
+		Quality:            encoded.Quality,
+		OutputSizeBytes:    len(encoded.Data),
+		Width:              encoded.Image.Bounds().Dx(),
+		Height:             encoded.Image.Bounds().Dy(),
+	}, nil
+}
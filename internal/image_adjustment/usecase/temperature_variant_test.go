@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKelvinToRGB(t *testing.T) {
+	// black body light of 6500K is close to, but not on, the white point of sRGB
+	reference := kelvinToRGB(referenceKelvin)
+	assert.InDelta(t, reference[1], reference[0], 0.1)
+	assert.InDelta(t, reference[1], reference[2], 0.1)
+
+	candle := kelvinToRGB(2000)
+	assert.Greater(t, candle[0], candle[1])
+	assert.Greater(t, candle[1], candle[2])
+
+	sky := kelvinToRGB(12000)
+	assert.Greater(t, sky[2], sky[1])
+	assert.Greater(t, sky[1], sky[0])
+}
+
+func TestKelvinShiftGains(t *testing.T) {
+	neutral := kelvinShiftGains(0)
+	assert.InDeltaSlice(t, []float64{1, 1, 1}, neutral, 1e-9)
+
+	// a positive offset takes the light for bluer than it is, the image is warmed
+	warm := kelvinShiftGains(600)
+	assert.Greater(t, warm[0], 1.0)
+	assert.Equal(t, 1.0, warm[1])
+	assert.Less(t, warm[2], 1.0)
+
+	cool := kelvinShiftGains(-600)
+	assert.Less(t, cool[0], 1.0)
+	assert.Greater(t, cool[2], 1.0)
+
+	// the offset is clamped to the range of the locus approximation
+	assert.Equal(t, kelvinShiftGains(maxKelvin-referenceKelvin), kelvinShiftGains(100000))
+	assert.Equal(t, kelvinShiftGains(minKelvin-referenceKelvin), kelvinShiftGains(-100000))
+}
+
+func TestVariantOffsets(t *testing.T) {
+	assert.Equal(t, []float64{-600, -300, 0, 300, 600}, variantOffsets(5, 300))
+	assert.Equal(t, []float64{-50, 50}, variantOffsets(2, 100))
+}
+
+func TestCombineGains(t *testing.T) {
+	assert.Equal(t, []float64{1.2346, 1, 0.5}, combineGains(nil, []float64{1.23456, 1, 0.5}))
+	assert.Equal(t, []float64{1, 1, 1}, combineGains([]float64{2, 1, 0.5}, []float64{0.5, 1, 2}))
+}
+
+// regionMean is the mean r,g,b of rect of img
+func regionMean(img image.Image, rect image.Rectangle) [3]float64 {
+	var mean [3]float64
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			mean[0] += float64(r >> 8)
+			mean[1] += float64(g >> 8)
+			mean[2] += float64(b >> 8)
+		}
+	}
+	for channel := range mean {
+		mean[channel] /= float64(rect.Dx() * rect.Dy())
+	}
+	return mean
+}
+
+func TestImageAdjustmentVariants(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := solidJpeg(t, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+	request := domain.ImageAdjustmentRequest{AdjustmentTemperature: 1, Variants: 3, StepKelvin: 500, ContactSheet: "true", ClientID: "alice"}
+
+	request.File = uploadOf(upload)
+	res, err := useCase.ImageAdjustmentVariants(newTestContext(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, 500.0, res.StepKelvin)
+	if !assert.Len(t, res.Variants, 3) {
+		return
+	}
+
+	keys := map[string]bool{}
+	for index, variant := range res.Variants {
+		assert.Equal(t, variantOffsets(3, 500)[index], variant.OffsetKelvin)
+		assert.Equal(t, combineGains(nil, kelvinShiftGains(variant.OffsetKelvin)), variant.Gains)
+		assert.True(t, stored(useCase, variant.OutputPathDirImage))
+		assert.Contains(t, variant.OutputFileImage, "signature=")
+		keys[variant.OutputPathDirImage] = true
+	}
+	assert.Len(t, keys, 3)
+
+	// the coolest variant is on the left of the contact sheet, the warmest on the right
+	if assert.NotNil(t, res.ContactSheet) {
+		assert.Contains(t, res.ContactSheet.OutputFileImage, "signature=")
+		data, err := readObject(context.Background(), useCase.storage, res.ContactSheet.OutputPathDirImage)
+		assert.NoError(t, err)
+		sheet, err := jpeg.Decode(bytes.NewReader(data))
+		assert.NoError(t, err)
+
+		scale := labelScale(contactSheetThumbWidth)
+		_, labelHeight := labelSize("0", scale)
+		assert.Equal(t, 3*(contactSheetThumbWidth+contactSheetGap)+contactSheetGap, sheet.Bounds().Dx())
+		assert.Equal(t, contactSheetThumbWidth+labelHeight+labelPadding*2*scale+contactSheetGap*2, sheet.Bounds().Dy())
+
+		thumb := func(index int) [3]float64 {
+			x := contactSheetGap + index*(contactSheetThumbWidth+contactSheetGap)
+			return regionMean(sheet, image.Rect(x+40, contactSheetGap+40, x+contactSheetThumbWidth-40, contactSheetGap+contactSheetThumbWidth-40))
+		}
+		cool, neutral, warm := thumb(0), thumb(1), thumb(2)
+		assert.Greater(t, cool[2], cool[0])
+		assert.InDelta(t, neutral[0], neutral[2], 3)
+		assert.Greater(t, warm[0], warm[2])
+	}
+
+	// the variants are plain adjustments, a second request is served from storage
+	request.File = uploadOf(upload)
+	again, err := useCase.ImageAdjustmentVariants(newTestContext(), request)
+	assert.NoError(t, err)
+	for index, variant := range again.Variants {
+		assert.True(t, variant.Cached)
+		assert.Equal(t, res.Variants[index].OutputPathDirImage, variant.OutputPathDirImage)
+	}
+	assert.Equal(t, res.ContactSheet.OutputPathDirImage, again.ContactSheet.OutputPathDirImage)
+}
+
+func TestImageAdjustmentVariantsPreview(t *testing.T) {
+	useCase := newTestUseCase(t)
+	res, err := useCase.ImageAdjustmentVariants(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 32, 16, 41)),
+		AdjustmentTemperature: 1,
+		Variants:              2,
+		Preview:               "true",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(domain.DefaultStepKelvin), res.StepKelvin)
+	assert.Equal(t, []float64{-150, 150}, []float64{res.Variants[0].OffsetKelvin, res.Variants[1].OffsetKelvin})
+
+	// a preview always answers the contact sheet and stores nothing
+	if assert.NotNil(t, res.ContactSheet) {
+		assert.NotEmpty(t, res.ContactSheet.OutputImage)
+		assert.Empty(t, res.ContactSheet.OutputPathDirImage)
+		assert.Equal(t, 2*(contactSheetThumbWidth+contactSheetGap)+contactSheetGap, res.ContactSheet.Width)
+	}
+	for _, variant := range res.Variants {
+		assert.Empty(t, variant.OutputPathDirImage)
+	}
+	keys, err := useCase.storage.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+// transformImage runs the geometric pre-adjustment operations in the order
+// rotate, flip, crop so a straightened image can be cropped in the same request.
+func transformImage(img image.Image, request domain.ImageAdjustmentRequest) (image.Image, error) {
+	result := img
+
+	if request.Rotate != 0 {
+		background, err := parseHexColor(request.Background)
+		if err != nil {
+			return nil, err
+		}
+		result = rotateImage(result, request.Rotate, background)
+	}
+
+	switch request.Flip {
+	case domain.FlipHorizontal:
+		result = flipImage(result, true, false)
+	case domain.FlipVertical:
+		result = flipImage(result, false, true)
+	case domain.FlipBoth:
+		result = flipImage(result, true, true)
+	}
+
+	if request.Crop != "" || request.CropAspect != "" {
+		rect, err := cropRect(result.Bounds(), request.Crop, request.CropAspect, request.Gravity)
+		if err != nil {
+			return nil, err
+		}
+		result = cropImage(result, rect)
+	}
+
+	return result, nil
+}
+
+// cropRect resolves either a pixel rect "x,y,width,height" or an aspect ratio
+// "width:height" placed by gravity inside bounds.
+func cropRect(bounds image.Rectangle, crop, aspect, gravity string) (image.Rectangle, error) {
+	if crop != "" {
+		parts := strings.Split(crop, ",")
+		if len(parts) != 4 {
+			return image.Rectangle{}, response.ErrInvalidCrop
+		}
+		var values [4]int
+		for index, part := range parts {
+			value, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || value < 0 {
+				return image.Rectangle{}, response.ErrInvalidCrop
+			}
+			values[index] = value
+		}
+		rect := image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3]).Add(bounds.Min)
+		if rect.Empty() || !rect.In(bounds) {
+			return image.Rectangle{}, response.ErrInvalidCrop
+		}
+		return rect, nil
+	}
+
+	parts := strings.Split(aspect, ":")
+	if len(parts) != 2 {
+		return image.Rectangle{}, response.ErrInvalidCrop
+	}
+	ratioWidth, errWidth := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	ratioHeight, errHeight := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errWidth != nil || errHeight != nil || ratioWidth <= 0 || ratioHeight <= 0 {
+		return image.Rectangle{}, response.ErrInvalidCrop
+	}
+
+	width, height := bounds.Dx(), bounds.Dy()
+	if float64(width)/float64(height) > ratioWidth/ratioHeight {
+		width = int(math.Round(float64(height) * ratioWidth / ratioHeight))
+	} else {
+		height = int(math.Round(float64(width) * ratioHeight / ratioWidth))
+	}
+	if width < 1 || height < 1 {
+		return image.Rectangle{}, response.ErrInvalidCrop
+	}
+
+	// center by default, gravity pulls the crop towards an edge
+	x := (bounds.Dx() - width) / 2
+	y := (bounds.Dy() - height) / 2
+	if strings.Contains(gravity, "west") {
+		x = 0
+	}
+	if strings.Contains(gravity, "east") {
+		x = bounds.Dx() - width
+	}
+	if strings.HasPrefix(gravity, "north") {
+		y = 0
+	}
+	if strings.HasPrefix(gravity, "south") {
+		y = bounds.Dy() - height
+	}
+
+	return image.Rect(x, y, x+width, y+height).Add(bounds.Min), nil
+}
+
+func cropImage(img image.Image, rect image.Rectangle) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
+
+func flipImage(img image.Image, horizontal, vertical bool) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			srcX, srcY := x, y
+			if horizontal {
+				srcX = bounds.Dx() - 1 - x
+			}
+			if vertical {
+				srcY = bounds.Dy() - 1 - y
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+srcX, bounds.Min.Y+srcY))
+		}
+	}
+	return dst
+}
+
+// rotateImage rotates clockwise by degrees, right angles are lossless and any
+// other angle is bilinear sampled onto a canvas filled with background.
+// A NaN or infinite angle has no canvas size, the image is left as is.
+func rotateImage(img image.Image, degrees float64, background color.Color) image.Image {
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return img
+	}
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	switch degrees {
+	case 0:
+		return img
+	case 90, 180, 270:
+		dstWidth, dstHeight := width, height
+		if degrees != 180 {
+			dstWidth, dstHeight = height, width
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				var dstX, dstY int
+				switch degrees {
+				case 90:
+					dstX, dstY = height-1-y, x
+				case 180:
+					dstX, dstY = width-1-x, height-1-y
+				default:
+					dstX, dstY = y, width-1-x
+				}
+				dst.Set(dstX, dstY, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+		return dst
+	}
+
+	radians := degrees * math.Pi / 180
+	sin, cos := math.Sin(radians), math.Cos(radians)
+	dstWidth := int(math.Ceil(math.Abs(float64(width)*cos) + math.Abs(float64(height)*sin)))
+	dstHeight := int(math.Ceil(math.Abs(float64(width)*sin) + math.Abs(float64(height)*cos)))
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	bgR, bgG, bgB, bgA := background.RGBA()
+	srcCenterX, srcCenterY := float64(width)/2, float64(height)/2
+	dstCenterX, dstCenterY := float64(dstWidth)/2, float64(dstHeight)/2
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			// map the destination pixel back onto the source (inverse rotation)
+			dx := float64(x) + 0.5 - dstCenterX
+			dy := float64(y) + 0.5 - dstCenterY
+			srcX := dx*cos + dy*sin + srcCenterX - 0.5
+			srcY := -dx*sin + dy*cos + srcCenterY - 0.5
+
+			if srcX < -0.5 || srcY < -0.5 || srcX > float64(width)-0.5 || srcY > float64(height)-0.5 {
+				dst.SetRGBA(x, y, color.RGBA{R: uint8(bgR >> 8), G: uint8(bgG >> 8), B: uint8(bgB >> 8), A: uint8(bgA >> 8)})
+				continue
+			}
+			dst.Set(x, y, bilinearAt(img, srcX, srcY))
+		}
+	}
+	return dst
+}
+
+func bilinearAt(img image.Image, x, y float64) color.Color {
+	bounds := img.Bounds()
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	var channels [4]float64
+	for _, sample := range []struct {
+		x, y   int
+		weight float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)},
+		{x0 + 1, y0, fx * (1 - fy)},
+		{x0, y0 + 1, (1 - fx) * fy},
+		{x0 + 1, y0 + 1, fx * fy},
+	} {
+		sx := clampInt(sample.x, 0, bounds.Dx()-1) + bounds.Min.X
+		sy := clampInt(sample.y, 0, bounds.Dy()-1) + bounds.Min.Y
+		r, g, b, a := img.At(sx, sy).RGBA()
+		channels[0] += float64(r) * sample.weight
+		channels[1] += float64(g) * sample.weight
+		channels[2] += float64(b) * sample.weight
+		channels[3] += float64(a) * sample.weight
+	}
+
+	return color.RGBA64{
+		R: uint16(channels[0]),
+		G: uint16(channels[1]),
+		B: uint16(channels[2]),
+		A: uint16(channels[3]),
+	}
+}
+
+// parseHexColor parses "#rrggbb" or "rrggbb", an empty value is black
+func parseHexColor(value string) (color.Color, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if value == "" {
+		return color.Black, nil
+	}
+	if len(value) != 6 {
+		return nil, response.ErrInvalidBackground
+	}
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, response.ErrInvalidBackground
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, nil
+}
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
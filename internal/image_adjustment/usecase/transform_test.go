@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// markedImage is black with a single white pixel at mark.
+func markedImage(width, height int, mark image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{A: 255})
+		}
+	}
+	img.SetRGBA(mark.X, mark.Y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	return img
+}
+
+func whiteAt(img image.Image) image.Point {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r == 0xffff {
+				return image.Pt(x-bounds.Min.X, y-bounds.Min.Y)
+			}
+		}
+	}
+	return image.Pt(-1, -1)
+}
+
+func TestCropRectPixels(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+
+	tests := []struct {
+		crop string
+		want image.Rectangle
+		err  error
+	}{
+		{crop: "10,5,20,30", want: image.Rect(10, 5, 30, 35)},
+		{crop: " 0, 0, 100, 50 ", want: bounds},
+		{crop: "90,40,10,10", want: image.Rect(90, 40, 100, 50)},
+		{crop: "91,40,10,10", err: response.ErrInvalidCrop},
+		{crop: "0,0,101,50", err: response.ErrInvalidCrop},
+		{crop: "0,0,0,10", err: response.ErrInvalidCrop},
+		{crop: "-1,0,10,10", err: response.ErrInvalidCrop},
+		{crop: "0,0,10", err: response.ErrInvalidCrop},
+		{crop: "a,0,10,10", err: response.ErrInvalidCrop},
+		{crop: "9223372036854775807,0,10,10", err: response.ErrInvalidCrop},
+	}
+	for _, test := range tests {
+		rect, err := cropRect(bounds, test.crop, "", "")
+		assert.Equal(t, test.err, err, test.crop)
+		if test.err == nil {
+			assert.Equal(t, test.want, rect, test.crop)
+		}
+	}
+}
+
+func TestCropRectOffsetBounds(t *testing.T) {
+	bounds := image.Rect(10, 20, 110, 70)
+
+	rect, err := cropRect(bounds, "0,0,100,50", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, bounds, rect)
+
+	_, err = cropRect(bounds, "1,0,100,50", "", "")
+	assert.Equal(t, response.ErrInvalidCrop, err)
+}
+
+func TestCropRectAspect(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 100)
+
+	tests := []struct {
+		aspect  string
+		gravity string
+		want    image.Rectangle
+		err     error
+	}{
+		{aspect: "1:1", want: image.Rect(50, 0, 150, 100)},
+		{aspect: "1:1", gravity: "west", want: image.Rect(0, 0, 100, 100)},
+		{aspect: "1:1", gravity: "southeast", want: image.Rect(100, 0, 200, 100)},
+		{aspect: "4:1", want: image.Rect(0, 25, 200, 75)},
+		{aspect: "4:1", gravity: "north", want: image.Rect(0, 0, 200, 50)},
+		{aspect: "4:1", gravity: "south", want: image.Rect(0, 50, 200, 100)},
+		{aspect: "1000:1", err: response.ErrInvalidCrop},
+		{aspect: "0:1", err: response.ErrInvalidCrop},
+		{aspect: "1", err: response.ErrInvalidCrop},
+	}
+	for _, test := range tests {
+		rect, err := cropRect(bounds, "", test.aspect, test.gravity)
+		assert.Equal(t, test.err, err, test.aspect)
+		if test.err == nil {
+			assert.Equal(t, test.want, rect, "%s %s", test.aspect, test.gravity)
+			assert.True(t, rect.In(bounds))
+		}
+	}
+}
+
+func TestRotateImageRightAngles(t *testing.T) {
+	img := markedImage(4, 2, image.Pt(0, 0))
+
+	tests := []struct {
+		degrees float64
+		size    image.Point
+		mark    image.Point
+	}{
+		{degrees: 0, size: image.Pt(4, 2), mark: image.Pt(0, 0)},
+		{degrees: 90, size: image.Pt(2, 4), mark: image.Pt(1, 0)},
+		{degrees: 180, size: image.Pt(4, 2), mark: image.Pt(3, 1)},
+		{degrees: 270, size: image.Pt(2, 4), mark: image.Pt(0, 3)},
+		{degrees: -90, size: image.Pt(2, 4), mark: image.Pt(0, 3)},
+		{degrees: 450, size: image.Pt(2, 4), mark: image.Pt(1, 0)},
+	}
+	for _, test := range tests {
+		rotated := rotateImage(img, test.degrees, color.Black)
+		assert.Equal(t, test.size, rotated.Bounds().Size(), "%v degrees", test.degrees)
+		assert.Equal(t, test.mark, whiteAt(rotated), "%v degrees", test.degrees)
+	}
+}
+
+func TestRotateImageArbitraryAngle(t *testing.T) {
+	img := markedImage(100, 50, image.Pt(0, 0))
+	background := color.RGBA{R: 255, A: 255}
+
+	rotated := rotateImage(img, 45, background)
+	// |w cos| + |h sin| for both sides, rounded up
+	assert.Equal(t, image.Pt(107, 107), rotated.Bounds().Size())
+	assert.Equal(t, color.RGBAModel.Convert(background), color.RGBAModel.Convert(rotated.At(0, 0)))
+	assert.Equal(t, color.RGBA{A: 255}, color.RGBAModel.Convert(rotated.At(53, 53)))
+}
+
+func TestRotateImageNonFinite(t *testing.T) {
+	img := markedImage(4, 2, image.Pt(0, 0))
+	for _, degrees := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		assert.Same(t, img, rotateImage(img, degrees, color.Black), "%v degrees", degrees)
+	}
+}
+
+func TestTransformImageOrder(t *testing.T) {
+	img := markedImage(4, 2, image.Pt(0, 0))
+
+	// rotate 90 puts the mark at (1,0), the horizontal flip moves it to (0,0),
+	// the crop keeps the top left pixel
+	result, err := transformImage(img, domain.ImageAdjustmentRequest{
+		Rotate: 90,
+		Flip:   domain.FlipHorizontal,
+		Crop:   "0,0,1,1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, image.Pt(1, 1), result.Bounds().Size())
+	assert.Equal(t, image.Pt(0, 0), whiteAt(result))
+
+	_, err = transformImage(img, domain.ImageAdjustmentRequest{Rotate: 10, Background: "#12345"})
+	assert.Equal(t, response.ErrInvalidBackground, err)
+}
+
+func TestParseHexColor(t *testing.T) {
+	value, err := parseHexColor("#ff8000")
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, G: 128, A: 255}, value)
+
+	value, err = parseHexColor("")
+	assert.NoError(t, err)
+	assert.Equal(t, color.Black, value)
+
+	_, err = parseHexColor("zzzzzz")
+	assert.Equal(t, response.ErrInvalidBackground, err)
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+	"gorm.io/gorm"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/webhook"
+)
+
+// deliverCallback posts the outcome of job to its callback url and records every attempt.
+// The progress is kept in the job row, a delivery interrupted by ctx stays pending and
+// is resumed by StartWorkers, one that runs out of attempts is dead lettered.
+func (i imageAdjustmentUseCase) deliverCallback(ctx context.Context, job domain.AdjustmentJob) {
+	payload := domain.WebhookPayload{
+		JobID:  job.ID,
+		Status: job.Status,
+		Error:  job.Error,
+	}
+	if job.Status == domain.JobStatusDone && job.Result != "" {
+		var res domain.ImageAdjustmentResponse
+		if err := json.Unmarshal([]byte(job.Result), &res); err != nil {
+			i.zapLogger.Errorf("webhook payload of job %s: %s", job.ID, err.Error())
+			return
+		}
+		// signed outside of any request, the links use appUrl or cdnUrl
+		i.signResponse(newJobContext(), &res)
+		payload.Data = &res
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		i.zapLogger.Errorf("webhook payload of job %s: %s", job.ID, err.Error())
+		return
+	}
+
+	if job.CallbackNextAttemptAt != nil {
+		timer := time.NewTimer(time.Until(*job.CallbackNextAttemptAt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	err = i.webhookSender.Deliver(ctx, job.CallbackUrl, "adjustment_job."+job.Status, body, job.CallbackAttempts+1, func(attempt webhook.Attempt) {
+		delivery := domain.WebhookDelivery{
+			JobID:      job.ID,
+			Url:        job.CallbackUrl,
+			Attempt:    attempt.Number,
+			StatusCode: attempt.StatusCode,
+			Delivered:  attempt.Err == nil,
+			DeadLetter: attempt.Err != nil && attempt.Final,
+			DurationMs: attempt.Duration.Milliseconds(),
+		}
+		if attempt.Err != nil {
+			delivery.Error = attempt.Err.Error()
+		}
+
+		ctx, cancel := i.recordContext()
+		defer cancel()
+		if err := i.webhookDeliveryRepository.Create(ctx, &delivery); err != nil {
+			i.zapLogger.Errorf("record webhook delivery: %s", err.Error())
+		}
+
+		job.CallbackAttempts = attempt.Number
+		job.CallbackNextAttemptAt = nil
+		if attempt.Final {
+			// the outcome is recorded once Deliver returned
+			return
+		}
+		job.CallbackNextAttemptAt = &attempt.NextAttemptAt
+		if err := i.adjustmentJobRepository.Update(ctx, &job); err != nil {
+			i.zapLogger.Errorf("record adjustment job: %s", err.Error())
+		}
+	})
+	if err != nil && ctx.Err() != nil {
+		// stays pending, the next start resumes it
+		return
+	}
+
+	job.CallbackStatus = domain.CallbackStatusDelivered
+	if err != nil {
+		job.CallbackStatus = domain.CallbackStatusDeadLetter
+		i.zapLogger.Errorf("webhook of job %s dead lettered: %s", job.ID, err.Error())
+	}
+	recordCtx, cancel := i.recordContext()
+	defer cancel()
+	if err := i.adjustmentJobRepository.Update(recordCtx, &job); err != nil {
+		i.zapLogger.Errorf("record adjustment job: %s", err.Error())
+	}
+}
+
+// resumeCallbacks delivers the callbacks a previous process left pending
+func (i imageAdjustmentUseCase) resumeCallbacks(ctx context.Context) error {
+	jobs, err := i.adjustmentJobRepository.FetchByCallbackStatus(ctx, domain.CallbackStatusPending, -1)
+	if err != nil {
+		return err
+	}
+	if len(jobs) > 0 {
+		i.zapLogger.Infof("resuming %d webhook deliveries", len(jobs))
+	}
+	for _, job := range jobs {
+		go i.deliverCallback(ctx, job)
+	}
+	return nil
+}
+
+func (i imageAdjustmentUseCase) FetchJobDeliveries(beegoCtx *beegoContext.Context, id string) (res []domain.WebhookDelivery, err error) {
+	ctx, cancel := context.WithTimeout(beegoCtx.Request.Context(), i.contextTimeout)
+	defer cancel()
+
+	_, err = i.adjustmentJobRepository.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, response.ErrJobNotFound
+		}
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return nil, err
+	}
+
+	res, err = i.webhookDeliveryRepository.FetchByJobID(ctx, id)
+	if err != nil {
+		beegoCtx.Input.SetData("stackTrace", i.zapLogger.SetMessageLog(err))
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+// callbackReceiver answers the callbacks it receives with the status codes in turn, the last one repeats
+type callbackReceiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	attempts []int
+	payloads []domain.WebhookPayload
+}
+
+func (c *callbackReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	if r.Header.Get(webhook.HeaderSignature) != "sha256="+webhook.Sign("secret", timestamp, body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	attempt, _ := strconv.Atoi(r.Header.Get(webhook.HeaderAttempt))
+	c.attempts = append(c.attempts, attempt)
+	var payload domain.WebhookPayload
+	json.Unmarshal(body, &payload)
+	c.payloads = append(c.payloads, payload)
+
+	status := c.statuses[len(c.statuses)-1]
+	if len(c.attempts) <= len(c.statuses) {
+		status = c.statuses[len(c.attempts)-1]
+	}
+	w.WriteHeader(status)
+}
+
+func (c *callbackReceiver) received() ([]int, []domain.WebhookPayload) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]int(nil), c.attempts...), append([]domain.WebhookPayload(nil), c.payloads...)
+}
+
+// waitForCallback polls the job until its callback left the pending state or the test gives up
+func waitForCallback(t *testing.T, useCase testUseCase, id string) domain.AdjustmentJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := useCase.jobs.GetByID(context.Background(), id)
+		assert.NoError(t, err)
+		if (job.CallbackStatus != "" && job.CallbackStatus != domain.CallbackStatusPending) || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallbackIsRetriedAndRecorded(t *testing.T) {
+	useCase := newTestUseCase(t)
+	receiver := &callbackReceiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 1, 5*time.Second))
+
+	queued, err := useCase.EnqueueImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 16, 16, 11)),
+		AdjustmentTemperature: 1.2,
+		CallbackUrl:           server.URL,
+		ClientID:              "alice",
+	})
+	assert.NoError(t, err)
+
+	job := waitForCallback(t, useCase, queued.ID)
+	assert.Equal(t, domain.CallbackStatusDelivered, job.CallbackStatus)
+	assert.Equal(t, 2, job.CallbackAttempts)
+	assert.Nil(t, job.CallbackNextAttemptAt)
+
+	attempts, payloads := receiver.received()
+	assert.Equal(t, []int{1, 2}, attempts)
+	if assert.Len(t, payloads, 2) && assert.NotNil(t, payloads[1].Data) {
+		assert.Equal(t, domain.JobStatusDone, payloads[1].Status)
+		assert.Contains(t, payloads[1].Data.OutputFileImage, "signature=")
+	}
+
+	deliveries, err := useCase.FetchJobDeliveries(newTestContext(), queued.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, http.StatusInternalServerError, deliveries[0].StatusCode)
+		assert.False(t, deliveries[0].Delivered)
+		assert.True(t, deliveries[1].Delivered)
+	}
+}
+
+func TestCallbackIsDeadLetteredWhenRetriesRunOut(t *testing.T) {
+	useCase := newTestUseCase(t)
+	receiver := &callbackReceiver{statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	_, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{
+		File:                  uploadOf(gradientJpeg(t, 16, 16, 12)),
+		AdjustmentTemperature: 1.2,
+		CallbackUrl:           server.URL,
+	})
+	assert.NoError(t, err)
+
+	var id string
+	for _, job := range useCase.jobs.fetch(func(job domain.AdjustmentJob) bool { return job.CallbackUrl == server.URL }, -1) {
+		id = job.ID
+	}
+	job := waitForCallback(t, useCase, id)
+	assert.Equal(t, domain.CallbackStatusDeadLetter, job.CallbackStatus)
+	assert.Equal(t, useCase.webhookSender.MaxAttempts(), job.CallbackAttempts)
+
+	attempts, _ := receiver.received()
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+	deliveries, err := useCase.FetchJobDeliveries(newTestContext(), id)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 3) {
+		assert.False(t, deliveries[1].DeadLetter)
+		assert.True(t, deliveries[2].DeadLetter)
+	}
+}
+
+func TestStartWorkersResumesPendingCallbacks(t *testing.T) {
+	useCase := newTestUseCase(t)
+	receiver := &callbackReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// a previous process made the first attempt and was stopped while waiting for the second
+	finishedAt := time.Now().Add(-time.Minute)
+	nextAttemptAt := time.Now().Add(20 * time.Millisecond)
+	useCase.jobs.jobs["pending"] = domain.AdjustmentJob{
+		ID:                    "pending",
+		Operation:             domain.OperationTemperature,
+		Status:                domain.JobStatusFailed,
+		Error:                 "boom",
+		CallbackUrl:           server.URL,
+		CallbackStatus:        domain.CallbackStatusPending,
+		CallbackAttempts:      1,
+		CallbackNextAttemptAt: &nextAttemptAt,
+		QueuedAt:              finishedAt,
+		FinishedAt:            &finishedAt,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, useCase.StartWorkers(ctx, 1, 5*time.Second))
+
+	job := waitForCallback(t, useCase, "pending")
+	assert.Equal(t, domain.CallbackStatusDelivered, job.CallbackStatus)
+	assert.Equal(t, 2, job.CallbackAttempts)
+
+	attempts, payloads := receiver.received()
+	assert.Equal(t, []int{2}, attempts)
+	if assert.Len(t, payloads, 1) {
+		assert.Equal(t, domain.WebhookPayload{JobID: "pending", Status: domain.JobStatusFailed, Error: "boom"}, payloads[0])
+	}
+}
+
+func TestCallbackStaysPendingWhenStopped(t *testing.T) {
+	useCase := newTestUseCase(t)
+	receiver := &callbackReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	nextAttemptAt := time.Now().Add(time.Hour)
+	job := domain.AdjustmentJob{
+		ID:                    "stopped",
+		Status:                domain.JobStatusFailed,
+		CallbackUrl:           server.URL,
+		CallbackStatus:        domain.CallbackStatusPending,
+		CallbackAttempts:      1,
+		CallbackNextAttemptAt: &nextAttemptAt,
+	}
+	useCase.jobs.jobs[job.ID] = job
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	useCase.deliverCallback(ctx, job)
+
+	stored, err := useCase.jobs.GetByID(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.CallbackStatusPending, stored.CallbackStatus)
+	attempts, _ := receiver.received()
+	assert.Empty(t, attempts)
+}
+
+// queueFillingJobRepository fills the job queue right after a job was created, as a
+// concurrent request would
+type queueFillingJobRepository struct {
+	*fakeAdjustmentJobRepository
+	queue chan string
+}
+
+func (r queueFillingJobRepository) Create(ctx context.Context, job *domain.AdjustmentJob) error {
+	for len(r.queue) < cap(r.queue) {
+		r.queue <- "other"
+	}
+	return r.fakeAdjustmentJobRepository.Create(ctx, job)
+}
+
+func TestRejectedJobSendsNoCallback(t *testing.T) {
+	useCase := newTestUseCase(t)
+	receiver := &callbackReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	image := createImage(t, useCase, gradientJpeg(t, 16, 16, 13), "alice")
+	useCase.adjustmentJobRepository = queueFillingJobRepository{fakeAdjustmentJobRepository: useCase.jobs, queue: useCase.jobQueue.jobs}
+
+	_, err := useCase.EnqueueImageTemperature(newTestContext(), image.ID, domain.ImageAdjustmentRequest{AdjustmentTemperature: 1.2, CallbackUrl: server.URL})
+	assert.Equal(t, response.ErrJobQueueFull, err)
+
+	jobs := useCase.jobs.fetch(func(job domain.AdjustmentJob) bool { return job.Status == domain.JobStatusFailed }, -1)
+	if assert.Len(t, jobs, 1) {
+		assert.Empty(t, jobs[0].CallbackUrl)
+		assert.Empty(t, jobs[0].CallbackStatus)
+	}
+	time.Sleep(50 * time.Millisecond)
+	attempts, _ := receiver.received()
+	assert.Empty(t, attempts)
+}
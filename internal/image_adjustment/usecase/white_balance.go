@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"image"
+	"io"
+	"math"
+	"sort"
+
+	beegoContext "github.com/beego/beego/v2/server/web/context"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+const (
+	// illuminantSamples bounds the pixels read to estimate an illuminant
+	illuminantSamples = 1 << 18
+	// pixels at or above illuminantClipLevel in any channel carry no colour
+	// information, pixels below illuminantDarkLevel in every channel mostly noise
+	illuminantClipLevel = 250
+	illuminantDarkLevel = 8
+	// white balance gains are kept within a factor of 4 either way
+	minWhiteBalanceGain = 0.25
+	maxWhiteBalanceGain = 4.0
+	// maxTintShift is the part of green a tint of 100 takes away, -100 adds it
+	maxTintShift = 0.3
+)
+
+// estimateIlluminant returns the r,g,b of the light of img by the gray world
+// assumption, the mean colour of a scene is neutral. Clipped and very dark pixels are left out.
+func estimateIlluminant(img image.Image) []float64 {
+	src := toFloatImage(img)
+	step := int(math.Sqrt(float64(src.width*src.height) / illuminantSamples))
+	if step < 1 {
+		step = 1
+	}
+
+	var sum [3]float64
+	var count int
+	for y := 0; y < src.height; y += step {
+		for x := 0; x < src.width; x += step {
+			pixel := src.pix[(y*src.width+x)*3:]
+			r, g, b := pixel[0], pixel[1], pixel[2]
+			if r >= illuminantClipLevel || g >= illuminantClipLevel || b >= illuminantClipLevel {
+				continue
+			}
+			if r < illuminantDarkLevel && g < illuminantDarkLevel && b < illuminantDarkLevel {
+				continue
+			}
+			sum[0] += float64(r)
+			sum[1] += float64(g)
+			sum[2] += float64(b)
+			count++
+		}
+	}
+	if count == 0 {
+		// nothing usable, assume the light is already neutral
+		return []float64{128, 128, 128}
+	}
+	return []float64{sum[0] / float64(count), sum[1] / float64(count), sum[2] / float64(count)}
+}
+
+// medianIlluminant is the per channel median of illuminants
+func medianIlluminant(illuminants [][]float64) []float64 {
+	result := make([]float64, 3)
+	values := make([]float64, len(illuminants))
+	for channel := range result {
+		for index, illuminant := range illuminants {
+			values[index] = illuminant[channel]
+		}
+		sort.Float64s(values)
+		middle := len(values) / 2
+		if len(values)%2 == 0 {
+			result[channel] = (values[middle-1] + values[middle]) / 2
+		} else {
+			result[channel] = values[middle]
+		}
+	}
+	return result
+}
+
+// whiteBalanceGainsOf returns the r,g,b gains which make illuminant neutral, green is kept as is
+func whiteBalanceGainsOf(illuminant []float64) []float64 {
+	gain := func(value float64) float64 {
+		if value <= 0 {
+			return 1
+		}
+		return math.Max(minWhiteBalanceGain, math.Min(maxWhiteBalanceGain, illuminant[1]/value))
+	}
+	return []float64{roundGain(gain(illuminant[0])), 1, roundGain(gain(illuminant[2]))}
+}
+
+// roundGain keeps four decimals so the gains read back from a response give the same output
+func roundGain(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
+
+// tintGains adds a tint on the green-magenta axis to gains, a positive tint lowers green towards
+// magenta and a negative one raises it. gains are returned as they are without a tint.
+func tintGains(gains []float64, tint float64) []float64 {
+	if tint == 0 {
+		return gains
+	}
+	return combineGains(gains, []float64{1, 1 - tint/100*maxTintShift, 1})
+}
+
+// whiteBalanceImage multiplies every channel by its gain, img is returned as is without gains
+func whiteBalanceImage(img image.Image, gains []float64) image.Image {
+	if len(gains) != 3 {
+		return img
+	}
+
+	var lookup [3][256]float32
+	for channel := range lookup {
+		for value := range lookup[channel] {
+			lookup[channel][value] = float32(float64(value) * gains[channel])
+		}
+	}
+
+	result := toFloatImage(img)
+	parallelTiles(result.height, func(startY, endY int) {
+		for offset := startY * result.width * 3; offset < endY*result.width*3; offset += 3 {
+			for channel := 0; channel < 3; channel++ {
+				result.pix[offset+channel] = lookup[channel][uint8(result.pix[offset+channel])]
+			}
+		}
+	})
+	return result.toRGBA()
+}
+
+func (i imageAdjustmentUseCase) ImageWhiteBalance(beegoCtx *beegoContext.Context, request domain.WhiteBalanceRequest) (res domain.WhiteBalanceResponse, err error) {
+	files := request.Files
+	if i.batchLimits.MaxEntries > 0 && len(files) > i.batchLimits.MaxEntries {
+		return res, response.ErrTooManyFiles
+	}
+	if request.Strategy == "" {
+		request.Strategy = domain.WhiteBalanceMedian
+	}
+	if request.Strategy == domain.WhiteBalanceAnchor && (request.AnchorIndex < 0 || request.AnchorIndex >= len(files)) {
+		return res, response.ErrInvalidAnchor
+	}
+
+	// The illuminants of all files are needed before any is adjusted. Only they are kept from
+	// this pass, every file is decoded again to be adjusted so no more than one image per
+	// worker is held at a time
+	decodeErrs := make([]error, len(files))
+	res.Files = make([]domain.ImageAdjustmentFileResult, len(files))
+	i.forEachConcurrently(len(files), func(index int) {
+		img, _, _, err := decodeUpload(files[index].File, false)
+		if err != nil {
+			decodeErrs[index] = err
+			return
+		}
+		res.Files[index].Illuminant = roundIlluminant(estimateIlluminant(img))
+	}, func(index int, err error) {
+		decodeErrs[index] = err
+		res.Files[index].Illuminant = nil
+	})
+
+	if request.Strategy == domain.WhiteBalanceAnchor {
+		if decodeErrs[request.AnchorIndex] != nil {
+			return domain.WhiteBalanceResponse{}, response.ErrInvalidAnchor
+		}
+		res.Illuminant = res.Files[request.AnchorIndex].Illuminant
+	} else {
+		var illuminants [][]float64
+		for index := range decodeErrs {
+			if decodeErrs[index] == nil {
+				illuminants = append(illuminants, res.Files[index].Illuminant)
+			}
+		}
+		if len(illuminants) > 0 {
+			res.Illuminant = roundIlluminant(medianIlluminant(illuminants))
+		}
+	}
+	res.Strategy = request.Strategy
+	res.AnchorIndex = request.AnchorIndex
+	if res.Illuminant != nil {
+		res.Gains = whiteBalanceGainsOf(res.Illuminant)
+	}
+
+	i.forEachConcurrently(len(files), func(index int) {
+		result := &res.Files[index]
+		result.FileName = files[index].FileHeader.Filename
+
+		err := decodeErrs[index]
+		if err == nil {
+			_, err = files[index].File.Seek(0, io.SeekStart)
+		}
+		if err == nil {
+			fileRequest := files[index]
+			fileRequest.WhiteBalanceGains = res.Gains
+			result.Data, err = i.ImageAdjustmentTemperature(fileContext(beegoCtx), fileRequest)
+		}
+		if err != nil {
+			failFileResult(result, err)
+			return
+		}
+		result.Status = domain.BatchEntryDone
+	}, func(index int, err error) {
+		res.Files[index].FileName = files[index].FileHeader.Filename
+		failFileResult(&res.Files[index], err)
+	})
+
+	return res, nil
+}
+
+func roundIlluminant(illuminant []float64) []float64 {
+	for channel := range illuminant {
+		illuminant[channel] = math.Round(illuminant[channel]*100) / 100
+	}
+	return illuminant
+}
@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"sync/atomic"
+	"testing"
+
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func solidImage(width, height int, fill color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for offset := 0; offset < len(img.Pix); offset += 4 {
+		img.Pix[offset], img.Pix[offset+1], img.Pix[offset+2], img.Pix[offset+3] = fill.R, fill.G, fill.B, fill.A
+	}
+	return img
+}
+
+func solidJpeg(t *testing.T, fill color.RGBA) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, solidImage(16, 16, fill), &jpeg.Options{Quality: 100}))
+	return buf.Bytes()
+}
+
+// meanColour is the mean r,g,b of a stored jpeg
+func meanColour(t *testing.T, useCase testUseCase, key string) []float64 {
+	data, err := readObject(context.Background(), useCase.storage, key)
+	assert.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	src := toFloatImage(img)
+	mean := make([]float64, 3)
+	for offset := 0; offset < len(src.pix); offset += 3 {
+		for channel := range mean {
+			mean[channel] += float64(src.pix[offset+channel])
+		}
+	}
+	for channel := range mean {
+		mean[channel] /= float64(src.width * src.height)
+	}
+	return mean
+}
+
+func TestEstimateIlluminant(t *testing.T) {
+	img := solidImage(10, 10, color.RGBA{R: 200, G: 150, B: 100, A: 255})
+	assert.Equal(t, []float64{200, 150, 100}, estimateIlluminant(img))
+
+	// clipped and very dark pixels are left out
+	for x := 0; x < 10; x++ {
+		img.SetRGBA(x, 0, color.RGBA{R: 255, G: 40, B: 40, A: 255})
+		img.SetRGBA(x, 1, color.RGBA{R: 2, G: 3, B: 4, A: 255})
+	}
+	assert.Equal(t, []float64{200, 150, 100}, estimateIlluminant(img))
+
+	// nothing usable is taken as neutral light
+	assert.Equal(t, []float64{128, 128, 128}, estimateIlluminant(solidImage(4, 4, color.RGBA{R: 255, G: 255, B: 255, A: 255})))
+}
+
+func TestMedianIlluminant(t *testing.T) {
+	assert.Equal(t, []float64{180, 150, 120}, medianIlluminant([][]float64{{200, 150, 100}, {160, 140, 140}, {180, 160, 120}}))
+	assert.Equal(t, []float64{170, 150, 110}, medianIlluminant([][]float64{{200, 150, 100}, {140, 150, 120}}))
+}
+
+func TestWhiteBalanceGainsOf(t *testing.T) {
+	assert.Equal(t, []float64{0.5, 1, 2}, whiteBalanceGainsOf([]float64{200, 100, 50}))
+	assert.Equal(t, []float64{0.8333, 1, 1.25}, whiteBalanceGainsOf([]float64{180, 150, 120}))
+	// gains stay within a factor of 4 and a channel without light is left as is
+	assert.Equal(t, []float64{0.25, 1, 4}, whiteBalanceGainsOf([]float64{250, 50, 5}))
+	assert.Equal(t, []float64{1, 1, 1}, whiteBalanceGainsOf([]float64{0, 100, 0}))
+}
+
+func TestWhiteBalanceImage(t *testing.T) {
+	img := solidImage(2, 2, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+	assert.Same(t, image.Image(img), whiteBalanceImage(img, nil))
+
+	balanced := whiteBalanceImage(img, []float64{0.5, 1, 2}).(*image.RGBA)
+	assert.Equal(t, color.RGBA{R: 100, G: 100, B: 100, A: 255}, balanced.RGBAAt(1, 1))
+	// the input is never modified
+	assert.Equal(t, color.RGBA{R: 200, G: 100, B: 50, A: 255}, img.RGBAAt(1, 1))
+}
+
+func whiteBalanceFile(name string, upload []byte) domain.ImageAdjustmentRequest {
+	return domain.ImageAdjustmentRequest{
+		File:                  uploadOf(upload),
+		FileHeader:            &multipart.FileHeader{Filename: name, Size: int64(len(upload))},
+		AdjustmentTemperature: 1,
+		ClientID:              "alice",
+	}
+}
+
+func TestImageWhiteBalanceMedian(t *testing.T) {
+	useCase := newTestUseCase(t)
+	truncated := solidJpeg(t, color.RGBA{R: 90, G: 150, B: 210, A: 255})
+
+	res, err := useCase.ImageWhiteBalance(newTestContext(), domain.WhiteBalanceRequest{Files: []domain.ImageAdjustmentRequest{
+		whiteBalanceFile("warm.jpg", solidJpeg(t, color.RGBA{R: 200, G: 150, B: 100, A: 255})),
+		whiteBalanceFile("mid.jpg", solidJpeg(t, color.RGBA{R: 180, G: 150, B: 120, A: 255})),
+		whiteBalanceFile("broken.jpg", truncated[:len(truncated)/2]),
+		whiteBalanceFile("cool.jpg", solidJpeg(t, color.RGBA{R: 160, G: 150, B: 140, A: 255})),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.WhiteBalanceMedian, res.Strategy)
+
+	// the broken file is left out of the median and fails alone
+	if assert.Len(t, res.Illuminant, 3) && assert.Len(t, res.Gains, 3) {
+		assert.InDelta(t, 180, res.Illuminant[0], 2)
+		assert.InDelta(t, 150, res.Illuminant[1], 2)
+		assert.InDelta(t, 120, res.Illuminant[2], 2)
+		assert.InDelta(t, 0.8333, res.Gains[0], 0.02)
+		assert.Equal(t, 1.0, res.Gains[1])
+		assert.InDelta(t, 1.25, res.Gains[2], 0.03)
+	}
+	if assert.Len(t, res.Files, 4) {
+		assert.Equal(t, domain.BatchEntryFailed, res.Files[2].Status)
+		assert.Equal(t, "broken.jpg", res.Files[2].FileName)
+		assert.Nil(t, res.Files[2].Illuminant)
+		for _, index := range []int{0, 1, 3} {
+			assert.Equal(t, domain.BatchEntryDone, res.Files[index].Status)
+			assert.Len(t, res.Files[index].Illuminant, 3)
+		}
+
+		// the shared gains make the file with the median cast neutral, the others keep their difference
+		mid := res.Files[1].Data.(domain.ImageAdjustmentResponse)
+		mean := meanColour(t, useCase, mid.OutputPathDirImage)
+		assert.InDelta(t, mean[1], mean[0], 3)
+		assert.InDelta(t, mean[1], mean[2], 3)
+		warm := meanColour(t, useCase, res.Files[0].Data.(domain.ImageAdjustmentResponse).OutputPathDirImage)
+		assert.Greater(t, warm[0], warm[2])
+	}
+}
+
+// rewindCountingUpload counts the rewinds of an upload, a rewind fails with err
+type rewindCountingUpload struct {
+	uploadFile
+	rewinds *int32
+	err     error
+}
+
+func (u rewindCountingUpload) Seek(offset int64, whence int) (int64, error) {
+	atomic.AddInt32(u.rewinds, 1)
+	if u.err != nil {
+		return 0, u.err
+	}
+	return u.uploadFile.Seek(offset, whence)
+}
+
+func TestImageWhiteBalanceDecodesEveryFileAgain(t *testing.T) {
+	useCase := newTestUseCase(t)
+	warm := solidJpeg(t, color.RGBA{R: 200, G: 150, B: 100, A: 255})
+	cool := solidJpeg(t, color.RGBA{R: 160, G: 150, B: 140, A: 255})
+
+	// only the illuminants are kept from the first pass, the adjust pass reads every upload from its start again
+	rewinds := make([]int32, 3)
+	files := []domain.ImageAdjustmentRequest{
+		whiteBalanceFile("warm.jpg", warm),
+		whiteBalanceFile("cool.jpg", cool),
+		whiteBalanceFile("gone.jpg", warm),
+	}
+	for index := range files {
+		files[index].File = rewindCountingUpload{uploadFile: files[index].File.(uploadFile), rewinds: &rewinds[index]}
+	}
+	files[2].File = rewindCountingUpload{uploadFile: uploadOf(warm).(uploadFile), rewinds: &rewinds[2], err: errors.New("upload is gone")}
+
+	res, err := useCase.ImageWhiteBalance(newTestContext(), domain.WhiteBalanceRequest{Files: files})
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{1, 1, 1}, rewinds)
+
+	if assert.Len(t, res.Files, 3) {
+		for index, upload := range [][]byte{warm, cool} {
+			assert.Equal(t, domain.BatchEntryDone, res.Files[index].Status)
+			stored, err := readObject(context.Background(), useCase.storage, res.Files[index].Data.(domain.ImageAdjustmentResponse).InputPathDirImage)
+			assert.NoError(t, err)
+			assert.Equal(t, upload, stored)
+		}
+
+		// a file which can not be read again fails alone, its illuminant still counted
+		assert.Equal(t, domain.BatchEntryFailed, res.Files[2].Status)
+		assert.Equal(t, "upload is gone", res.Files[2].Message)
+		assert.Len(t, res.Files[2].Illuminant, 3)
+		assert.Nil(t, res.Files[2].Data)
+	}
+}
+
+func TestImageWhiteBalanceAnchor(t *testing.T) {
+	useCase := newTestUseCase(t)
+	files := func() []domain.ImageAdjustmentRequest {
+		broken := solidJpeg(t, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+		return []domain.ImageAdjustmentRequest{
+			whiteBalanceFile("warm.jpg", solidJpeg(t, color.RGBA{R: 200, G: 100, B: 50, A: 255})),
+			whiteBalanceFile("cool.jpg", solidJpeg(t, color.RGBA{R: 120, G: 150, B: 180, A: 255})),
+			whiteBalanceFile("broken.jpg", broken[:len(broken)/2]),
+		}
+	}
+
+	res, err := useCase.ImageWhiteBalance(newTestContext(), domain.WhiteBalanceRequest{Strategy: domain.WhiteBalanceAnchor, AnchorIndex: 0, Files: files()})
+	assert.NoError(t, err)
+	assert.Equal(t, res.Files[0].Illuminant, res.Illuminant)
+	if assert.Len(t, res.Gains, 3) {
+		assert.InDelta(t, 0.5, res.Gains[0], 0.02)
+		assert.InDelta(t, 2, res.Gains[2], 0.1)
+	}
+
+	for _, anchorIndex := range []int{-1, 3, 2} {
+		_, err = useCase.ImageWhiteBalance(newTestContext(), domain.WhiteBalanceRequest{Strategy: domain.WhiteBalanceAnchor, AnchorIndex: anchorIndex, Files: files()})
+		assert.Equal(t, response.ErrInvalidAnchor, err, anchorIndex)
+	}
+
+	useCase.batchLimits.MaxEntries = 2
+	_, err = useCase.ImageWhiteBalance(newTestContext(), domain.WhiteBalanceRequest{Files: files()})
+	assert.Equal(t, response.ErrTooManyFiles, err)
+}
+
+func TestTintGains(t *testing.T) {
+	assert.Nil(t, tintGains(nil, 0))
+	assert.Equal(t, []float64{0.5, 1, 2}, tintGains([]float64{0.5, 1, 2}, 0))
+
+	// a positive tint takes green away towards magenta, a negative one adds it
+	assert.Equal(t, []float64{1, 0.7, 1}, tintGains(nil, 100))
+	assert.Equal(t, []float64{1, 1.15, 1}, tintGains(nil, -50))
+	assert.Equal(t, []float64{0.5, 0.94, 2}, tintGains([]float64{0.5, 1, 2}, 20))
+}
+
+func TestImageAdjustmentTemperatureTint(t *testing.T) {
+	useCase := newTestUseCase(t)
+	upload := solidJpeg(t, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+
+	magenta, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1, Tint: 100})
+	assert.NoError(t, err)
+	mean := meanColour(t, useCase, magenta.OutputPathDirImage)
+	assert.InDelta(t, 128, mean[0], 2)
+	assert.InDelta(t, 128*0.7, mean[1], 2)
+	assert.InDelta(t, 128, mean[2], 2)
+
+	// the tint is applied with the white balance gains, the same colour given as gains is the same output
+	gains, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1, WhiteBalanceGains: []float64{1, 0.7, 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, mean, meanColour(t, useCase, gains.OutputPathDirImage))
+
+	green, err := useCase.ImageAdjustmentTemperature(newTestContext(), domain.ImageAdjustmentRequest{File: uploadOf(upload), AdjustmentTemperature: 1, Tint: -50})
+	assert.NoError(t, err)
+	mean = meanColour(t, useCase, green.OutputPathDirImage)
+	assert.InDelta(t, 128*1.15, mean[1], 2)
+	assert.NotEqual(t, magenta.OutputPathDirImage, green.OutputPathDirImage)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,11 +14,18 @@ import (
 	beegoContext "github.com/beego/beego/v2/server/web/context"
 	"github.com/beego/beego/v2/server/web/filter/cors"
 	"github.com/beego/i18n"
+	"github.com/radyatamaa/image-temperature-adjustment/internal/domain"
 	"github.com/radyatamaa/image-temperature-adjustment/internal/middlewares"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/database"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/helper"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/signer"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/storage"
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/webhook"
 	"github.com/radyatamaa/image-temperature-adjustment/pkg/zaplogger"
 
 	imageAdjustmentHandler "github.com/radyatamaa/image-temperature-adjustment/internal/image_adjustment/delivery/http/v1"
+	imageAdjustmentRepository "github.com/radyatamaa/image-temperature-adjustment/internal/image_adjustment/repository"
 	imageAdjustmentUsecase "github.com/radyatamaa/image-temperature-adjustment/internal/image_adjustment/usecase"
 )
 
@@ -65,12 +73,59 @@ func main() {
 	// zap logger
 	zapLog := zaplogger.NewZapLogger(logPath, slackWebHookUrl)
 
-	err = os.Mkdir("external/storage", 0755)
-	if err != nil && !strings.Contains(err.Error(),"Cannot create a file when that file already exists."){
+	// storage
+	storageDriver := beego.AppConfig.DefaultString("storageDriver", storage.DriverLocal)
+	storageLocalPath := beego.AppConfig.DefaultString("storageLocalPath", "external/storage")
+	var imageStorage storage.Storage
+	switch storageDriver {
+	case storage.DriverS3:
+		imageStorage, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  beego.AppConfig.DefaultString("storageS3Endpoint", ""),
+			Region:    beego.AppConfig.DefaultString("storageS3Region", ""),
+			Bucket:    beego.AppConfig.DefaultString("storageS3Bucket", ""),
+			AccessKey: beego.AppConfig.DefaultString("storageS3AccessKey", ""),
+			SecretKey: beego.AppConfig.DefaultString("storageS3SecretKey", ""),
+			PathStyle: beego.AppConfig.DefaultBool("storageS3PathStyle", true),
+			PublicUrl: beego.AppConfig.DefaultString("storageS3PublicUrl", ""),
+			Timeout:   timeoutContext,
+		})
+	case storage.DriverMemory:
+		imageStorage = storage.NewMemoryStorage("/external/storage")
+	default:
+		imageStorage, err = storage.NewLocalStorage(storageLocalPath, "/external/storage")
+	}
+	if err != nil {
 		fmt.Println(err.Error())
 		panic(err)
 	}
 
+	// envelope encryption at rest
+	var encryptedStorage *storage.EncryptedStorage
+	if beego.AppConfig.DefaultBool("storageEncryptionEnabled", false) {
+		keyring, err := storage.LoadKeyFile(beego.AppConfig.DefaultString("storageEncryptionKeyFile", "conf/storage.keys"),
+			beego.AppConfig.DefaultString("storageEncryptionActiveKey", ""))
+		if err != nil {
+			panic(err)
+		}
+		encryptedStorage = storage.NewEncryptedStorage(imageStorage, keyring)
+		imageStorage = encryptedStorage
+	}
+
+	// `go run main.go reencrypt-storage` rewrites the storage with the active key and exits
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt-storage" {
+		if encryptedStorage == nil {
+			fmt.Println("storage encryption is not enabled, set storageEncryptionEnabled = true")
+			os.Exit(1)
+		}
+		stats, err := encryptedStorage.Reencrypt(context.Background())
+		fmt.Printf("scanned %d objects, re-encrypted %d\n", stats.Scanned, stats.Reencrypted)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	if beego.BConfig.RunMode == "dev" {
 		// static files swagger
 		beego.BConfig.WebConfig.DirectoryIndex = true
@@ -84,11 +139,41 @@ func main() {
 		beego.BConfig.WebConfig.StaticDir["/swagger"] = "swagger"
 	}
 
-	beego.BConfig.WebConfig.StaticDir["/external/storage"] = "external/storage"
+
+	// signed download links
+	urlSigningKeys, err := signer.ParseKeys(beego.AppConfig.DefaultString("urlSigningKeys", ""))
+	if err != nil {
+		panic(err)
+	}
+	if len(urlSigningKeys) == 0 {
+		panic("urlSigningKeys is not configured")
+	}
+	for keyID, secret := range urlSigningKeys {
+		if err := signer.ValidateSecret("urlSigningKeys "+keyID, secret); err != nil {
+			panic(err)
+		}
+	}
+	urlSigner, err := signer.NewURLSigner(urlSigningKeys,
+		beego.AppConfig.DefaultString("urlSigningActiveKey", ""),
+		time.Duration(beego.AppConfig.DefaultInt64("urlSigningTTL", 3600))*time.Second)
+	if err != nil {
+		panic(err)
+	}
+
+	// public urls
+	trustedProxies, err := helper.ParseTrustedProxies(beego.AppConfig.DefaultString("trustedProxies", ""))
+	if err != nil {
+		panic(err)
+	}
+	configHelper := helper.ConfigHelper{
+		AppUrl:         beego.AppConfig.DefaultString("appUrl", ""),
+		CdnUrl:         beego.AppConfig.DefaultString("cdnUrl", ""),
+		TrustedProxies: trustedProxies,
+	}
 
 	// middleware init
 	beego.InsertFilter("*", beego.BeforeRouter, cors.Allow(&cors.Options{
-		AllowMethods:    []string{http.MethodGet, http.MethodPost},
+		AllowMethods:    []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
 		AllowAllOrigins: true,
 	}))
 
@@ -105,14 +190,79 @@ func main() {
 	// default error handler
 	beego.ErrorController(&response.ErrorController{})
 
+	// database
+	db, err := database.NewDatabase(database.Config{
+		Driver:       beego.AppConfig.DefaultString("databaseDriver", database.DriverSqlite),
+		Dsn:          beego.AppConfig.DefaultString("databaseDsn", "external/image_adjustment.db"),
+		MaxOpenConns: beego.AppConfig.DefaultInt("databaseMaxOpenConns", 10),
+		MaxIdleConns: beego.AppConfig.DefaultInt("databaseMaxIdleConns", 5),
+		MaxLifetime:  time.Duration(beego.AppConfig.DefaultInt64("databaseMaxLifetime", 300)) * time.Second,
+		Debug:        beego.BConfig.RunMode == "dev",
+	})
+	if err != nil {
+		panic(err)
+	}
+	if beego.AppConfig.DefaultBool("databaseAutoMigrate", true) {
+		if err := db.AutoMigrate(&domain.Image{}, &domain.AdjustmentJob{}, &domain.WebhookDelivery{}, &domain.Preset{}); err != nil {
+			panic(err)
+		}
+	}
+
 	// init repository
+	imageRepository := imageAdjustmentRepository.NewImageRepository(db)
+	adjustmentJobRepository := imageAdjustmentRepository.NewAdjustmentJobRepository(db)
+	webhookDeliveryRepository := imageAdjustmentRepository.NewWebhookDeliveryRepository(db)
+	presetRepository := imageAdjustmentRepository.NewPresetRepository(db)
 
+	// job completion callbacks
+	webhookSecret := beego.AppConfig.DefaultString("webhookSecret", "")
+	if err := signer.ValidateSecret("webhookSecret", []byte(webhookSecret)); err != nil {
+		panic(err)
+	}
+	webhookSender := webhook.NewSender(webhook.Config{
+		Secret:               webhookSecret,
+		MaxAttempts:          beego.AppConfig.DefaultInt("webhookMaxAttempts", 6),
+		InitialBackoff:       time.Duration(beego.AppConfig.DefaultInt64("webhookInitialBackoff", 2)) * time.Second,
+		MaxBackoff:           time.Duration(beego.AppConfig.DefaultInt64("webhookMaxBackoff", 300)) * time.Second,
+		Timeout:              time.Duration(beego.AppConfig.DefaultInt64("webhookTimeout", 10)) * time.Second,
+		DeadLetterPath:       beego.AppConfig.DefaultString("webhookDeadLetterPath", "./logs/webhook_dead_letter.log"),
+		AllowPrivateNetworks: beego.AppConfig.DefaultBool("webhookAllowPrivateNetworks", false),
+	})
 
 	// init usecase
-	imageAdjustmentUseCase := imageAdjustmentUsecase.NewImageAdjustmentUseCase(timeoutContext, zapLog)
+	imageAdjustmentUseCase := imageAdjustmentUsecase.NewImageAdjustmentUseCase(timeoutContext, zapLog, imageStorage, urlSigner, configHelper,
+		beego.AppConfig.DefaultInt64("imageCacheMaxBytes", 256<<20), imageRepository, adjustmentJobRepository,
+		beego.AppConfig.DefaultInt("jobQueueSize", 1000),
+		webhookDeliveryRepository, webhookSender, domain.BatchLimits{
+			Workers:    beego.AppConfig.DefaultInt("batchWorkers", 0),
+			MaxEntries: beego.AppConfig.DefaultInt("batchMaxEntries", 1000),
+			Timeout:    time.Duration(beego.AppConfig.DefaultInt64("batchTimeout", 600)) * time.Second,
+		})
+
+	presetUseCase := imageAdjustmentUsecase.NewPresetUseCase(timeoutContext, zapLog, presetRepository)
+
+	// asynchronous adjustment jobs
+	err = imageAdjustmentUseCase.StartWorkers(context.Background(),
+		beego.AppConfig.DefaultInt("jobWorkers", 2),
+		time.Duration(beego.AppConfig.DefaultInt64("jobTimeout", 600))*time.Second)
+	if err != nil {
+		panic(err)
+	}
+
+	// storage retention janitor
+	storageJanitor := imageAdjustmentUsecase.NewStorageJanitor(imageStorage, imageRepository, domain.RetentionPolicy{
+		MaxAge:            time.Duration(beego.AppConfig.DefaultInt64("retentionMaxAge", 0)) * time.Second,
+		MaxTotalBytes:     beego.AppConfig.DefaultInt64("retentionMaxTotalBytes", 0),
+		KeepLastPerClient: beego.AppConfig.DefaultInt("retentionKeepLastPerClient", 0),
+	}, zapLog)
+	if beego.AppConfig.DefaultBool("retentionEnabled", false) {
+		storageJanitor.Start(context.Background(), time.Duration(beego.AppConfig.DefaultInt64("retentionInterval", 600))*time.Second)
+	}
 
 	// init handler
-	imageAdjustmentHandler.NewImageAdjustmentHandler(imageAdjustmentUseCase, zapLog)
+	imageAdjustmentHandler.NewImageAdjustmentHandler(imageAdjustmentUseCase, presetUseCase, zapLog)
+	imageAdjustmentHandler.NewPresetHandler(presetUseCase, zapLog, beego.AppConfig.DefaultString("adminApiKey", ""))
+	imageAdjustmentHandler.NewAdminHandler(storageJanitor, zapLog, beego.AppConfig.DefaultString("adminApiKey", ""))
 
 	// default error handler
 	beego.ErrorController(&internal.BaseController{})
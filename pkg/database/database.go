@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	DriverSqlite    = "sqlite"
+	DriverPostgres  = "postgres"
+	DriverSqlserver = "sqlserver"
+)
+
+type Config struct {
+	Driver string
+	// Dsn is a file path for sqlite, e.g. external/image_adjustment.db, or the
+	// connection string of the server
+	Dsn          string
+	MaxOpenConns int
+	MaxIdleConns int
+	MaxLifetime  time.Duration
+	Debug        bool
+}
+
+// NewDatabase opens a gorm connection, sqlite is meant for local use and
+// postgres for production
+func NewDatabase(config Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch config.Driver {
+	case DriverSqlite:
+		dialector = sqlite.Open(config.Dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(config.Dsn)
+	case DriverSqlserver:
+		dialector = sqlserver.Open(config.Dsn)
+	default:
+		return nil, fmt.Errorf("database: unknown driver %q", config.Driver)
+	}
+
+	logLevel := logger.Silent
+	if config.Debug {
+		logLevel = logger.Info
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if config.Driver == DriverSqlite {
+		// sqlite allows a single writer, one connection avoids "database is locked"
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(config.MaxLifetime)
+
+	return db, nil
+}
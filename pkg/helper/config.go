@@ -1,5 +1,114 @@
 package helper
 
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
 type ConfigHelper struct {
 	AppUrl string
+	// CdnUrl replaces AppUrl for links to stored files when set
+	CdnUrl string
+	// TrustedProxies are the networks allowed to set Forwarded / X-Forwarded-* headers
+	TrustedProxies []*net.IPNet
+}
+
+// ParseTrustedProxies parses "10.0.0.0/8|127.0.0.1" into networks, a plain ip is a single host
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var result []*net.IPNet
+	for _, item := range strings.Split(value, "|") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if strings.Contains(item, ":") {
+				item += "/128"
+			} else {
+				item += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+func (c ConfigHelper) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range c.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedProtoHost reads the first hop of the Forwarded header, falling back to X-Forwarded-Proto and X-Forwarded-Host
+func forwardedProtoHost(request *http.Request) (proto, host string) {
+	if forwarded := request.Header.Get("Forwarded"); forwarded != "" {
+		firstHop := strings.Split(forwarded, ",")[0]
+		for _, pair := range strings.Split(firstHop, ";") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.Trim(parts[1], `"`)
+			switch strings.ToLower(parts[0]) {
+			case "proto":
+				proto = value
+			case "host":
+				host = value
+			}
+		}
+	}
+	if proto == "" {
+		proto = strings.TrimSpace(strings.Split(request.Header.Get("X-Forwarded-Proto"), ",")[0])
+	}
+	if host == "" {
+		host = strings.TrimSpace(strings.Split(request.Header.Get("X-Forwarded-Host"), ",")[0])
+	}
+	return proto, host
+}
+
+// BaseUrl returns the public scheme and host of the service without a trailing slash,
+// forwarded headers are only honoured when the request comes from a trusted proxy.
+func (c ConfigHelper) BaseUrl(request *http.Request) string {
+	scheme, host := "http", request.Host
+	if appUrl := strings.TrimSuffix(c.AppUrl, "/"); appUrl != "" {
+		if parts := strings.SplitN(appUrl, "://", 2); len(parts) == 2 {
+			scheme, host = parts[0], parts[1]
+		}
+	}
+
+	if c.isTrustedProxy(request.RemoteAddr) {
+		proto, forwardedHost := forwardedProtoHost(request)
+		if proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	return scheme + "://" + host
+}
+
+// FileBaseUrl is BaseUrl unless a CDN is configured in front of the stored files
+func (c ConfigHelper) FileBaseUrl(request *http.Request) string {
+	if c.CdnUrl != "" {
+		return strings.TrimSuffix(c.CdnUrl, "/")
+	}
+	return c.BaseUrl(request)
 }
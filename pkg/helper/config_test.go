@@ -0,0 +1,91 @@
+package helper
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := ParseTrustedProxies(" 10.0.0.0/8 | 127.0.0.1 | ::1 |")
+	assert.NoError(t, err)
+	assert.Len(t, networks, 3)
+	assert.Equal(t, "127.0.0.1/32", networks[1].String())
+	assert.Equal(t, "::1/128", networks[2].String())
+
+	_, err = ParseTrustedProxies("not-an-ip")
+	assert.Error(t, err)
+}
+
+func TestBaseUrl(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		config     ConfigHelper
+		remoteAddr string
+		header     map[string]string
+		want       string
+	}{
+		{
+			name:       "request host",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "http://example.com",
+		},
+		{
+			name:       "app url",
+			config:     ConfigHelper{AppUrl: "https://api.example.com/"},
+			remoteAddr: "192.0.2.1:1234",
+			want:       "https://api.example.com",
+		},
+		{
+			name:       "forwarded headers from an untrusted client",
+			config:     ConfigHelper{AppUrl: "https://api.example.com", TrustedProxies: proxies},
+			remoteAddr: "192.0.2.1:1234",
+			header:     map[string]string{"X-Forwarded-Proto": "http", "X-Forwarded-Host": "evil.example.com"},
+			want:       "https://api.example.com",
+		},
+		{
+			name:       "x-forwarded headers from a trusted proxy",
+			config:     ConfigHelper{TrustedProxies: proxies},
+			remoteAddr: "10.1.2.3:1234",
+			header:     map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "public.example.com, internal"},
+			want:       "https://public.example.com",
+		},
+		{
+			name:       "forwarded header wins",
+			config:     ConfigHelper{TrustedProxies: proxies},
+			remoteAddr: "10.1.2.3:1234",
+			header: map[string]string{
+				"Forwarded":        `proto=https;host="cdn.example.com", proto=http;host=internal`,
+				"X-Forwarded-Host": "other.example.com",
+			},
+			want: "https://cdn.example.com",
+		},
+		{
+			name:       "unknown forwarded proto",
+			config:     ConfigHelper{TrustedProxies: proxies},
+			remoteAddr: "10.1.2.3:1234",
+			header:     map[string]string{"X-Forwarded-Proto": "javascript"},
+			want:       "http://example.com",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "http://example.com/api", nil)
+			request.RemoteAddr = test.remoteAddr
+			for name, value := range test.header {
+				request.Header.Set(name, value)
+			}
+			assert.Equal(t, test.want, test.config.BaseUrl(request))
+		})
+	}
+}
+
+func TestFileBaseUrl(t *testing.T) {
+	request := httptest.NewRequest("GET", "http://example.com/api", nil)
+	assert.Equal(t, "http://example.com", ConfigHelper{}.FileBaseUrl(request))
+	assert.Equal(t, "https://cdn.example.com", ConfigHelper{CdnUrl: "https://cdn.example.com/"}.FileBaseUrl(request))
+}
@@ -3,6 +3,7 @@ package helper
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -139,6 +140,29 @@ func StringToInt(value string) int {
 	}
 	return 0
 }
+// StringToIntSlice parses "1,2,3" or "[1,2,3]" into a slice of int
+func StringToIntSlice(value string) []int {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	if value == "" {
+		return nil
+	}
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		result = append(result, StringToInt(strings.TrimSpace(item)))
+	}
+	return result
+}
+func StringToFloatSlice(value string) []float64 {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	if value == "" {
+		return nil
+	}
+	var result []float64
+	for _, item := range strings.Split(value, ",") {
+		result = append(result, StringToFloat(strings.TrimSpace(item)))
+	}
+	return result
+}
 func StringNullableToInt(value *string) int {
 
 	if value != nil {
@@ -1,6 +1,7 @@
 package response
 
 import (
+	"context"
 	"errors"
 
 	"github.com/beego/i18n"
@@ -64,6 +65,18 @@ const (
 
 	InvalidFormatFileJpegErrorCode = "ERROR-API-035"
 	RequiredFileErrorCode = "ERROR-API-036"
+	OutputSizeUnreachableErrorCode = "ERROR-API-037"
+	InvalidCropErrorCode = "ERROR-API-038"
+	InvalidBackgroundErrorCode = "ERROR-API-039"
+	UploadTooLargeErrorCode = "ERROR-API-040"
+	JobQueueFullErrorCode = "ERROR-API-041"
+	AsyncPreviewErrorCode = "ERROR-API-042"
+	InvalidArchiveErrorCode = "ERROR-API-043"
+	TooManyFilesErrorCode = "ERROR-API-044"
+	MultiFilePreviewErrorCode = "ERROR-API-045"
+	InvalidAnchorErrorCode = "ERROR-API-046"
+	PresetNotFoundErrorCode = "ERROR-API-047"
+	AsyncVariantsErrorCode = "ERROR-API-048"
 )
 
 var (
@@ -84,6 +97,28 @@ var (
 
 	ErrInvalidFormatFileJpeg = errors.New("file format must be JPEG image")
 	ErrRequiredFile = errors.New("file required")
+	ErrOutputSizeUnreachable = errors.New("output image can not fit max output bytes")
+	ErrInvalidCrop = errors.New("crop must be x,y,width,height inside the image or an aspect ratio width:height")
+	ErrInvalidBackground = errors.New("background must be a hex color like #ffffff")
+	ErrUploadTooLarge = errors.New("uploaded file is too large to be stored")
+	ErrJobQueueFull = errors.New("adjustment job queue is full")
+	ErrAsyncPreview = errors.New("async can not be combined with preview")
+	ErrAsyncVariants = errors.New("async and callback_url can not be combined with variants")
+	ErrInvalidArchive = errors.New("file must be a zip archive holding images within the batch limit")
+	ErrTooManyFiles = errors.New("too many files in one request")
+	ErrMultiFilePreview = errors.New("preview returns a single image and can not be combined with files[]")
+	ErrInvalidAnchor = errors.New("anchor_index must point to a valid file")
+	ErrPresetNotFound = errors.New("preset not found")
+	ErrPresetAlreadyExists = errors.New("preset already exists")
+	ErrMissingApiKey = errors.New("api key is missing")
+	ErrInvalidApiKey = errors.New("api key is invalid")
+
+	ErrFileNotFound = errors.New("file not found")
+	ErrImageNotFound = errors.New("image not found")
+	ErrJobNotFound = errors.New("job not found")
+	ErrMissingToken = errors.New("token is missing")
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token is expired")
 )
 
 func ErrorCodeText(code, locale string, args ...interface{}) string {
@@ -168,7 +203,60 @@ func ErrorCodeText(code, locale string, args ...interface{}) string {
 		return i18n.Tr(locale, "message.errorInvalidFormatFileJpeg", args)
 	case RequiredFileErrorCode:
 		return i18n.Tr(locale, "message.errorRequiredFile", args)
+	case OutputSizeUnreachableErrorCode:
+		return i18n.Tr(locale, "message.errorOutputSizeUnreachable", args)
+	case InvalidCropErrorCode:
+		return i18n.Tr(locale, "message.errorInvalidCrop", args)
+	case InvalidBackgroundErrorCode:
+		return i18n.Tr(locale, "message.errorInvalidBackground", args)
+	case UploadTooLargeErrorCode:
+		return i18n.Tr(locale, "message.errorUploadTooLarge", args)
+	case JobQueueFullErrorCode:
+		return i18n.Tr(locale, "message.errorJobQueueFull", args)
+	case AsyncPreviewErrorCode:
+		return i18n.Tr(locale, "message.errorAsyncPreview", args)
+	case InvalidArchiveErrorCode:
+		return i18n.Tr(locale, "message.errorInvalidArchive", args)
+	case TooManyFilesErrorCode:
+		return i18n.Tr(locale, "message.errorTooManyFiles", args)
+	case MultiFilePreviewErrorCode:
+		return i18n.Tr(locale, "message.errorMultiFilePreview", args)
+	case InvalidAnchorErrorCode:
+		return i18n.Tr(locale, "message.errorInvalidAnchor", args)
+	case PresetNotFoundErrorCode:
+		return i18n.Tr(locale, "message.errorPresetNotFound", args)
+	case AsyncVariantsErrorCode:
+		return i18n.Tr(locale, "message.errorAsyncVariants", args)
 	default:
 		return ""
 	}
 }
+
+// ErrorCodeOf returns the error code reported for err when it can not be answered
+// with its own http status, e.g. for one entry of a batch
+func ErrorCodeOf(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return RequestTimeoutCodeError
+	case errors.Is(err, ErrRequiredFile):
+		return RequiredFileErrorCode
+	case errors.Is(err, ErrInvalidFormatFileJpeg):
+		return InvalidFormatFileJpegErrorCode
+	case errors.Is(err, ErrOutputSizeUnreachable):
+		return OutputSizeUnreachableErrorCode
+	case errors.Is(err, ErrInvalidCrop):
+		return InvalidCropErrorCode
+	case errors.Is(err, ErrInvalidBackground):
+		return InvalidBackgroundErrorCode
+	case errors.Is(err, ErrUploadTooLarge):
+		return UploadTooLargeErrorCode
+	case errors.Is(err, ErrJobQueueFull):
+		return JobQueueFullErrorCode
+	case errors.Is(err, ErrImageNotFound), errors.Is(err, ErrJobNotFound):
+		return DataNotFoundCodeError
+	case errors.Is(err, ErrPresetNotFound):
+		return PresetNotFoundErrorCode
+	default:
+		return ServerErrorCode
+	}
+}
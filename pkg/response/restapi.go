@@ -51,6 +51,19 @@ func (r ApiResponse) Ok(ctx *context.Context, message string, data interface{})
 	}, beego.BConfig.RunMode != "prod", false)
 }
 
+// Accepted answers a request whose processing continues in the background
+func (r ApiResponse) Accepted(ctx *context.Context, message string, data interface{}) error {
+	ctx.Output.SetStatus(http.StatusAccepted)
+
+	return ctx.Output.JSON(ApiResponse{
+		Code:      http.StatusText(http.StatusAccepted),
+		RequestId: ctx.ResponseWriter.ResponseWriter.Header().Get("X-REQUEST-ID"),
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
+	}, beego.BConfig.RunMode != "prod", false)
+}
+
 func (r ApiResponse) ResponseError(ctx *context.Context, httpStatus int, errorCode string, message string, err error) error {
 	var apiResponse ApiResponse
 	var errorValidations []Errors = nil
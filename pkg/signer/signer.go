@@ -0,0 +1,140 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+)
+
+const (
+	QueryExpires   = "expires"
+	QueryKeyID     = "kid"
+	QuerySignature = "signature"
+
+	// MinSecretLength is the shortest secret accepted for signing
+	MinSecretLength = 32
+	// placeholderSecret is the value older configs shipped with
+	placeholderSecret = "change-me-to-a-long-random-secret"
+	// tokenSubjectPrefix keeps token signatures apart from link signatures
+	tokenSubjectPrefix = "token\n"
+)
+
+// URLSigner signs object keys with HMAC-SHA256 and an expiry, several keys can
+// be configured so links signed before a key rotation keep working until they expire.
+type URLSigner struct {
+	keys        map[string][]byte
+	activeKeyID string
+	ttl         time.Duration
+}
+
+// ParseKeys parses "id1:secret1|id2:secret2" as found in conf/app.ini
+func ParseKeys(value string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(value, "|") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("signer: invalid key %q, expected id:secret", pair)
+		}
+		keys[parts[0]] = []byte(parts[1])
+	}
+	return keys, nil
+}
+
+// ValidateSecret rejects a secret which is empty, shorter than MinSecretLength
+// or still the shipped placeholder, name is only used in the error.
+func ValidateSecret(name string, secret []byte) error {
+	switch {
+	case len(secret) == 0:
+		return fmt.Errorf("signer: %s is not configured", name)
+	case string(secret) == placeholderSecret:
+		return fmt.Errorf("signer: %s is still the placeholder, generate one with openssl rand -base64 32", name)
+	case len(secret) < MinSecretLength:
+		return fmt.Errorf("signer: %s must be at least %d characters", name, MinSecretLength)
+	}
+	return nil
+}
+
+func NewURLSigner(keys map[string][]byte, activeKeyID string, ttl time.Duration) (*URLSigner, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("signer: active key %q is not configured", activeKeyID)
+	}
+	return &URLSigner{
+		keys:        keys,
+		activeKeyID: activeKeyID,
+		ttl:         ttl,
+	}, nil
+}
+
+func (s *URLSigner) signature(keyID, objectKey string, expires int64) string {
+	mac := hmac.New(sha256.New, s.keys[keyID])
+	mac.Write([]byte(keyID + "\n" + objectKey + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the query which grants access to objectKey until now plus the ttl
+func (s *URLSigner) Sign(objectKey string, now time.Time) url.Values {
+	expires := now.Add(s.ttl).Unix()
+	query := url.Values{}
+	query.Set(QueryExpires, strconv.FormatInt(expires, 10))
+	query.Set(QueryKeyID, s.activeKeyID)
+	query.Set(QuerySignature, s.signature(s.activeKeyID, objectKey, expires))
+	return query
+}
+
+// Verify checks the query produced by Sign for objectKey
+func (s *URLSigner) Verify(objectKey, expires, keyID, signature string, now time.Time) error {
+	if expires == "" || keyID == "" || signature == "" {
+		return response.ErrMissingToken
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return response.ErrInvalidToken
+	}
+	if _, ok := s.keys[keyID]; !ok {
+		return response.ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(keyID, objectKey, expiresAt))) {
+		return response.ErrInvalidToken
+	}
+	if now.Unix() > expiresAt {
+		return response.ErrExpiredToken
+	}
+	return nil
+}
+
+// Token signs subject with the active key and no expiry, it is handed out to
+// prove ownership later, e.g. of an uploaded image
+func (s *URLSigner) Token(subject string) string {
+	return s.activeKeyID + "." + s.signature(s.activeKeyID, tokenSubjectPrefix+subject, 0)
+}
+
+// VerifyToken checks a token produced by Token for subject, tokens of a key
+// removed from the configuration are no longer valid
+func (s *URLSigner) VerifyToken(subject, token string) error {
+	if token == "" {
+		return response.ErrMissingToken
+	}
+	index := strings.LastIndex(token, ".")
+	if index <= 0 {
+		return response.ErrInvalidToken
+	}
+	keyID, signature := token[:index], token[index+1:]
+	if _, ok := s.keys[keyID]; !ok {
+		return response.ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(keyID, tokenSubjectPrefix+subject, 0))) {
+		return response.ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package signer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radyatamaa/image-temperature-adjustment/pkg/response"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	oldSecret = []byte("old-secret-0123456789abcdefghijklmnop")
+	newSecret = []byte("new-secret-0123456789abcdefghijklmnop")
+)
+
+func TestVerifyExpiry(t *testing.T) {
+	signer, err := NewURLSigner(map[string][]byte{"k1": oldSecret}, "k1", time.Hour)
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	query := signer.Sign("output/a.jpg", now)
+	assert.Equal(t, "1700003600", query.Get(QueryExpires))
+
+	verify := func(at time.Time) error {
+		return signer.Verify("output/a.jpg", query.Get(QueryExpires), query.Get(QueryKeyID), query.Get(QuerySignature), at)
+	}
+	assert.NoError(t, verify(now))
+	assert.NoError(t, verify(now.Add(time.Hour)))
+	assert.Equal(t, response.ErrExpiredToken, verify(now.Add(time.Hour+time.Second)))
+}
+
+func TestVerifyTampering(t *testing.T) {
+	signer, err := NewURLSigner(map[string][]byte{"k1": oldSecret}, "k1", time.Hour)
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	query := signer.Sign("output/a.jpg", now)
+	expires, keyID, signature := query.Get(QueryExpires), query.Get(QueryKeyID), query.Get(QuerySignature)
+
+	assert.Equal(t, response.ErrInvalidToken, signer.Verify("output/b.jpg", expires, keyID, signature, now))
+	assert.Equal(t, response.ErrInvalidToken, signer.Verify("output/a.jpg", "1700007200", keyID, signature, now))
+	assert.Equal(t, response.ErrInvalidToken, signer.Verify("output/a.jpg", "soon", keyID, signature, now))
+	assert.Equal(t, response.ErrInvalidToken, signer.Verify("output/a.jpg", expires, "k2", signature, now))
+	assert.Equal(t, response.ErrInvalidToken, signer.Verify("output/a.jpg", expires, keyID, strings.ToUpper(signature), now))
+	assert.Equal(t, response.ErrMissingToken, signer.Verify("output/a.jpg", "", keyID, signature, now))
+	assert.Equal(t, response.ErrMissingToken, signer.Verify("output/a.jpg", expires, "", signature, now))
+	assert.Equal(t, response.ErrMissingToken, signer.Verify("output/a.jpg", expires, keyID, "", now))
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	before, err := NewURLSigner(map[string][]byte{"k1": oldSecret}, "k1", time.Hour)
+	assert.NoError(t, err)
+	oldLink := before.Sign("output/a.jpg", now)
+
+	// k2 is active, k1 is kept until its links expired
+	rotated, err := NewURLSigner(map[string][]byte{"k1": oldSecret, "k2": newSecret}, "k2", time.Hour)
+	assert.NoError(t, err)
+	newLink := rotated.Sign("output/a.jpg", now)
+	assert.Equal(t, "k2", newLink.Get(QueryKeyID))
+	assert.NoError(t, rotated.Verify("output/a.jpg", oldLink.Get(QueryExpires), oldLink.Get(QueryKeyID), oldLink.Get(QuerySignature), now))
+	assert.NoError(t, rotated.Verify("output/a.jpg", newLink.Get(QueryExpires), newLink.Get(QueryKeyID), newLink.Get(QuerySignature), now))
+
+	// a link of k1 presented as k2 does not verify
+	assert.Equal(t, response.ErrInvalidToken, rotated.Verify("output/a.jpg", oldLink.Get(QueryExpires), "k2", oldLink.Get(QuerySignature), now))
+
+	// once k1 is dropped its links stop working
+	after, err := NewURLSigner(map[string][]byte{"k2": newSecret}, "k2", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, response.ErrInvalidToken, after.Verify("output/a.jpg", oldLink.Get(QueryExpires), oldLink.Get(QueryKeyID), oldLink.Get(QuerySignature), now))
+	assert.NoError(t, after.Verify("output/a.jpg", newLink.Get(QueryExpires), newLink.Get(QueryKeyID), newLink.Get(QuerySignature), now))
+}
+
+func TestVerifyToken(t *testing.T) {
+	before, err := NewURLSigner(map[string][]byte{"k1": oldSecret}, "k1", time.Hour)
+	assert.NoError(t, err)
+	token := before.Token("image\nalice\nabc")
+
+	assert.NoError(t, before.VerifyToken("image\nalice\nabc", token))
+	assert.Equal(t, response.ErrMissingToken, before.VerifyToken("image\nalice\nabc", ""))
+	assert.Equal(t, response.ErrInvalidToken, before.VerifyToken("image\nbob\nabc", token))
+	assert.Equal(t, response.ErrInvalidToken, before.VerifyToken("image\nalice\nabc", "k1"))
+	assert.Equal(t, response.ErrInvalidToken, before.VerifyToken("image\nalice\nabc", "k9."+token[3:]))
+
+	// a token is no link signature for the same value
+	query := before.Sign("image\nalice\nabc", time.Now())
+	assert.NotEqual(t, query.Get(QuerySignature), token[3:])
+
+	rotated, err := NewURLSigner(map[string][]byte{"k1": oldSecret, "k2": newSecret}, "k2", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, rotated.VerifyToken("image\nalice\nabc", token))
+
+	after, err := NewURLSigner(map[string][]byte{"k2": newSecret}, "k2", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, response.ErrInvalidToken, after.VerifyToken("image\nalice\nabc", token))
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" k1:a:b | k2:c |")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"k1": []byte("a:b"), "k2": []byte("c")}, keys)
+
+	_, err = ParseKeys("k1")
+	assert.Error(t, err)
+	_, err = ParseKeys(":secret")
+	assert.Error(t, err)
+
+	_, err = NewURLSigner(keys, "k3", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestValidateSecret(t *testing.T) {
+	assert.NoError(t, ValidateSecret("webhookSecret", oldSecret))
+	assert.Error(t, ValidateSecret("webhookSecret", nil))
+	assert.Error(t, ValidateSecret("webhookSecret", []byte("short")))
+	assert.Error(t, ValidateSecret("webhookSecret", []byte(placeholderSecret)))
+}
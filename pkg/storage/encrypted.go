@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	// encryptedMagic starts every encrypted object, objects without it are
+	// read as plain so storage written before encryption was enabled keeps working
+	encryptedMagic = "ITAENC1\n"
+	// encryptedChunkSize is the plaintext size sealed at a time, downloads
+	// are decrypted chunk by chunk instead of buffering the whole object
+	encryptedChunkSize = 64 << 10
+	gcmNonceSize       = 12
+	gcmTagSize         = 16
+	wrappedKeySize     = gcmNonceSize + encryptionKeySize + gcmTagSize
+	// maxHeaderSize is the longest header, a key id has at most 255 bytes
+	maxHeaderSize = len(encryptedMagic) + 1 + 255 + wrappedKeySize + gcmNonceSize
+)
+
+// ErrCorruptObject is returned when an encrypted object fails authentication
+var ErrCorruptObject = errors.New("storage: encrypted object is corrupt or truncated")
+
+// EncryptedStorage encrypts objects of the wrapped storage with AES-256-GCM
+// envelope encryption. Every object gets its own random data key which is
+// sealed by the active key of the keyring and kept in the object header:
+//
+//	magic | key id length | key id | wrapped data key | base nonce | chunks
+//
+// Chunks are sealed separately, the last one is always shorter than a full
+// chunk and authenticated as final so truncation is detected.
+type EncryptedStorage struct {
+	Storage
+	keyring *Keyring
+}
+
+// ReencryptStats reports what Reencrypt did
+type ReencryptStats struct {
+	Scanned     int `json:"scanned"`
+	Reencrypted int `json:"reencrypted"`
+}
+
+func NewEncryptedStorage(inner Storage, keyring *Keyring) *EncryptedStorage {
+	return &EncryptedStorage{
+		Storage: inner,
+		keyring: keyring,
+	}
+}
+
+type encryptedHeader struct {
+	keyID     string
+	dataKey   []byte
+	baseNonce []byte
+	size      int
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce derives the nonce of chunk index from the random base nonce of the object
+func chunkNonce(baseNonce []byte, index uint64) []byte {
+	nonce := append([]byte(nil), baseNonce...)
+	counter := binary.BigEndian.Uint64(nonce[gcmNonceSize-8:]) ^ index
+	binary.BigEndian.PutUint64(nonce[gcmNonceSize-8:], counter)
+	return nonce
+}
+
+func chunkAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func (e *EncryptedStorage) newHeader() ([]byte, *encryptedHeader, error) {
+	keyID := e.keyring.activeKeyID
+	masterKey, err := e.keyring.key(keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	random := make([]byte, encryptionKeySize+gcmNonceSize*2)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return nil, nil, err
+	}
+	dataKey := random[:encryptionKeySize]
+	wrapNonce := random[encryptionKeySize : encryptionKeySize+gcmNonceSize]
+	baseNonce := random[encryptionKeySize+gcmNonceSize:]
+
+	var header bytes.Buffer
+	header.WriteString(encryptedMagic)
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+
+	wrapper, err := newGCM(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Write(wrapNonce)
+	header.Write(wrapper.Seal(nil, wrapNonce, dataKey, header.Bytes()[:len(encryptedMagic)+1+len(keyID)]))
+	header.Write(baseNonce)
+
+	return header.Bytes(), &encryptedHeader{keyID: keyID, dataKey: dataKey, baseNonce: baseNonce, size: header.Len()}, nil
+}
+
+// readHeader parses the header at the start of reader, a nil header means a plain object
+func (e *EncryptedStorage) readHeader(reader *bufio.Reader) (*encryptedHeader, error) {
+	magic, err := reader.Peek(len(encryptedMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(magic) != encryptedMagic {
+		return nil, nil
+	}
+
+	prefix := make([]byte, len(encryptedMagic)+1)
+	if _, err := io.ReadFull(reader, prefix); err != nil {
+		return nil, ErrCorruptObject
+	}
+	keyID := make([]byte, prefix[len(encryptedMagic)])
+	wrapped := make([]byte, wrappedKeySize)
+	baseNonce := make([]byte, gcmNonceSize)
+	for _, field := range [][]byte{keyID, wrapped, baseNonce} {
+		if _, err := io.ReadFull(reader, field); err != nil {
+			return nil, ErrCorruptObject
+		}
+	}
+
+	masterKey, err := e.keyring.key(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	wrapper, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := wrapper.Open(nil, wrapped[:gcmNonceSize], wrapped[gcmNonceSize:], append(prefix, keyID...))
+	if err != nil {
+		return nil, ErrCorruptObject
+	}
+
+	return &encryptedHeader{
+		keyID:     string(keyID),
+		dataKey:   dataKey,
+		baseNonce: baseNonce,
+		size:      len(prefix) + len(keyID) + wrappedKeySize + gcmNonceSize,
+	}, nil
+}
+
+// encryptReader seals the plaintext of source chunk by chunk as it is read
+type encryptReader struct {
+	source    io.Reader
+	aead      cipher.AEAD
+	baseNonce []byte
+	index     uint64
+	plain     []byte
+	pending   []byte
+	done      bool
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.source, r.plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		final := n < len(r.plain)
+		r.pending = r.aead.Seal(r.pending[:0], chunkNonce(r.baseNonce, r.index), r.plain[:n], chunkAdditionalData(final))
+		r.index++
+		r.done = final
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// decryptReader opens the chunks written by encryptReader as they are read
+type decryptReader struct {
+	source    io.Reader
+	closer    io.Closer
+	aead      cipher.AEAD
+	baseNonce []byte
+	index     uint64
+	sealed    []byte
+	pending   []byte
+	done      bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.source, r.sealed)
+		if err == io.EOF {
+			return 0, ErrCorruptObject
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		final := n < len(r.sealed)
+		r.pending, err = r.aead.Open(r.pending[:0], chunkNonce(r.baseNonce, r.index), r.sealed[:n], chunkAdditionalData(final))
+		if err != nil {
+			return 0, ErrCorruptObject
+		}
+		r.index++
+		r.done = final
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
+
+// plainReader serves an object written before encryption was enabled
+type plainReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (e *EncryptedStorage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	header, encryption, err := e.newHeader()
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(encryption.dataKey)
+	if err != nil {
+		return err
+	}
+
+	return e.Storage.Put(ctx, key, io.MultiReader(bytes.NewReader(header), &encryptReader{
+		source:    reader,
+		aead:      aead,
+		baseNonce: encryption.baseNonce,
+		plain:     make([]byte, encryptedChunkSize),
+	}), contentType)
+}
+
+func (e *EncryptedStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, err := e.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(content)
+	header, err := e.readHeader(buffered)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	if header == nil {
+		return plainReader{Reader: buffered, Closer: content}, nil
+	}
+
+	aead, err := newGCM(header.dataKey)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	return &decryptReader{
+		source:    buffered,
+		closer:    content,
+		aead:      aead,
+		baseNonce: header.baseNonce,
+		sealed:    make([]byte, encryptedChunkSize+gcmTagSize),
+	}, nil
+}
+
+// Stat reports the plaintext size, only the header is read to know its length.
+// List keeps reporting the stored size, which is what retention budgets count.
+func (e *EncryptedStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := e.Storage.Stat(ctx, key)
+	if err != nil {
+		return info, err
+	}
+
+	header, err := e.headerOf(ctx, key)
+	if err != nil || header == nil {
+		return info, err
+	}
+
+	body := info.Size - int64(header.size)
+	chunks := body/(encryptedChunkSize+gcmTagSize) + 1
+	info.Size = body - chunks*gcmTagSize
+	return info, nil
+}
+
+// headerOf reads no more than the header of the object, with a range read
+// when the wrapped storage supports it
+func (e *EncryptedStorage) headerOf(ctx context.Context, key string) (*encryptedHeader, error) {
+	var content io.ReadCloser
+	var err error
+	if ranged, ok := e.Storage.(RangeGetter); ok {
+		content, err = ranged.GetRange(ctx, key, 0, int64(maxHeaderSize))
+	} else {
+		content, err = e.Storage.Get(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return e.readHeader(bufio.NewReaderSize(io.LimitReader(content, int64(maxHeaderSize)), maxHeaderSize))
+}
+
+// Reencrypt rewrites every plain object and every object sealed by a key
+// other than the active one, run it after adding a new active key to the keyfile.
+func (e *EncryptedStorage) Reencrypt(ctx context.Context) (ReencryptStats, error) {
+	var stats ReencryptStats
+
+	objects, err := e.Storage.List(ctx, "")
+	if err != nil {
+		return stats, err
+	}
+
+	for _, object := range objects {
+		if strings.HasPrefix(path.Base(object.Key), TempFilePrefix) {
+			continue
+		}
+		stats.Scanned++
+
+		header, err := e.headerOf(ctx, object.Key)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		if header != nil && header.keyID == e.keyring.activeKeyID {
+			continue
+		}
+
+		// not every backend lists the content type, s3 only answers it per object
+		contentType := object.ContentType
+		if contentType == "" {
+			info, err := e.Storage.Stat(ctx, object.Key)
+			if errors.Is(err, ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return stats, err
+			}
+			contentType = info.ContentType
+		}
+
+		content, err := e.Get(ctx, object.Key)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		err = e.Put(ctx, object.Key, content, contentType)
+		content.Close()
+		if err != nil {
+			return stats, err
+		}
+		stats.Reencrypted++
+	}
+
+	return stats, nil
+}
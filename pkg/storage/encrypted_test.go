@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(seed byte) []byte {
+	return bytes.Repeat([]byte{seed}, encryptionKeySize)
+}
+
+func newTestEncryptedStorage(t *testing.T, inner Storage, keys map[string][]byte, activeKeyID string) *EncryptedStorage {
+	keyring, err := NewKeyring(keys, activeKeyID)
+	assert.NoError(t, err)
+	return NewEncryptedStorage(inner, keyring)
+}
+
+func payloadOf(size int) []byte {
+	payload := make([]byte, size)
+	for index := range payload {
+		payload[index] = byte(index*7 + index/251)
+	}
+	return payload
+}
+
+func readAll(ctx context.Context, objectStorage Storage, key string) ([]byte, error) {
+	reader, err := objectStorage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestEncryptedStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage("/files")
+	encrypted := newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1)}, "k1")
+
+	sizes := []int{0, 1, encryptedChunkSize - 1, encryptedChunkSize, encryptedChunkSize + 1, 2*encryptedChunkSize + 5}
+	for _, size := range sizes {
+		payload := payloadOf(size)
+		assert.NoError(t, encrypted.Put(ctx, "object", bytes.NewReader(payload), "image/jpeg"))
+
+		data, err := readAll(ctx, encrypted, "object")
+		assert.NoError(t, err, "size %d", size)
+		assert.Equal(t, len(payload), len(data), "size %d", size)
+		assert.True(t, bytes.Equal(payload, data), "size %d", size)
+
+		info, err := encrypted.Stat(ctx, "object")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(size), info.Size, "size %d", size)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+
+		stored, err := readAll(ctx, inner, "object")
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(stored, []byte(encryptedMagic)))
+		if size >= 64 {
+			assert.False(t, bytes.Contains(stored, payload[:64]), "size %d", size)
+		}
+	}
+}
+
+func TestEncryptedStoragePlainObject(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage("/files")
+	encrypted := newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1)}, "k1")
+
+	assert.NoError(t, inner.Put(ctx, "plain", bytes.NewReader([]byte("plain jpeg")), "image/jpeg"))
+	assert.NoError(t, inner.Put(ctx, "short", bytes.NewReader([]byte("ab")), "image/jpeg"))
+
+	data, err := readAll(ctx, encrypted, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain jpeg", string(data))
+
+	info, err := encrypted.Stat(ctx, "short")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), info.Size)
+
+	_, err = encrypted.Stat(ctx, "missing")
+	assert.Equal(t, ErrObjectNotFound, err)
+}
+
+func TestEncryptedStorageTampering(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage("/files")
+	encrypted := newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1)}, "k1")
+
+	payload := payloadOf(3*encryptedChunkSize + 100)
+	assert.NoError(t, encrypted.Put(ctx, "object", bytes.NewReader(payload), "image/jpeg"))
+	stored, err := readAll(ctx, inner, "object")
+	assert.NoError(t, err)
+
+	headerSize := len(encryptedMagic) + 1 + len("k1") + wrappedKeySize + gcmNonceSize
+	sealedChunk := encryptedChunkSize + gcmTagSize
+	chunk := func(index int) []byte {
+		return stored[headerSize+index*sealedChunk : headerSize+(index+1)*sealedChunk]
+	}
+
+	var reordered []byte
+	reordered = append(reordered, stored[:headerSize]...)
+	reordered = append(reordered, chunk(1)...)
+	reordered = append(reordered, chunk(0)...)
+	reordered = append(reordered, stored[headerSize+2*sealedChunk:]...)
+
+	flipped := append([]byte(nil), stored...)
+	flipped[headerSize+10] ^= 1
+
+	tests := []struct {
+		name   string
+		stored []byte
+	}{
+		{name: "last byte cut", stored: stored[:len(stored)-1]},
+		{name: "final chunk dropped", stored: stored[:headerSize+3*sealedChunk]},
+		{name: "cut on a chunk boundary", stored: stored[:headerSize+sealedChunk]},
+		{name: "only the header", stored: stored[:headerSize]},
+		{name: "chunks reordered", stored: reordered},
+		{name: "bit flipped", stored: flipped},
+		{name: "header cut", stored: stored[:headerSize-1]},
+	}
+
+	for _, test := range tests {
+		assert.NoError(t, inner.Put(ctx, "tampered", bytes.NewReader(test.stored), "image/jpeg"))
+		_, err := readAll(ctx, encrypted, "tampered")
+		assert.True(t, errors.Is(err, ErrCorruptObject), "%s: %v", test.name, err)
+	}
+}
+
+func TestEncryptedStorageKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage("/files")
+	payload := payloadOf(encryptedChunkSize + 10)
+
+	before := newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1)}, "k1")
+	assert.NoError(t, before.Put(ctx, "old", bytes.NewReader(payload), "image/jpeg"))
+	assert.NoError(t, inner.Put(ctx, "plain", bytes.NewReader([]byte("plain")), "image/jpeg"))
+
+	// the new key is active, the old one still decrypts
+	rotating := newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+	data, err := readAll(ctx, rotating, "old")
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(payload, data))
+	assert.NoError(t, rotating.Put(ctx, "new", bytes.NewReader([]byte("new")), "image/jpeg"))
+
+	// once the old key is removed its objects can not be read anymore
+	rotatedOut := newTestEncryptedStorage(t, inner, map[string][]byte{"k2": testKey(2)}, "k2")
+	_, err = readAll(ctx, rotatedOut, "old")
+	assert.EqualError(t, err, `storage: unknown encryption key "k1"`)
+	_, err = rotatedOut.Stat(ctx, "old")
+	assert.Error(t, err)
+
+	stats, err := rotating.Reencrypt(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ReencryptStats{Scanned: 3, Reencrypted: 2}, stats)
+
+	for key, expected := range map[string][]byte{"old": payload, "new": []byte("new"), "plain": []byte("plain")} {
+		data, err := readAll(ctx, rotatedOut, key)
+		assert.NoError(t, err, key)
+		assert.True(t, bytes.Equal(expected, data), key)
+	}
+
+	stats, err = rotatedOut.Reencrypt(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ReencryptStats{Scanned: 3, Reencrypted: 0}, stats)
+}
+
+// countingStorage counts the bytes read through Get
+type countingStorage struct {
+	Storage
+	read int64
+}
+
+type countingReader struct {
+	io.ReadCloser
+	storage *countingStorage
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.storage.read += int64(n)
+	return n, err
+}
+
+func (c *countingStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := c.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return countingReader{ReadCloser: reader, storage: c}, nil
+}
+
+// rangedStorage serves only range reads
+type rangedStorage struct {
+	Storage
+	ranges [][2]int64
+}
+
+func (r *rangedStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, errors.New("full read")
+}
+
+func (r *rangedStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	r.ranges = append(r.ranges, [2]int64{offset, length})
+	data, err := readAll(ctx, r.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data[offset:minInt(len(data), int(offset+length))])), nil
+}
+
+func TestEncryptedStorageStatReadsOnlyTheHeader(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStorage("/files")
+	payload := payloadOf(4 * encryptedChunkSize)
+	assert.NoError(t, newTestEncryptedStorage(t, inner, map[string][]byte{"k1": testKey(1)}, "k1").
+		Put(ctx, "object", bytes.NewReader(payload), "image/jpeg"))
+
+	counting := &countingStorage{Storage: inner}
+	info, err := newTestEncryptedStorage(t, counting, map[string][]byte{"k1": testKey(1)}, "k1").Stat(ctx, "object")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), info.Size)
+	assert.True(t, counting.read <= int64(maxHeaderSize), "read %d bytes", counting.read)
+
+	ranged := &rangedStorage{Storage: inner}
+	info, err = newTestEncryptedStorage(t, ranged, map[string][]byte{"k1": testKey(1)}, "k1").Stat(ctx, "object")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), info.Size)
+	assert.Equal(t, [][2]int64{{0, int64(maxHeaderSize)}}, ranged.ranges)
+}
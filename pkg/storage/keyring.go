@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptionKeySize is the AES-256 key size every keyfile entry must have
+const encryptionKeySize = 32
+
+// Keyring holds the key encryption keys by id, new objects are always
+// encrypted with the active key while every key can still decrypt.
+type Keyring struct {
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+// LoadKeyFile reads one "id:base64-key" per line, blank lines and lines
+// starting with # are ignored. A key can be generated with `openssl rand -base64 32`.
+func LoadKeyFile(filePath, activeKeyID string) (*Keyring, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := map[string][]byte{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || len(parts[0]) > 255 {
+			return nil, fmt.Errorf("storage: keyfile line %d, expected id:base64-key", line)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+		if err != nil || len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("storage: keyfile line %d, key %q must be %d base64 encoded bytes", line, parts[0], encryptionKeySize)
+		}
+		keys[parts[0]] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewKeyring(keys, activeKeyID)
+}
+
+func NewKeyring(keys map[string][]byte, activeKeyID string) (*Keyring, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("storage: active encryption key %q is not configured", activeKeyID)
+	}
+	return &Keyring{
+		keys:        keys,
+		activeKeyID: activeKeyID,
+	}, nil
+}
+
+func (k *Keyring) key(keyID string) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown encryption key %q", keyID)
+	}
+	return key, nil
+}
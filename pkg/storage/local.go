@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TempFilePrefix marks objects which are still being written
+const TempFilePrefix = ".tmp-"
+
+type localStorage struct {
+	rootDir   string
+	urlPrefix string
+}
+
+// NewLocalStorage stores objects under rootDir, urlPrefix is the path the
+// directory is served from, e.g. "/external/storage".
+func NewLocalStorage(rootDir, urlPrefix string) (Storage, error) {
+	if err := os.MkdirAll(rootDir, 0755); err != nil {
+		return nil, err
+	}
+	return &localStorage{
+		rootDir:   rootDir,
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+	}, nil
+}
+
+func (l *localStorage) path(key string) string {
+	// path.Clean on a rooted key keeps "../" from escaping rootDir
+	return filepath.Join(l.rootDir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (l *localStorage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	filePath := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// write to a temp file in the same directory and rename it once complete,
+	// so a failed or interrupted write never leaves a half written object behind
+	file, err := ioutil.TempFile(filepath.Dir(filePath), TempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+
+	_, err = io.Copy(file, reader)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return syncDir(filepath.Dir(filePath))
+}
+
+// syncDir flushes the directory entry of a rename to disk
+func syncDir(dir string) error {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+
+	// some platforms can not fsync a directory, the rename itself already happened
+	directory.Sync()
+	return nil
+}
+
+func (l *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+	return file, err
+}
+
+func (l *localStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		return ErrObjectNotFound
+	}
+	return err
+}
+
+func (l *localStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := os.Stat(l.path(key))
+	if os.IsNotExist(err) {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (l *localStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var result []ObjectInfo
+	err := filepath.Walk(l.rootDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(l.rootDir, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		result = append(result, ObjectInfo{
+			Key:         key,
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			ModTime:     info.ModTime(),
+		})
+		return nil
+	})
+	return result, err
+}
+
+func (l *localStorage) URL(key string) string {
+	return l.urlPrefix + "/" + strings.TrimPrefix(key, "/")
+}
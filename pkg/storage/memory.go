@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+type memoryStorage struct {
+	mutex     sync.RWMutex
+	objects   map[string]memoryObject
+	urlPrefix string
+}
+
+// NewMemoryStorage keeps objects in process memory, it is meant for tests and
+// local runs where nothing has to survive a restart.
+func NewMemoryStorage(urlPrefix string) Storage {
+	return &memoryStorage{
+		objects:   map[string]memoryObject{},
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+	}
+}
+
+func (m *memoryStorage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[key] = memoryObject{data: data, contentType: contentType, modTime: time.Now()}
+	return nil
+}
+
+func (m *memoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return ErrObjectNotFound
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	return ObjectInfo{
+		Key:         key,
+		Size:        int64(len(object.data)),
+		ContentType: object.contentType,
+		ModTime:     object.modTime,
+	}, nil
+}
+
+func (m *memoryStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	var result []ObjectInfo
+	for key, object := range m.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		result = append(result, ObjectInfo{
+			Key:         key,
+			Size:        int64(len(object.data)),
+			ContentType: object.contentType,
+			ModTime:     object.modTime,
+		})
+	}
+	return result, nil
+}
+
+func (m *memoryStorage) URL(key string) string {
+	return m.urlPrefix + "/" + strings.TrimPrefix(key, "/")
+}
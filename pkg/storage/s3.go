@@ -0,0 +1,357 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+	s3DefaultRegion = "us-east-1"
+)
+
+type S3Config struct {
+	// Endpoint is the scheme and host of the S3 compatible API, e.g. http://localhost:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as endpoint/bucket instead of bucket.endpoint, MinIO needs it
+	PathStyle bool
+	// PublicUrl is the base url objects are served from, defaults to the bucket url
+	PublicUrl string
+	Timeout   time.Duration
+}
+
+type s3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Storage talks to any S3 compatible API (AWS S3, MinIO, ...) with
+// signature version 4 over plain net/http.
+func NewS3Storage(config S3Config) (Storage, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("storage: s3 bucket is required")
+	}
+	if config.Region == "" {
+		config.Region = s3DefaultRegion
+	}
+
+	return &s3Storage{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+func (s *s3Storage) objectURL(key string) *url.URL {
+	objectURL := *s.endpoint
+	escapedKey := s3EscapePath(strings.TrimPrefix(key, "/"))
+	if s.config.PathStyle {
+		objectURL.Path = "/" + s.config.Bucket + "/" + strings.TrimPrefix(key, "/")
+		objectURL.RawPath = "/" + s3EscapePath(s.config.Bucket) + "/" + escapedKey
+	} else {
+		objectURL.Host = s.config.Bucket + "." + s.endpoint.Host
+		objectURL.Path = "/" + strings.TrimPrefix(key, "/")
+		objectURL.RawPath = "/" + escapedKey
+	}
+	return &objectURL
+}
+
+func (s *s3Storage) do(ctx context.Context, method string, requestURL *url.URL, body []byte, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.ContentLength = int64(len(body))
+	s.sign(request, body, time.Now().UTC())
+
+	return s.client.Do(request)
+}
+
+// sign adds the AWS signature version 4 authorization header
+func (s *s3Storage) sign(request *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHex)
+
+	var signedHeaders []string
+	canonicalHeaders := map[string]string{}
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		if lower == "host" || lower == "content-type" || lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			signedHeaders = append(signedHeaders, lower)
+			canonicalHeaders[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	sort.Strings(signedHeaders)
+
+	var headerLines strings.Builder
+	for _, name := range signedHeaders {
+		headerLines.WriteString(name + ":" + canonicalHeaders[name] + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		s3CanonicalQuery(request.URL.Query()),
+		headerLines.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHex,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(s3DateFormat), s.config.Region, s3Service, "aws4_request"}, "/")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	signingKey := s3HMAC([]byte("AWS4"+s.config.SecretKey), now.Format(s3DateFormat))
+	signingKey = s3HMAC(signingKey, s.config.Region)
+	signingKey = s3HMAC(signingKey, s3Service)
+	signingKey = s3HMAC(signingKey, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return s3CheckResponse(resp)
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil, http.Header{})
+	if err != nil {
+		return nil, err
+	}
+	if err := s3CheckResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Storage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil, header)
+	if err != nil {
+		return nil, err
+	}
+	// an object shorter than offset has nothing in the range
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if err := s3CheckResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	// S3 answers 204 for missing keys too, stat first to keep the local semantics
+	if _, err := s.Stat(ctx, key); err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil, http.Header{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return s3CheckResponse(resp)
+}
+
+func (s *s3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(key), nil, http.Header{})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := s3CheckResponse(resp); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return ObjectInfo{
+		Key:         key,
+		Size:        size,
+		ContentType: resp.Header.Get("Content-Type"),
+		ModTime:     modTime,
+	}, nil
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var result []ObjectInfo
+	continuationToken := ""
+	for {
+		listURL := s.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		listURL.RawQuery = s3CanonicalQuery(query)
+
+		resp, err := s.do(ctx, http.MethodGet, listURL, nil, http.Header{})
+		if err != nil {
+			return nil, err
+		}
+		if err := s3CheckResponse(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page s3ListBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range page.Contents {
+			result = append(result, ObjectInfo{
+				Key:     content.Key,
+				Size:    content.Size,
+				ModTime: content.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return result, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+func (s *s3Storage) URL(key string) string {
+	if s.config.PublicUrl != "" {
+		return strings.TrimSuffix(s.config.PublicUrl, "/") + "/" + s3EscapePath(strings.TrimPrefix(key, "/"))
+	}
+	return s.objectURL(key).String()
+}
+
+func s3CheckResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrObjectNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage: s3 responded %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath escapes every path segment the way signature version 4 expects
+func s3EscapePath(value string) string {
+	segments := strings.Split(value, "/")
+	for index, segment := range segments {
+		segments[index] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3Escape(value string) string {
+	var builder strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' {
+			builder.WriteByte(b)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%%%02X", b))
+	}
+	return builder.String()
+}
+
+func s3CanonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		sortedValues := append([]string(nil), values[key]...)
+		sort.Strings(sortedValues)
+		for _, value := range sortedValues {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	DriverLocal  = "local"
+	DriverS3     = "s3"
+	DriverMemory = "memory"
+)
+
+// ErrObjectNotFound is returned when the key does not exist in the storage
+var ErrObjectNotFound = errors.New("storage object not found")
+
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage is our contract for where images are persisted, every key is a
+// slash separated relative path such as "2022/abc-input.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, reader io.Reader, contentType string) error
+
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	Delete(ctx context.Context, key string) error
+
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+
+	// List returns every object whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// URL returns where the object is served, either an absolute url or a
+	// path relative to the service host.
+	URL(key string) string
+}
+
+// RangeGetter is implemented by storages which can read part of an object
+// without transferring the rest of it, such as S3 with a Range request.
+type RangeGetter interface {
+	// GetRange returns at most length bytes of the object starting at offset
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
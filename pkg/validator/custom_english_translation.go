@@ -147,4 +147,36 @@ func registerCustomEnglishTranslator(v *validatorGo.Validate, trans ut.Translato
 	}); err != nil {
 		panic(err)
 	}
+
+	if err := v.RegisterTranslation("slug", trans, func(ut ut.Translator) error {
+		if err := ut.Add("slug", "{0} must be lower case letters and digits joined by dashes, e.g. golden-hour", false); err != nil {
+			return err
+		}
+		return nil
+	}, func(ut ut.Translator, fe validatorGo.FieldError) string {
+		t, err := ut.T(fe.Tag(), fe.Field())
+		if err != nil {
+			log.Printf("warning: error translating FieldError: %#v", fe)
+			return fe.(error).Error()
+		}
+		return t
+	}); err != nil {
+		panic(err)
+	}
+
+	if err := v.RegisterTranslation("finite", trans, func(ut ut.Translator) error {
+		if err := ut.Add("finite", "{0} must be a finite number", false); err != nil {
+			return err
+		}
+		return nil
+	}, func(ut ut.Translator, fe validatorGo.FieldError) string {
+		t, err := ut.T(fe.Tag(), fe.Field())
+		if err != nil {
+			log.Printf("warning: error translating FieldError: %#v", fe)
+			return fe.(error).Error()
+		}
+		return t
+	}); err != nil {
+		panic(err)
+	}
 }
@@ -148,4 +148,35 @@ func registerCustomIndonesianTranslator(v *validatorGo.Validate, trans ut.Transl
 		panic(err)
 	}
 
+	if err := v.RegisterTranslation("slug", trans, func(ut ut.Translator) error {
+		if err := ut.Add("slug", "{0} harus berupa huruf kecil dan angka yang dipisahkan tanda hubung, contoh golden-hour", false); err != nil {
+			return err
+		}
+		return nil
+	}, func(ut ut.Translator, fe validatorGo.FieldError) string {
+		t, err := ut.T(fe.Tag(), fe.Field())
+		if err != nil {
+			log.Printf("warning: error translating FieldError: %#v", fe)
+			return fe.(error).Error()
+		}
+		return t
+	}); err != nil {
+		panic(err)
+	}
+
+	if err := v.RegisterTranslation("finite", trans, func(ut ut.Translator) error {
+		if err := ut.Add("finite", "{0} harus berupa angka yang terhingga", false); err != nil {
+			return err
+		}
+		return nil
+	}, func(ut ut.Translator, fe validatorGo.FieldError) string {
+		t, err := ut.T(fe.Tag(), fe.Field())
+		if err != nil {
+			log.Printf("warning: error translating FieldError: %#v", fe)
+			return fe.(error).Error()
+		}
+		return t
+	}); err != nil {
+		panic(err)
+	}
 }
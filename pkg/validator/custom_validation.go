@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -29,6 +30,12 @@ func registerCustomValidation(db *gorm.DB, v *validatorGo.Validate) {
 	if err := v.RegisterValidation("no_space", ValidateNoSpace); err != nil {
 		panic(err)
 	}
+	if err := v.RegisterValidation("slug", ValidateSlug); err != nil {
+		panic(err)
+	}
+	if err := v.RegisterValidation("finite", ValidateFinite); err != nil {
+		panic(err)
+	}
 	if err := v.RegisterValidation("check_fk", func(fl validatorGo.FieldLevel) bool {
 		param := strings.Split(fl.Param(), `:`)
 		paramFieldValue := param[0]
@@ -192,6 +199,19 @@ func ValidateNoSpace(field validatorGo.FieldLevel) bool {
 	return true
 }
 
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidateSlug accepts lower case words of letters and digits joined by single dashes, e.g. golden-hour
+func ValidateSlug(field validatorGo.FieldLevel) bool {
+	return slugRegex.MatchString(field.Field().String())
+}
+
+// ValidateFinite rejects NaN and infinite floats, which pass min and max as every comparison with them is false
+func ValidateFinite(field validatorGo.FieldLevel) bool {
+	value := field.Field().Float()
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
 func requireCheckFieldKind(fl validatorGo.FieldLevel, param string) bool {
 	field := fl.Field()
 	if len(param) > 0 {
@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderAttempt   = "X-Webhook-Attempt"
+)
+
+type Config struct {
+	// Secret signs every payload, receivers verify it with Sign
+	Secret      string
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, it doubles up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	// DeadLetterPath receives one json line per payload that could not be delivered
+	DeadLetterPath string
+	// AllowPrivateNetworks lets callbacks reach loopback, private and link-local
+	// addresses, only meant for local development
+	AllowPrivateNetworks bool
+}
+
+// ErrForbiddenAddress is returned when a callback url resolves to an address
+// the service must not call, such as loopback, a private network or cloud metadata
+var ErrForbiddenAddress = errors.New("webhook: callback address is not allowed")
+
+// forbiddenNetworks are checked on the resolved address of every connection, so
+// a host name or a redirect pointing at them is refused as well
+var forbiddenNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // this network
+		"10.0.0.0/8",     // RFC1918
+		"100.64.0.0/10",  // carrier grade nat
+		"127.0.0.0/8",    // loopback
+		"169.254.0.0/16", // link-local, cloud metadata at 169.254.169.254
+		"172.16.0.0/12",  // RFC1918
+		"192.0.0.0/24",   // ietf protocol assignments
+		"192.168.0.0/16", // RFC1918
+		"198.18.0.0/15",  // benchmarking
+		"224.0.0.0/4",    // multicast
+		"240.0.0.0/4",    // reserved and broadcast
+		"::/128",         // unspecified
+		"::1/128",        // loopback
+		"fc00::/7",       // unique local
+		"fe80::/10",      // link-local
+		"ff00::/8",       // multicast
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// ForbiddenIP reports whether a callback must not connect to ip
+func ForbiddenIP(ip net.IP) bool {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardAddress runs after the host name was resolved and before connecting
+func guardAddress(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ForbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// Attempt is the outcome of one POST of a payload
+type Attempt struct {
+	Number     int
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	// Final is set on the last attempt, delivered or not
+	Final bool
+	// NextAttemptAt is when the next attempt is made, zero on the final attempt
+	NextAttemptAt time.Time
+}
+
+// Sender posts signed json payloads and retries with exponential backoff
+type Sender struct {
+	config     Config
+	client     *http.Client
+	deadLetter sync.Mutex
+}
+
+func NewSender(config Config) *Sender {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = guardAddress
+	}
+	// no proxy, it would be the one connecting to the guarded address
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: config.Timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Sender{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout, Transport: transport},
+	}
+}
+
+// MaxAttempts is the number of attempts made before a payload is dead lettered
+func (s *Sender) MaxAttempts() int {
+	return s.config.MaxAttempts
+}
+
+// Backoff is the wait after the failed attempt number, it doubles from InitialBackoff up to MaxBackoff
+func (s *Sender) Backoff(number int) time.Duration {
+	backoff := s.config.InitialBackoff
+	for step := 1; step < number; step++ {
+		backoff *= 2
+		if s.config.MaxBackoff > 0 && backoff > s.config.MaxBackoff {
+			return s.config.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body", sent as "sha256=<hex>" in HeaderSignature
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts body to url starting with attempt number first until a 2xx
+// answer or MaxAttempts, onAttempt is called after every attempt so the caller
+// can persist the progress and resume it later. A forbidden address is not
+// retried. A payload which is never delivered goes to the dead-letter log, a
+// delivery interrupted by ctx returns ctx.Err() and is not dead lettered.
+func (s *Sender) Deliver(ctx context.Context, url, event string, body []byte, first int, onAttempt func(Attempt)) error {
+	if first < 1 {
+		first = 1
+	}
+
+	lastErr := fmt.Errorf("webhook: no attempt left after %d", first-1)
+	for number := first; number <= s.config.MaxAttempts; number++ {
+		started := time.Now()
+		statusCode, err := s.post(ctx, url, event, number, body)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+
+		final := err == nil || number == s.config.MaxAttempts || errors.Is(err, ErrForbiddenAddress)
+		attempt := Attempt{Number: number, StatusCode: statusCode, Err: err, Duration: time.Since(started), Final: final}
+		if !final {
+			attempt.NextAttemptAt = time.Now().Add(s.Backoff(number))
+		}
+		if onAttempt != nil {
+			onAttempt(attempt)
+		}
+		if err == nil {
+			return nil
+		}
+		if final {
+			break
+		}
+
+		timer := time.NewTimer(time.Until(attempt.NextAttemptAt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if err := s.writeDeadLetter(url, event, body, lastErr); err != nil {
+		return fmt.Errorf("%v, dead letter: %v", lastErr, err)
+	}
+	return lastErr
+}
+
+func (s *Sender) post(ctx context.Context, url, event string, attempt int, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, event)
+	request.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, "sha256="+Sign(s.config.Secret, timestamp, body))
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook: %s responded %d", url, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+type deadLetter struct {
+	Time    time.Time       `json:"time"`
+	Url     string          `json:"url"`
+	Event   string          `json:"event"`
+	Error   string          `json:"error"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (s *Sender) writeDeadLetter(url, event string, body []byte, deliveryErr error) error {
+	if s.config.DeadLetterPath == "" {
+		return nil
+	}
+	message := ""
+	if deliveryErr != nil {
+		message = deliveryErr.Error()
+	}
+	line, err := json.Marshal(deadLetter{Time: time.Now(), Url: url, Event: event, Error: message, Payload: body})
+	if err != nil {
+		return err
+	}
+
+	s.deadLetter.Lock()
+	defer s.deadLetter.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.config.DeadLetterPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.config.DeadLetterPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// hmac-sha256 of "1656669600.{}" with key "secret"
+	signature := Sign("secret", 1656669600, []byte("{}"))
+	assert.Equal(t, "88c7fa40ee3aa06637a964570754fcde2e8c093ec8914adff66a790ef9d9a231", signature)
+	assert.NotEqual(t, signature, Sign("other", 1656669600, []byte("{}")))
+	assert.NotEqual(t, signature, Sign("secret", 1656669601, []byte("{}")))
+}
+
+func TestForbiddenIP(t *testing.T) {
+	for address, forbidden := range map[string]bool{
+		"127.0.0.1":              true,
+		"10.1.2.3":               true,
+		"172.16.0.1":             true,
+		"172.31.255.255":         true,
+		"192.168.1.1":            true,
+		"169.254.169.254":        true,
+		"100.64.0.1":             true,
+		"0.0.0.0":                true,
+		"::1":                    true,
+		"::":                     true,
+		"fe80::1":                true,
+		"fc00::1":                true,
+		"::ffff:127.0.0.1":       true,
+		"::ffff:169.254.169.254": true,
+		"8.8.8.8":                false,
+		"172.32.0.1":             false,
+		"2001:4860:4860::8888":   false,
+	} {
+		assert.Equal(t, forbidden, ForbiddenIP(net.ParseIP(address)), address)
+	}
+}
+
+// countingServer counts the posts it received and answers them with status
+func countingServer(t *testing.T, status func(count int64) int) (*httptest.Server, *int64) {
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		number := atomic.AddInt64(&count, 1)
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(status(number))
+	}))
+	t.Cleanup(server.Close)
+	return server, &count
+}
+
+func TestDeliverRefusesInternalAddresses(t *testing.T) {
+	server, count := countingServer(t, func(int64) int { return http.StatusOK })
+	deadLetterPath := filepath.Join(t.TempDir(), "dead_letter.log")
+	sender := NewSender(Config{Secret: "secret", MaxAttempts: 3, InitialBackoff: time.Millisecond, Timeout: time.Second, DeadLetterPath: deadLetterPath})
+
+	var attempts []Attempt
+	err := sender.Deliver(context.Background(), server.URL, "adjustment_job.done", []byte("{}"), 1, func(attempt Attempt) {
+		attempts = append(attempts, attempt)
+	})
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), err)
+	assert.Equal(t, int64(0), atomic.LoadInt64(count))
+	// a forbidden address is not retried
+	if assert.Len(t, attempts, 1) {
+		assert.True(t, attempts[0].Final)
+		assert.True(t, attempts[0].NextAttemptAt.IsZero())
+	}
+	_, statErr := os.Stat(deadLetterPath)
+	assert.NoError(t, statErr)
+
+	// a host name resolving to loopback is refused as well
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	err = sender.Deliver(context.Background(), "http://localhost:"+port, "adjustment_job.done", []byte("{}"), 1, nil)
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), err)
+	assert.Equal(t, int64(0), atomic.LoadInt64(count))
+}
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	var headers []http.Header
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		assert.Equal(t, "sha256="+Sign("secret", timestamp, body), r.Header.Get(HeaderSignature))
+		headers = append(headers, r.Header.Clone())
+		if atomic.AddInt64(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	sender := NewSender(Config{Secret: "secret", MaxAttempts: 5, InitialBackoff: 5 * time.Millisecond, Timeout: time.Second, AllowPrivateNetworks: true})
+
+	var attempts []Attempt
+	err := sender.Deliver(context.Background(), server.URL, "adjustment_job.done", []byte(`{"job_id":"1"}`), 1, func(attempt Attempt) {
+		attempts = append(attempts, attempt)
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, attempts, 3) {
+		assert.Equal(t, http.StatusServiceUnavailable, attempts[0].StatusCode)
+		assert.False(t, attempts[0].Final)
+		assert.False(t, attempts[0].NextAttemptAt.IsZero())
+		assert.Equal(t, http.StatusNoContent, attempts[2].StatusCode)
+		assert.True(t, attempts[2].Final)
+	}
+	if assert.Len(t, headers, 3) {
+		assert.Equal(t, "adjustment_job.done", headers[0].Get(HeaderEvent))
+		assert.Equal(t, []string{"1", "2", "3"}, []string{headers[0].Get(HeaderAttempt), headers[1].Get(HeaderAttempt), headers[2].Get(HeaderAttempt)})
+	}
+}
+
+func TestDeliverResumesFromAttempt(t *testing.T) {
+	server, count := countingServer(t, func(int64) int { return http.StatusInternalServerError })
+	deadLetterPath := filepath.Join(t.TempDir(), "dead_letter.log")
+	sender := NewSender(Config{Secret: "secret", MaxAttempts: 4, InitialBackoff: time.Millisecond, Timeout: time.Second, DeadLetterPath: deadLetterPath, AllowPrivateNetworks: true})
+
+	var numbers []int
+	err := sender.Deliver(context.Background(), server.URL, "adjustment_job.failed", []byte(`{"job_id":"1"}`), 3, func(attempt Attempt) {
+		numbers = append(numbers, attempt.Number)
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []int{3, 4}, numbers)
+	assert.Equal(t, int64(2), atomic.LoadInt64(count))
+
+	file, err := os.Open(deadLetterPath)
+	if assert.NoError(t, err) {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		assert.True(t, scanner.Scan())
+		var line deadLetter
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		assert.Equal(t, server.URL, line.Url)
+		assert.Equal(t, "adjustment_job.failed", line.Event)
+		assert.JSONEq(t, `{"job_id":"1"}`, string(line.Payload))
+		assert.False(t, scanner.Scan())
+	}
+}
+
+func TestDeliverStopsWithoutDeadLetter(t *testing.T) {
+	server, _ := countingServer(t, func(int64) int { return http.StatusInternalServerError })
+	deadLetterPath := filepath.Join(t.TempDir(), "dead_letter.log")
+	sender := NewSender(Config{Secret: "secret", MaxAttempts: 3, InitialBackoff: time.Hour, Timeout: time.Second, DeadLetterPath: deadLetterPath, AllowPrivateNetworks: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sender.Deliver(ctx, server.URL, "adjustment_job.done", []byte("{}"), 1, func(attempt Attempt) {
+		// stop while waiting for the second attempt
+		cancel()
+	})
+	assert.Equal(t, context.Canceled, err)
+	_, statErr := os.Stat(deadLetterPath)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestBackoff(t *testing.T) {
+	sender := NewSender(Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	assert.Equal(t, time.Second, sender.Backoff(1))
+	assert.Equal(t, 2*time.Second, sender.Backoff(2))
+	assert.Equal(t, 4*time.Second, sender.Backoff(3))
+	assert.Equal(t, 5*time.Second, sender.Backoff(4))
+	assert.Equal(t, 1, sender.MaxAttempts())
+}
@@ -26,15 +26,2670 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/v1/admin/storage/janitor": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "StorageJanitorStats reports the runs of the storage retention janitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/files/{key}": {
+            "get": {
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "ImageAdjustment"
+                ],
+                "summary": "DownloadFile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "stored input or output key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "expiry unix time of the link",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the signing key",
+                        "name": "kid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "link signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.UnauthorizedResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/image_adjustment/batch": {
+            "post": {
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "ImageAdjustment"
+                ],
+                "summary": "ImageAdjustmentBatch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "zip archive of jpeg images",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "adjustment_temperature, required without preset",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of a preset filling in every parameter not sent with the request",
+                        "name": "preset",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max size of output jpeg in bytes, quality is lowered to fit",
+                        "name": "max_output_bytes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes",
+                        "name": "allow_downscale",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output width in pixels",
+                        "name": "width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output height in pixels",
+                        "name": "height",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "fit = contain, cover, fill or inside (default inside)",
+                        "name": "fit",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "interpolation = lanczos3 or bilinear (default lanczos3)",
+                        "name": "interpolation",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "clockwise rotation in degrees, applied first",
+                        "name": "rotate",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "background fill for arbitrary rotation, hex e.g. #ffffff (default black)",
+                        "name": "background",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "flip = horizontal, vertical or both, applied after rotate",
+                        "name": "flip",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "crop rect x,y,width,height, applied after rotate and flip",
+                        "name": "crop",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "crop to aspect ratio width:height, e.g. 16:9",
+                        "name": "crop_aspect",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "gravity for crop_aspect = center, north, south, east, west, northeast, northwest, southeast or southwest",
+                        "name": "gravity",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "denoise = median or bilateral, applied before the temperature pass",
+                        "name": "denoise",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "denoise window radius 1-5 (default 1)",
+                        "name": "denoise_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "tint from green -100 to magenta 100",
+                        "name": "tint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gaussian blur sigma in pixels, applied after the temperature pass",
+                        "name": "blur_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask amount, e.g. 0.5, applied last",
+                        "name": "sharpen_amount",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask radius in pixels (default 1)",
+                        "name": "sharpen_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask threshold 0-255",
+                        "name": "sharpen_threshold",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "zip of the adjusted images with a manifest.json giving the status, error and parameters of every entry",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/image_adjustment/histogram": {
+            "post": {
+                "produces": [
+                    "application/json",
+                    "image/png"
+                ],
+                "tags": [
+                    "ImageAdjustment"
+                ],
+                "summary": "ImageHistogram returns the r, g, b and luminance histograms with clipping and statistics of an uploaded or stored image, with adjustment_temperature also those of the adjusted image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "jpeg image, required without image_id",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of an image uploaded to /v1/images",
+                        "name": "image_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "render = png answers the histogram drawn as a png instead of json, the adjusted one below the original",
+                        "name": "render",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "also return the histogram of the image adjusted with the parameters of /v1/image_adjustment/temperature",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "also return the histogram of the image adjusted with a preset, parameters sent with the request override it",
+                        "name": "preset",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/image_adjustment/palette": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ImageAdjustment"
+                ],
+                "summary": "ImagePalette returns the dominant colours of an uploaded or stored image, with adjustment_temperature also those of the adjusted image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "jpeg image, required without image_id",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of an image uploaded to /v1/images",
+                        "name": "image_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of colours 1-16 (default 5)",
+                        "name": "colors",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "also return the palette of the image adjusted with the parameters of /v1/image_adjustment/temperature",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "also return the palette of the image adjusted with a preset, parameters sent with the request override it",
+                        "name": "preset",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
         "/v1/image_adjustment/temperature": {
             "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ImageAdjustment"
+                    "ImageAdjustment"
+                ],
+                "summary": "ImageAdjustmentTemperature",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id for retention, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "several files adjusted concurrently instead of file, answers one result per file",
+                        "name": "files[]",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "adjustment_temperature, required without preset",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of a preset filling in every parameter not sent with the request",
+                        "name": "preset",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "preview = true or false, a preview returns the image and stores nothing",
+                        "name": "preview",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "store_input = false to not keep the original upload",
+                        "name": "store_input",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "async = true answers 202 with a job, poll /v1/jobs/{id} for the result",
+                        "name": "async",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "url which receives a signed POST once the job is done or failed",
+                        "name": "callback_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max size of output jpeg in bytes, quality is lowered to fit",
+                        "name": "max_output_bytes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes",
+                        "name": "allow_downscale",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output width in pixels",
+                        "name": "width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output height in pixels",
+                        "name": "height",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "fit = contain, cover, fill or inside (default inside)",
+                        "name": "fit",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "interpolation = lanczos3 or bilinear (default lanczos3)",
+                        "name": "interpolation",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "rendition widths, e.g. [320,640,1280], clamped to the source width",
+                        "name": "sizes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "clockwise rotation in degrees, applied first",
+                        "name": "rotate",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "background fill for arbitrary rotation, hex e.g. #ffffff (default black)",
+                        "name": "background",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "flip = horizontal, vertical or both, applied after rotate",
+                        "name": "flip",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "crop rect x,y,width,height, applied after rotate and flip",
+                        "name": "crop",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "crop to aspect ratio width:height, e.g. 16:9",
+                        "name": "crop_aspect",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "gravity for crop_aspect = center, north, south, east, west, northeast, northwest, southeast or southwest",
+                        "name": "gravity",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "denoise = median or bilateral, applied before the temperature pass",
+                        "name": "denoise",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "denoise window radius 1-5 (default 1)",
+                        "name": "denoise_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "r,g,b gains applied before the temperature pass, e.g. the gains of /v1/image_adjustment/white_balance",
+                        "name": "white_balance_gains",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "tint from green -100 to magenta 100, applied with the white balance gains",
+                        "name": "tint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "compare = split, side_by_side or wipe renders the original and the adjusted image into one output",
+                        "name": "compare",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "divider position of split and wipe between 0 and 1 (default 0.5)",
+                        "name": "compare_position",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "compare_labels = true labels Before and After, or give both texts e.g. Original,Graded",
+                        "name": "compare_labels",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "render 2-15 variants step_kelvin apart around the requested adjustment instead of one output, runs synchronously so async and callback_url are rejected",
+                        "name": "variants",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "kelvin between two variants (default 300)",
+                        "name": "step_kelvin",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "contact_sheet = true adds one image of the labelled variants side by side, a preview answers only this image",
+                        "name": "contact_sheet",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "metrics = true adds psnr, ssim and the mean and max CIEDE2000 delta e between input and output",
+                        "name": "metrics",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "metrics_heatmap = true also stores a heat map of the delta e of every pixel, not for a preview",
+                        "name": "metrics_heatmap",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gaussian blur sigma in pixels, applied after the temperature pass",
+                        "name": "blur_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask amount, e.g. 0.5, applied last",
+                        "name": "sharpen_amount",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask radius in pixels (default 1)",
+                        "name": "sharpen_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask threshold 0-255",
+                        "name": "sharpen_threshold",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/image_adjustment/white_balance": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ImageAdjustment"
+                ],
+                "summary": "ImageWhiteBalance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id for retention, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "images of the same shoot, every one gets the same white balance correction",
+                        "name": "files[]",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "strategy = median (default) for the median illuminant of the files or anchor for the illuminant of one file",
+                        "name": "strategy",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "index in files[] of the anchor file, for strategy = anchor",
+                        "name": "anchor_index",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "adjustment_temperature applied after the white balance (default 1, unchanged)",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of a preset filling in every parameter not sent with the request",
+                        "name": "preset",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "store_input = false to not keep the original uploads",
+                        "name": "store_input",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max size of output jpeg in bytes, quality is lowered to fit",
+                        "name": "max_output_bytes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "allow_downscale = true or false, shrink image when quality alone can not fit max_output_bytes",
+                        "name": "allow_downscale",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output width in pixels",
+                        "name": "width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output height in pixels",
+                        "name": "height",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "fit = contain, cover, fill or inside (default inside)",
+                        "name": "fit",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "interpolation = lanczos3 or bilinear (default lanczos3)",
+                        "name": "interpolation",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "rendition widths, e.g. [320,640,1280], clamped to the source width",
+                        "name": "sizes",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/images": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "CreateImage, keep the owner_token of the response to delete the image or read its history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id for retention, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/images/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "GetImage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "image id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "DeleteImage removes the upload and its outputs of the caller, objects other clients still use are kept",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id the image was uploaded with, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "owner_token returned by the upload",
+                        "name": "X-Owner-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.UnauthorizedResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/images/{id}/adjustments": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "FetchImageAdjustments returns the adjustments the caller requested of an image, latest first",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id the image was uploaded with, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "owner_token returned by the upload",
+                        "name": "X-Owner-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.UnauthorizedResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/images/{id}/temperature": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Image"
+                ],
+                "summary": "ImageTemperature adjusts an uploaded image, takes the parameters of /v1/image_adjustment/temperature except file and store_input",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client id for retention, defaults to the caller ip",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "image id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "adjustment_temperature, required without preset",
+                        "name": "adjustment_temperature",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of a preset filling in every parameter not sent with the request",
+                        "name": "preset",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "preview = true or false, a preview returns the image and stores nothing",
+                        "name": "preview",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "async = true answers 202 with a job, poll /v1/jobs/{id} for the result",
+                        "name": "async",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "url which receives a signed POST once the job is done or failed",
+                        "name": "callback_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output width in pixels",
+                        "name": "width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "output height in pixels",
+                        "name": "height",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "fit = contain, cover, fill or inside (default inside)",
+                        "name": "fit",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "rendition widths, e.g. [320,640,1280], clamped to the source width",
+                        "name": "sizes",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/jobs/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "GetJob reports the state of an adjustment job, queued, running, done or failed, with the result urls once done",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/jobs/{id}/deliveries": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "FetchJobDeliveries lists every attempt to post the job outcome to its callback_url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/presets": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Preset"
+                ],
+                "summary": "FetchPresets returns every preset by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Preset"
+                ],
+                "summary": "CreatePreset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "preset name of lower case letters and digits joined by dashes, e.g. golden-hour",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "description",
+                        "name": "description",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "adjustment_temperature",
+                        "name": "adjustment_temperature",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "r,g,b gains applied before the temperature pass",
+                        "name": "white_balance_gains",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "tint from green -100 to magenta 100",
+                        "name": "tint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "denoise = median or bilateral",
+                        "name": "denoise",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "denoise window radius 1-5",
+                        "name": "denoise_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gaussian blur sigma in pixels",
+                        "name": "blur_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask amount",
+                        "name": "sharpen_amount",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask radius in pixels",
+                        "name": "sharpen_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask threshold 0-255",
+                        "name": "sharpen_threshold",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestErrorValidationResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/swagger.ValidationErrors"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/presets/{name}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Preset"
+                ],
+                "summary": "GetPreset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Preset"
                 ],
-                "summary": "ImageAdjustmentTemperature",
+                "summary": "UpdatePreset replaces the description and every parameter of a preset",
                 "parameters": [
                     {
                         "type": "string",
@@ -43,12 +2698,25 @@ var doc = `{
                         "in": "header"
                     },
                     {
-                        "type": "file",
-                        "description": "file",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "description",
+                        "name": "description",
+                        "in": "formData"
+                    },
                     {
                         "type": "string",
                         "description": "adjustment_temperature",
@@ -58,8 +2726,50 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "preview = true or false",
-                        "name": "preview",
+                        "description": "r,g,b gains applied before the temperature pass",
+                        "name": "white_balance_gains",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "tint from green -100 to magenta 100",
+                        "name": "tint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "denoise = median or bilateral",
+                        "name": "denoise",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "denoise window radius 1-5",
+                        "name": "denoise_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "gaussian blur sigma in pixels",
+                        "name": "blur_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask amount",
+                        "name": "sharpen_amount",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask radius in pixels",
+                        "name": "sharpen_radius",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "unsharp mask threshold 0-255",
+                        "name": "sharpen_threshold",
                         "in": "formData"
                     }
                 ],
@@ -112,12 +2822,165 @@ var doc = `{
                             ]
                         }
                     },
-                    "408": {
-                        "description": "Request Timeout",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "allOf": [
                                 {
-                                    "$ref": "#/definitions/swagger.RequestTimeoutResponse"
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.InternalServerErrorResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Preset"
+                ],
+                "summary": "DeletePreset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "lang",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin api key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BaseResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        },
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "object"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/swagger.BadRequestResponse"
                                 },
                                 {
                                     "type": "object",
@@ -188,6 +3051,29 @@ var doc = `{
                 }
             }
         },
+        "swagger.BadRequestResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "KDMU-02-011"
+                },
+                "data": {},
+                "errors": {},
+                "message": {
+                    "type": "string",
+                    "example": "data yang anda minta tidak ditemukan."
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "24fa3770-628c-49de-aa17-3a338f73d99b"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2022-04-27 23:19:56"
+                }
+            }
+        },
         "swagger.BaseResponse": {
             "type": "object",
             "properties": {
@@ -257,6 +3143,29 @@ var doc = `{
                 }
             }
         },
+        "swagger.UnauthorizedResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "KDMU-02-012"
+                },
+                "data": {},
+                "errors": {},
+                "message": {
+                    "type": "string",
+                    "example": "token tidak valid."
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "24fa3770-628c-49de-aa17-3a338f73d99b"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2022-04-27 23:19:56"
+                }
+            }
+        },
         "swagger.ValidationErrors": {
             "type": "object",
             "properties": {